@@ -0,0 +1,67 @@
+// Command apikey issues and revokes the API keys that identify link owners.
+//
+//	apikey create -owner team-a [-name ci]
+//	apikey revoke -id 7
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/nanda/doit/config"
+	"github.com/nanda/doit/modules/core"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cfg := config.Load()
+	database, err := config.NewPostgresDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer func() {
+		if err := database.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+	}()
+
+	svc := core.NewAPIKeyService(database)
+	ctx := context.Background()
+
+	switch os.Args[1] {
+	case "create":
+		flags := flag.NewFlagSet("create", flag.ExitOnError)
+		owner := flags.String("owner", "", "owner the key acts for (required)")
+		name := flags.String("name", "", "label to recognise the key by")
+		_ = flags.Parse(os.Args[2:])
+
+		rawKey, key, err := svc.Issue(ctx, *owner, *name)
+		if err != nil {
+			log.Fatalf("Failed to create API key: %v", err)
+		}
+		fmt.Printf("id:    %d\nowner: %s\nkey:   %s\n", key.ID, key.OwnerID, rawKey)
+		fmt.Println("Store the key now; it cannot be shown again.")
+	case "revoke":
+		flags := flag.NewFlagSet("revoke", flag.ExitOnError)
+		id := flags.Int64("id", 0, "ID of the key to revoke (required)")
+		_ = flags.Parse(os.Args[2:])
+
+		if err := svc.Revoke(ctx, *id); err != nil {
+			log.Fatalf("Failed to revoke API key %d: %v", *id, err)
+		}
+		fmt.Printf("API key %d revoked\n", *id)
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: apikey create -owner OWNER [-name NAME] | apikey revoke -id ID")
+	os.Exit(2)
+}
@@ -0,0 +1,145 @@
+// Command rebuildcache restores the Redis link mappings from the analytics
+// records in Postgres, after Redis lost data. Links still in Redis are left alone.
+//
+//	rebuildcache [-dry-run] [-batch 500] [-checkpoint FILE] [-after ID]
+//
+// With -checkpoint, the last link ID handled is saved after each batch and a rerun
+// resumes from it; the file is removed once the rebuild completes.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nanda/doit/config"
+	"github.com/nanda/doit/modules/core"
+	"github.com/nanda/doit/modules/core/service"
+)
+
+func main() {
+	j := parseFlags()
+	svc, closeAll := connect()
+	defer closeAll()
+
+	if j.opts.AfterURLID > 0 {
+		fmt.Printf("Resuming after link %d\n", j.opts.AfterURLID)
+	}
+	result, err := svc.Rebuild(context.Background(), j.opts, j.report)
+	if err != nil {
+		log.Fatalf("Rebuild stopped after link %d: %v", result.LastURLID, err)
+	}
+	j.finish(result)
+}
+
+// job is a rebuild as requested on the command line.
+type job struct {
+	opts       service.RebuildOptions
+	checkpoint string
+}
+
+// parseFlags reads the flags and the checkpoint they point at. -after wins over
+// the checkpoint.
+func parseFlags() job {
+	dryRun := flag.Bool("dry-run", false, "count the links to restore without writing to Redis")
+	batch := flag.Int("batch", service.DefaultRebuildBatchSize, "links read and written per batch")
+	checkpoint := flag.String("checkpoint", "", "file to save progress in and resume from")
+	after := flag.Int64("after", 0, "start after this link ID, overriding the checkpoint")
+	flag.Parse()
+
+	j := job{opts: service.RebuildOptions{DryRun: *dryRun, BatchSize: *batch}, checkpoint: *checkpoint}
+	if j.checkpoint != "" {
+		last, err := readCheckpoint(j.checkpoint)
+		if err != nil {
+			log.Fatalf("Failed to read checkpoint: %v", err)
+		}
+		j.opts.AfterURLID = last
+	}
+	if *after > 0 {
+		j.opts.AfterURLID = *after
+	}
+	return j
+}
+
+// connect opens Postgres and Redis and returns the rebuilder on top of them, with
+// a function that closes both.
+func connect() (*service.CacheRebuilderService, func()) {
+	cfg := config.Load()
+	database, err := config.NewPostgresDB(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	redisClient, err := config.NewRedisClient(cfg.RedisURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize Redis: %v", err)
+	}
+
+	closeAll := func() {
+		if err := redisClient.Close(); err != nil {
+			log.Printf("Error closing Redis client: %v", err)
+		}
+		if err := database.Close(); err != nil {
+			log.Printf("Error closing database: %v", err)
+		}
+	}
+	return core.NewCacheRebuilderService(database, redisClient), closeAll
+}
+
+// report prints the progress after each batch and saves it to the checkpoint.
+func (j job) report(p service.RebuildProgress) {
+	printProgress(p, j.opts.DryRun)
+	if !j.saveCheckpoint() {
+		return
+	}
+	if err := writeCheckpoint(j.checkpoint, p.LastURLID); err != nil {
+		log.Printf("Failed to save checkpoint: %v", err)
+	}
+}
+
+// finish removes the checkpoint of a completed rebuild and prints the totals.
+func (j job) finish(result service.RebuildProgress) {
+	if j.saveCheckpoint() {
+		if err := os.Remove(j.checkpoint); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Failed to remove checkpoint: %v", err)
+		}
+	}
+	fmt.Print("Done: ")
+	printProgress(result, j.opts.DryRun)
+}
+
+// saveCheckpoint reports whether progress is saved. A dry run writes nothing, so
+// there is nothing to resume.
+func (j job) saveCheckpoint() bool {
+	return j.checkpoint != "" && !j.opts.DryRun
+}
+
+func printProgress(p service.RebuildProgress, dryRun bool) {
+	if dryRun {
+		fmt.Printf("through link %d: %d scanned, %d to restore, %d skipped\n",
+			p.LastURLID, p.Scanned, p.Restored, p.Skipped)
+		return
+	}
+	fmt.Printf("through link %d: %d scanned, %d restored, %d already present, %d skipped\n",
+		p.LastURLID, p.Scanned, p.Restored, p.Present, p.Skipped)
+}
+
+// readCheckpoint returns the link ID saved in path, or 0 if there is no checkpoint yet.
+func readCheckpoint(path string) (int64, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+}
+
+func writeCheckpoint(path string, lastURLID int64) error {
+	return os.WriteFile(path, []byte(strconv.FormatInt(lastURLID, 10)+"\n"), 0o644)
+}
@@ -1,13 +1,16 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/nanda/doit/config"
 	"github.com/nanda/doit/modules/core"
 	"github.com/nanda/doit/modules/core/handler"
+	"github.com/nanda/doit/modules/core/service"
 )
 
 func main() {
@@ -37,7 +40,17 @@ func main() {
 	}()
 
 	// Build application dependencies
-	builder := core.NewBuilder(db, redisClient)
+	builder, err := core.NewBuilder(cfg, db, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to build application: %v", err)
+	}
+
+	// Creates are refused until the ID sequence is verified against Postgres
+	verifyIDSequence(builder.IDSequenceGuard)
+	go watchIDSequence(builder.IDSequenceGuard)
+
+	// Repair links left half-created by creates that failed midway
+	go reconcileLinks(builder.LinkReconcilerService)
 
 	// Initialize Echo server
 	e := echo.New()
@@ -51,9 +64,17 @@ func main() {
 	e.GET("/healthz", builder.HealthzHandler.Handle)
 	e.GET("/metrics", echo.WrapHandler(config.NewPrometheusHandler()))
 
-	e.POST("/s", builder.LinkCreatorHandler.Handle)
+	// Link management routes resolve the caller's API key; redirects stay public
+	auth := builder.APIKeyAuth
+	e.POST("/s", builder.LinkCreatorHandler.Handle, auth.Resolve, auth.RequireForCreate)
+	e.POST("/s/batch", builder.LinkCreatorHandler.HandleBatch, auth.Resolve, auth.RequireForCreate)
 	e.GET("/s/:short_code", builder.LinkRedirectorHandler.Handle)
-	e.GET("/stats/:short_code", builder.LinkAnalyzerHandler.Handle)
+	e.POST("/s/:short_code/unlock", builder.LinkRedirectorHandler.HandleUnlock)
+	e.PATCH("/s/:short_code", builder.LinkEditorHandler.Handle, auth.Resolve)
+	e.DELETE("/s/:short_code", builder.LinkRevokerHandler.Handle, auth.Resolve)
+	e.GET("/stats/:short_code", builder.LinkAnalyzerHandler.Handle, auth.Resolve)
+	e.GET("/stats/:short_code/history", builder.LinkEditorHandler.HandleHistory, auth.Resolve)
+	e.GET("/links", builder.LinkListerHandler.Handle, auth.Resolve)
 
 	// Start server
 	log.Printf("Starting server on :%s", cfg.Port)
@@ -61,3 +82,42 @@ func main() {
 		log.Fatalf("Failed to start server: %v", err)
 	}
 }
+
+// reconcileLinks settles pending links every service.ReconcileInterval for the
+// life of the process. Each replica may run it; settling a link is idempotent.
+func reconcileLinks(reconciler *service.LinkReconcilerService) {
+	ticker := time.NewTicker(service.ReconcileInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		result, err := reconciler.Reconcile(context.Background(), time.Now().Add(-service.ReconcileGrace))
+		if err != nil {
+			log.Printf("Error reconciling pending links: %v", err)
+		}
+		if result.Confirmed > 0 || result.Discarded > 0 {
+			log.Printf("Reconciled pending links: %d confirmed, %d discarded", result.Confirmed, result.Discarded)
+		}
+	}
+}
+
+// watchIDSequence re-verifies the ID sequence every service.IDSequenceCheckInterval,
+// catching Redis data loss while the server runs.
+func watchIDSequence(guard *service.IDSequenceGuard) {
+	ticker := time.NewTicker(service.IDSequenceCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		verifyIDSequence(guard)
+	}
+}
+
+func verifyIDSequence(guard *service.IDSequenceGuard) {
+	skipped, err := guard.Verify(context.Background())
+	if err != nil {
+		log.Printf("Error verifying ID sequence, refusing creates: %v", err)
+		return
+	}
+	if skipped > 0 {
+		log.Printf("ID sequence was behind recorded links, advanced it by %d", skipped)
+	}
+}
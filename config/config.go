@@ -3,6 +3,8 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -12,6 +14,60 @@ type Config struct {
 	DatabaseURL string
 	RedisURL    string
 	Port        string
+
+	// PublicBaseURL is the externally visible origin used to build short URLs,
+	// e.g. "https://sho.rt". Defaults to http://localhost:{Port}.
+	PublicBaseURL string
+
+	// DedupeByDefault makes POST /s reuse an active link for the same destination
+	// unless the request opts out with "dedupe": false.
+	DedupeByDefault bool
+
+	// DestinationPolicyFile is an optional JSON file of allow and deny domain lists
+	// for link destinations. It is reloaded when it changes.
+	DestinationPolicyFile string
+
+	// ShortLinkHosts are extra hosts, besides the PublicBaseURL host, that serve
+	// this shortener's links. Destinations on these hosts are never stored as-is.
+	ShortLinkHosts []string
+
+	// SelfLinkMode is "flatten" to replace a destination that is one of our own
+	// short links with its final destination, or "refuse" to reject it.
+	SelfLinkMode string
+
+	// RequireAPIKey refuses anonymous link creation. When false, callers without a
+	// key can still create links, which then have no owner.
+	RequireAPIKey bool
+
+	// AdminOwners are the owners whose API keys can edit and revoke every link,
+	// including links created without a key.
+	AdminOwners []string
+
+	// ShortCodeCodec is the format of new generated codes: "hex" for the codes of
+	// ADR-001, "base57" for shorter, tagged codes or "keyed" for codes that cannot
+	// be enumerated. Codes of every format resolve.
+	ShortCodeCodec string
+
+	// ShortCodeKeys are the "version:secret" keys of keyed codes. The highest
+	// version encodes new codes; lower versions keep older codes resolving.
+	// "version/checks:secret" makes codes of that version end in 1 or 2 check
+	// characters, verified before any lookup. Check characters are only accepted
+	// with the keyed codec.
+	ShortCodeKeys []string
+
+	// BlockedWords are substrings that generated codes and aliases may not contain,
+	// in addition to the curated defaults unless NoDefaultBlockedWords is set.
+	BlockedWords          []string
+	NoDefaultBlockedWords bool
+
+	// IdempotencySecret keys the HMAC of the password in Idempotency-Key request
+	// fingerprints. Empty means a random key per process.
+	IdempotencySecret string
+
+	// NonCanonicalCodes is "redirect" (the default) to answer a code typed with the
+	// wrong case or confusable characters with a 301 to its canonical spelling, or
+	// "resolve" to redirect straight to the destination.
+	NonCanonicalCodes string
 }
 
 // Load loads the configuration from environment variables.
@@ -25,6 +81,28 @@ func Load() *Config {
 		DatabaseURL: os.Getenv("DATABASE_URL"),
 		RedisURL:    os.Getenv("REDIS_URL"),
 		Port:        os.Getenv("PORT"),
+
+		PublicBaseURL: os.Getenv("PUBLIC_BASE_URL"),
+
+		DedupeByDefault: getEnvBool("DEDUPE_BY_DEFAULT", false),
+
+		DestinationPolicyFile: os.Getenv("DESTINATION_POLICY_FILE"),
+
+		ShortLinkHosts: getEnvList("SHORT_LINK_HOSTS"),
+		SelfLinkMode:   os.Getenv("SELF_LINK_MODE"),
+
+		RequireAPIKey: getEnvBool("REQUIRE_API_KEY", false),
+		AdminOwners:   getEnvList("ADMIN_OWNERS"),
+
+		ShortCodeCodec: os.Getenv("SHORT_CODE_CODEC"),
+		ShortCodeKeys:  getEnvList("SHORT_CODE_KEYS"),
+
+		BlockedWords:          getEnvList("BLOCKED_WORDS"),
+		NoDefaultBlockedWords: getEnvBool("NO_DEFAULT_BLOCKED_WORDS", false),
+
+		IdempotencySecret: os.Getenv("IDEMPOTENCY_SECRET"),
+
+		NonCanonicalCodes: os.Getenv("NON_CANONICAL_CODES"),
 	}
 
 	// Set default port if not specified
@@ -32,6 +110,27 @@ func Load() *Config {
 		cfg.Port = "8080"
 	}
 
+	// Set default public base URL if not specified
+	if cfg.PublicBaseURL == "" {
+		cfg.PublicBaseURL = "http://localhost:" + cfg.Port
+	}
+	cfg.PublicBaseURL = strings.TrimRight(cfg.PublicBaseURL, "/")
+
+	// Set default self-link mode if not specified
+	if cfg.SelfLinkMode == "" {
+		cfg.SelfLinkMode = "flatten"
+	}
+
+	// Set default handling of non-canonical codes if not specified
+	if cfg.NonCanonicalCodes == "" {
+		cfg.NonCanonicalCodes = "redirect"
+	}
+
+	// Set default short code codec if not specified
+	if cfg.ShortCodeCodec == "" {
+		cfg.ShortCodeCodec = "hex"
+	}
+
 	// Set default Redis URL if not specified
 	if cfg.RedisURL == "" {
 		cfg.RedisURL = "redis://localhost:6379/0"
@@ -39,3 +138,23 @@ func Load() *Config {
 
 	return cfg
 }
+
+// getEnvBool parses a boolean environment variable, returning fallback when unset or invalid.
+func getEnvBool(key string, fallback bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// getEnvList parses a comma-separated environment variable, skipping empty entries.
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
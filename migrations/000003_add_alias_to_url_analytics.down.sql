@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_url_analytics_alias;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS alias;
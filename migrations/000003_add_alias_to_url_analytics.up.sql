@@ -0,0 +1,6 @@
+-- Vanity aliases are stored alongside the numeric url_id so /stats can
+-- resolve them after the Redis alias key has expired
+ALTER TABLE url_analytics ADD COLUMN alias TEXT;
+
+-- Partial btree index: only aliased links are ever looked up by alias
+CREATE INDEX idx_url_analytics_alias ON url_analytics (alias, created_at DESC) WHERE alias IS NOT NULL;
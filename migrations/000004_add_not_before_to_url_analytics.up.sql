@@ -0,0 +1,2 @@
+-- Scheduled links go live at not_before; NULL means active from creation
+ALTER TABLE url_analytics ADD COLUMN not_before TIMESTAMPTZ;
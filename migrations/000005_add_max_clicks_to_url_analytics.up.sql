@@ -0,0 +1,2 @@
+-- Click-limited links stop redirecting after max_clicks redirects; NULL means unlimited
+ALTER TABLE url_analytics ADD COLUMN max_clicks BIGINT;
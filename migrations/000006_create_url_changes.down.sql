@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_url_changes_url_id;
+DROP TABLE IF EXISTS url_changes;
@@ -0,0 +1,13 @@
+-- History of edits to a link's destination and expiry
+CREATE TABLE url_changes (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL,
+    old_long_url TEXT NOT NULL,
+    new_long_url TEXT NOT NULL,
+    old_expires_at TIMESTAMPTZ NOT NULL,
+    new_expires_at TIMESTAMPTZ NOT NULL,
+    changed_at TIMESTAMPTZ NOT NULL
+);
+
+-- Changes are read per link in order
+CREATE INDEX idx_url_changes_url_id ON url_changes (url_id, changed_at);
@@ -0,0 +1,2 @@
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS revoke_reason;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS revoked_at;
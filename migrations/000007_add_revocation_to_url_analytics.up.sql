@@ -0,0 +1,3 @@
+-- Revoked links stop redirecting; NULL revoked_at means the link was never revoked
+ALTER TABLE url_analytics ADD COLUMN revoked_at TIMESTAMPTZ;
+ALTER TABLE url_analytics ADD COLUMN revoke_reason TEXT;
@@ -0,0 +1,2 @@
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS owner_id;
+DROP TABLE IF EXISTS api_keys;
@@ -0,0 +1,12 @@
+-- API keys identify link owners; only a hash of each key is stored
+CREATE TABLE api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+
+-- Links created with a key belong to its owner; anonymous links have none
+ALTER TABLE url_analytics ADD COLUMN owner_id TEXT;
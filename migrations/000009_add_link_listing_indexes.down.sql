@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_url_analytics_owner_destination_host;
+DROP INDEX IF EXISTS idx_url_analytics_owner_last_accessed_at;
+DROP INDEX IF EXISTS idx_url_analytics_owner_click_count;
+DROP INDEX IF EXISTS idx_url_analytics_owner_created_at;
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS destination_host;
+
+DROP INDEX IF EXISTS idx_url_analytics_url_id;
+CREATE INDEX idx_url_analytics_id_brin ON url_analytics USING BRIN (id);
+CREATE INDEX idx_url_analytics_url_id_brin ON url_analytics USING BRIN (url_id);
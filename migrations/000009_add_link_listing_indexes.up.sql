@@ -0,0 +1,20 @@
+-- BRIN only narrows scans to block ranges; lookups by url_id and the per-owner
+-- listings need exact btree indexes instead
+DROP INDEX IF EXISTS idx_url_analytics_url_id_brin;
+DROP INDEX IF EXISTS idx_url_analytics_id_brin;
+CREATE INDEX idx_url_analytics_url_id ON url_analytics (url_id);
+
+-- Destination host of the canonical long_url, for filtering links by domain.
+-- Canonical URLs are lowercase with no default port: scheme://[userinfo@]host[:port]...
+ALTER TABLE url_analytics ADD COLUMN destination_host TEXT
+    GENERATED ALWAYS AS (substring(long_url from '^[a-z][a-z0-9+.-]*://(?:[^/?#@]*@)?(\[[^]]*\]|[^/?#:]*)')) STORED;
+
+-- One index per sort order of GET /links; id breaks ties for keyset pagination
+CREATE INDEX idx_url_analytics_owner_created_at ON url_analytics (owner_id, created_at, id)
+    WHERE owner_id IS NOT NULL;
+CREATE INDEX idx_url_analytics_owner_click_count ON url_analytics (owner_id, click_count, id)
+    WHERE owner_id IS NOT NULL;
+CREATE INDEX idx_url_analytics_owner_last_accessed_at ON url_analytics (owner_id, COALESCE(last_accessed_at, 'epoch'::timestamptz), id)
+    WHERE owner_id IS NOT NULL;
+CREATE INDEX idx_url_analytics_owner_destination_host ON url_analytics (owner_id, destination_host, created_at, id)
+    WHERE owner_id IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_url_analytics_url_id;
+CREATE INDEX idx_url_analytics_url_id ON url_analytics (url_id);
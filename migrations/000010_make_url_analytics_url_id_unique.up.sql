@@ -0,0 +1,6 @@
+-- url_id is the link ID allocated in Redis. If the ID sequence falls behind and
+-- hands out a recorded ID again, the insert must fail so the creator discards the
+-- new Redis link instead of recording a second link under the same ID.
+-- Duplicate url_id rows must be resolved before this migration can run.
+DROP INDEX IF EXISTS idx_url_analytics_url_id;
+CREATE UNIQUE INDEX idx_url_analytics_url_id ON url_analytics (url_id);
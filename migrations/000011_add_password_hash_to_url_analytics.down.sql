@@ -0,0 +1 @@
+ALTER TABLE url_analytics DROP COLUMN IF EXISTS password_hash;
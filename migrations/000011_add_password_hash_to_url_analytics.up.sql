@@ -0,0 +1,4 @@
+-- Copy of the link password hash kept in Redis, so links can be restored from here.
+-- '' means the link has no password; NULL marks links created before this column,
+-- whose protection is unknown.
+ALTER TABLE url_analytics ADD COLUMN password_hash TEXT;
@@ -3,10 +3,18 @@ package core
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/nanda/doit/config"
 	"github.com/nanda/doit/modules/core/handler"
 	"github.com/nanda/doit/modules/core/internal/repo/cache"
 	"github.com/nanda/doit/modules/core/internal/repo/db"
+	"github.com/nanda/doit/modules/core/lib"
 	"github.com/nanda/doit/modules/core/service"
 	"github.com/redis/go-redis/v9"
 )
@@ -14,53 +22,283 @@ import (
 // Builder holds all the dependencies for the application.
 type Builder struct {
 	// Repositories
-	URLCacheRepo    service.URLCacheRepo
-	URLAnalyticRepo service.URLAnalyticRepo
+	URLCacheRepo      service.URLCacheRepo
+	URLAnalyticRepo   service.URLAnalyticRepo
+	IdempotencyRepo   service.IdempotencyRepo
+	UnlockAttemptRepo service.UnlockAttemptRepo
+	APIKeyRepo        service.APIKeyRepo
 
 	// Services
 	LinkCreatorService    *service.LinkCreatorService
 	LinkRedirectorService *service.LinkRedirectorService
+	LinkEditorService     *service.LinkEditorService
+	LinkRevokerService    *service.LinkRevokerService
+	LinkListerService     *service.LinkListerService
 	LinkAnalyzerService   *service.LinkAnalyzerService
+	LinkReconcilerService *service.LinkReconcilerService
+	IDSequenceGuard       *service.IDSequenceGuard
+	IdempotencyService    *service.IdempotencyService
+	APIKeyService         *service.APIKeyService
 
 	// Handlers
 	LinkCreatorHandler    *handler.LinkCreatorHandler
 	LinkRedirectorHandler *handler.LinkRedirectorHandler
+	LinkEditorHandler     *handler.LinkEditorHandler
+	LinkRevokerHandler    *handler.LinkRevokerHandler
+	LinkListerHandler     *handler.LinkListerHandler
 	LinkAnalyzerHandler   *handler.LinkAnalyzerHandler
 	HealthzHandler        *handler.HealthzHandler
+
+	// Middleware
+	APIKeyAuth *handler.APIKeyAuth
 }
 
 // NewBuilder creates a new Builder with all dependencies initialized.
-func NewBuilder(database *sql.DB, redisClient *redis.Client) *Builder {
+// It fails when a configured destination policy file cannot be loaded or the
+// self-link mode, short code codec or non-canonical code handling is unknown.
+func NewBuilder(cfg *config.Config, database *sql.DB, redisClient *redis.Client) (*Builder, error) {
 	// Initialize repositories
 	cacheRepo := cache.NewRedisURLCacheRepo(redisClient)
 	analyticRepo := db.NewPostgresURLAnalyticRepo(database)
+	idempotencyRepo := cache.NewRedisIdempotencyRepo(redisClient)
+	unlockAttemptRepo := cache.NewRedisUnlockAttemptRepo(redisClient)
+	apiKeyRepo := db.NewPostgresAPIKeyRepo(database)
 
 	// Initialize services
-	creatorSvc := service.NewLinkCreatorService(cacheRepo, analyticRepo)
-	redirectorSvc := service.NewLinkRedirectorService(cacheRepo, analyticRepo)
-	analyzerSvc := service.NewLinkAnalyzerService(analyticRepo)
+	shortLinkOpt, err := shortLinkHostsOption(cfg)
+	if err != nil {
+		return nil, err
+	}
+	codes, err := codeSet(cfg)
+	if err != nil {
+		return nil, err
+	}
+	sequenceGuard := service.NewIDSequenceGuard(cacheRepo, analyticRepo)
+	creatorOpts := []service.LinkCreatorOption{
+		service.WithIDSequenceGuard(sequenceGuard),
+		service.WithDedupeByDefault(cfg.DedupeByDefault),
+		service.WithCodeSet(codes),
+		service.WithBlocklist(blocklist(cfg)),
+		service.WithCodeBlocklist(codeBlocklist(cfg, codes)),
+		shortLinkOpt,
+	}
+	if cfg.DestinationPolicyFile != "" {
+		policy, err := service.NewFileDestinationPolicy(cfg.DestinationPolicyFile, service.DestinationPolicyReloadInterval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load destination policy: %w", err)
+		}
+		creatorOpts = append(creatorOpts, service.WithDestinationPolicy(policy))
+	}
+	creatorSvc := service.NewLinkCreatorService(cacheRepo, analyticRepo, creatorOpts...)
+	nonCanonicalMode, err := nonCanonicalMode(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cacheBreaker := service.NewCircuitBreaker(service.CacheBreakerThreshold, service.CacheBreakerCooldown).
+		OnStateChange(handler.RecordCircuitBreaker("redis"))
+	redirectorSvc := service.NewLinkRedirectorService(
+		cacheRepo, analyticRepo, unlockAttemptRepo, codes,
+		service.WithNonCanonicalMode(nonCanonicalMode),
+		service.WithCacheFallback(cacheBreaker, cache.IsUnavailable),
+	)
+	editorSvc := service.NewLinkEditorService(cacheRepo, analyticRepo, creatorSvc)
+	revokerSvc := service.NewLinkRevokerService(cacheRepo, analyticRepo, codes)
+	listerSvc := service.NewLinkListerService(analyticRepo, codes)
+	analyzerSvc := service.NewLinkAnalyzerService(analyticRepo, codes)
+	reconcilerSvc := service.NewLinkReconcilerService(cacheRepo, analyticRepo)
+	idempotencySvc := service.NewIdempotencyService(idempotencyRepo)
+	apiKeySvc := service.NewAPIKeyService(apiKeyRepo)
 
 	// Initialize handlers
-	creatorHandler := handler.NewLinkCreatorHandler(creatorSvc)
+	creatorHandler := handler.NewLinkCreatorHandler(creatorSvc, idempotencySvc, cfg.PublicBaseURL)
+	if cfg.IdempotencySecret != "" {
+		creatorHandler.WithFingerprintKey([]byte(cfg.IdempotencySecret))
+	}
 	redirectorHandler := handler.NewLinkRedirectorHandler(redirectorSvc)
+	editorHandler := handler.NewLinkEditorHandler(editorSvc, cfg.PublicBaseURL)
+	revokerHandler := handler.NewLinkRevokerHandler(revokerSvc)
+	listerHandler := handler.NewLinkListerHandler(listerSvc, cfg.PublicBaseURL)
 	analyzerHandler := handler.NewLinkAnalyzerHandler(analyzerSvc)
+	// Redirects fall back to the database while Redis is down, so only the
+	// database is required
 	healthzHandler := handler.NewHealthzHandler(func() error {
-		// Check both database and Redis health
-		if err := database.Ping(); err != nil {
-			return err
-		}
+		return database.Ping()
+	}).WithDegradedCheck("redis", func() error {
 		return redisClient.Ping(context.Background()).Err()
-	})
+	}).WithDegradedCheck("id_sequence", sequenceGuard.Check)
+	apiKeyAuth := handler.NewAPIKeyAuth(apiKeySvc, cfg.RequireAPIKey).WithAdminOwners(cfg.AdminOwners)
 
 	return &Builder{
 		URLCacheRepo:          cacheRepo,
 		URLAnalyticRepo:       analyticRepo,
+		IdempotencyRepo:       idempotencyRepo,
+		UnlockAttemptRepo:     unlockAttemptRepo,
+		APIKeyRepo:            apiKeyRepo,
 		LinkCreatorService:    creatorSvc,
 		LinkRedirectorService: redirectorSvc,
+		LinkEditorService:     editorSvc,
+		LinkRevokerService:    revokerSvc,
+		LinkListerService:     listerSvc,
 		LinkAnalyzerService:   analyzerSvc,
+		LinkReconcilerService: reconcilerSvc,
+		IDSequenceGuard:       sequenceGuard,
+		IdempotencyService:    idempotencySvc,
+		APIKeyService:         apiKeySvc,
 		LinkCreatorHandler:    creatorHandler,
 		LinkRedirectorHandler: redirectorHandler,
+		LinkEditorHandler:     editorHandler,
+		LinkRevokerHandler:    revokerHandler,
+		LinkListerHandler:     listerHandler,
 		LinkAnalyzerHandler:   analyzerHandler,
 		HealthzHandler:        healthzHandler,
+		APIKeyAuth:            apiKeyAuth,
+	}, nil
+}
+
+// NewAPIKeyService creates the API key service on its own, for tools that manage
+// keys without running the server.
+func NewAPIKeyService(database *sql.DB) *service.APIKeyService {
+	return service.NewAPIKeyService(db.NewPostgresAPIKeyRepo(database))
+}
+
+// NewCacheRebuilderService creates the cache rebuilder on its own, for tools that
+// restore Redis without running the server.
+func NewCacheRebuilderService(database *sql.DB, redisClient *redis.Client) *service.CacheRebuilderService {
+	return service.NewCacheRebuilderService(cache.NewRedisURLCacheRepo(redisClient), db.NewPostgresURLAnalyticRepo(database))
+}
+
+// shortLinkHostsOption registers the public host and any extra short link hosts so
+// links pointing back at this service are flattened or refused.
+func shortLinkHostsOption(cfg *config.Config) (service.LinkCreatorOption, error) {
+	mode := service.SelfLinkMode(cfg.SelfLinkMode)
+	switch mode {
+	case "", service.SelfLinkFlatten:
+		mode = service.SelfLinkFlatten
+	case service.SelfLinkRefuse:
+	default:
+		return nil, fmt.Errorf("unknown self-link mode %q", cfg.SelfLinkMode)
+	}
+
+	hosts := append([]string(nil), cfg.ShortLinkHosts...)
+	if parsed, err := url.Parse(cfg.PublicBaseURL); err == nil && parsed.Hostname() != "" {
+		hosts = append(hosts, parsed.Hostname())
+	}
+	return service.WithShortLinkHosts(hosts, mode), nil
+}
+
+// nonCanonicalMode reads how redirects treat retyped codes, redirecting to the
+// canonical spelling by default.
+func nonCanonicalMode(cfg *config.Config) (service.NonCanonicalMode, error) {
+	switch mode := service.NonCanonicalMode(cfg.NonCanonicalCodes); mode {
+	case "":
+		return service.NonCanonicalRedirect, nil
+	case service.NonCanonicalRedirect, service.NonCanonicalResolve:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown non-canonical code mode %q", cfg.NonCanonicalCodes)
+	}
+}
+
+// codeSet encodes new codes with the configured codec. Codes of the other
+// formats and of every configured key keep resolving, so the codec can be
+// switched and keys rotated without breaking links.
+func codeSet(cfg *config.Config) (*lib.CodeSet, error) {
+	keyed, err := keyedCodecs(cfg.ShortCodeKeys)
+	if err != nil {
+		return nil, err
+	}
+	hex, base57 := lib.HexCodec{}, lib.NewBase57Codec()
+
+	var encoder lib.Codec
+	switch cfg.ShortCodeCodec {
+	case "", "hex":
+		encoder = hex
+	case "base57":
+		encoder = base57
+	case "keyed":
+		if len(keyed) == 0 {
+			return nil, errors.New("the keyed short code codec needs at least one SHORT_CODE_KEYS entry")
+		}
+		// Keys are sorted, so the last one is the current version
+		encoder = keyed[len(keyed)-1]
+	default:
+		return nil, fmt.Errorf("unknown short code codec %q", cfg.ShortCodeCodec)
+	}
+	// Hex and base-57 codes have no room for check characters: appended ones would
+	// still read as a valid code of the same format, so they cannot be verified
+	if _, ok := encoder.(*lib.KeyedCodec); !ok {
+		for _, codec := range keyed {
+			if codec.Checks() > 0 {
+				return nil, errors.New("short code check characters need SHORT_CODE_CODEC=keyed")
+			}
+		}
+	}
+
+	decoders := []lib.Codec{hex, base57}
+	for _, codec := range keyed {
+		decoders = append(decoders, codec)
+	}
+	return lib.NewCodeSet(encoder, decoders...), nil
+}
+
+// blocklist combines the configured blocked words with the curated defaults.
+func blocklist(cfg *config.Config) *lib.Blocklist {
+	var words []string
+	if !cfg.NoDefaultBlockedWords {
+		words = append(words, lib.DefaultBlockedWords...)
+	}
+	return lib.NewBlocklist(append(words, cfg.BlockedWords...))
+}
+
+// codeBlocklist combines the configured blocked words with the default words the
+// alphabet of new codes can spell.
+func codeBlocklist(cfg *config.Config, codes *lib.CodeSet) *lib.Blocklist {
+	var words []string
+	if !cfg.NoDefaultBlockedWords {
+		words = append(words, lib.DefaultCodeWords(codes.Alphabet())...)
+	}
+	return lib.NewBlocklist(append(words, cfg.BlockedWords...))
+}
+
+// keyedCodecs parses "version[/checks]:secret" keys into codecs ordered by version.
+func keyedCodecs(keys []string) ([]*lib.KeyedCodec, error) {
+	byVersion := make(map[int]*lib.KeyedCodec, len(keys))
+	versions := make([]int, 0, len(keys))
+	for _, key := range keys {
+		version, checks, secret, err := parseCodeKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if byVersion[version] != nil {
+			return nil, fmt.Errorf("short code key version %d is set twice", version)
+		}
+		codec, err := lib.NewKeyedCodec(version, []byte(secret), checks)
+		if err != nil {
+			return nil, fmt.Errorf("short code key version %d: %w", version, err)
+		}
+		byVersion[version] = codec
+		versions = append(versions, version)
+	}
+
+	sort.Ints(versions)
+	codecs := make([]*lib.KeyedCodec, len(versions))
+	for i, version := range versions {
+		codecs[i] = byVersion[version]
+	}
+	return codecs, nil
+}
+
+// parseCodeKey splits a "version[/checks]:secret" key. The error never quotes the
+// key, which holds the secret.
+func parseCodeKey(key string) (version, checks int, secret string, err error) {
+	prefix, secret, ok := strings.Cut(key, ":")
+	rawVersion, rawChecks, hasChecks := strings.Cut(prefix, "/")
+	version, versionErr := strconv.Atoi(rawVersion)
+	if hasChecks {
+		checks, err = strconv.Atoi(rawChecks)
+	}
+	if !ok || versionErr != nil || err != nil {
+		return 0, 0, "", errors.New("short code keys must be version:secret or version/checks:secret")
 	}
+	return version, checks, secret, nil
 }
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// APIKey identifies the owner of the links created with it. Only the SHA-256 hash
+// of the key is stored; the key itself is shown once when it is issued.
+type APIKey struct {
+	ID        int64
+	OwnerID   string
+	Name      string
+	KeyHash   string
+	CreatedAt time.Time
+	RevokedAt *time.Time
+}
@@ -0,0 +1,8 @@
+package entity
+
+// BatchLinkResult is the outcome of one item in a batch create.
+// Exactly one of ShortCode or Err is set.
+type BatchLinkResult struct {
+	ShortCode string
+	Err       error
+}
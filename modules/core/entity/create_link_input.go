@@ -0,0 +1,22 @@
+package entity
+
+import "time"
+
+// CreateLinkInput holds the caller-supplied options for a new short link.
+type CreateLinkInput struct {
+	LongURL    string
+	TTLSeconds *int64
+	Alias      *string
+
+	// ExpiresAt is an absolute alternative to TTLSeconds.
+	ExpiresAt *time.Time
+	// NotBefore delays activation; the link does not redirect before this time.
+	NotBefore *time.Time
+	// MaxClicks retires the link after that many successful redirects.
+	MaxClicks *int64
+	// Password protects the link; it is stored only as a slow hash.
+	Password *string
+
+	// Dedupe overrides the server default for reusing an active link to the same URL.
+	Dedupe *bool
+}
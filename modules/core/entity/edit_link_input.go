@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// EditLinkInput holds the changes requested for an existing short link.
+// Unset fields are left as they are.
+type EditLinkInput struct {
+	LongURL *string
+
+	// TTLSeconds and ExpiresAt replace the expiry; TTLSeconds counts from now,
+	// or from the activation time of a scheduled link.
+	TTLSeconds *int64
+	ExpiresAt  *time.Time
+}
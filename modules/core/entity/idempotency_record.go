@@ -0,0 +1,10 @@
+package entity
+
+// IdempotencyRecord is the stored outcome of a request made with an Idempotency-Key.
+// A record without Completed set marks a request that is still in flight.
+type IdempotencyRecord struct {
+	Fingerprint string `json:"fingerprint"`
+	Completed   bool   `json:"completed"`
+	StatusCode  int    `json:"status_code,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// Link is the result of creating a short link.
+type Link struct {
+	ID        int64
+	ShortCode string
+	LongURL   string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+	NotBefore *time.Time
+	MaxClicks *int64
+
+	PasswordProtected bool
+
+	// Reused is set when an active link to the same destination was returned
+	// instead of minting a new one.
+	Reused bool
+}
@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// LinkChange records one edit of a link's destination or expiry.
+type LinkChange struct {
+	ID           int64
+	URLID        int64
+	OldLongURL   string
+	NewLongURL   string
+	OldExpiresAt time.Time
+	NewExpiresAt time.Time
+	ChangedAt    time.Time
+}
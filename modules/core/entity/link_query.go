@@ -0,0 +1,75 @@
+package entity
+
+import "time"
+
+// LinkSort names a sort key of a link listing.
+type LinkSort string
+
+const (
+	SortCreatedAt      LinkSort = "created_at"
+	SortClickCount     LinkSort = "click_count"
+	SortLastAccessedAt LinkSort = "last_accessed_at"
+)
+
+// LinkStatus splits links into active, expired and revoked. A revoked link is
+// reported as revoked whether or not it has also expired.
+type LinkStatus string
+
+const (
+	LinkStatusActive  LinkStatus = "active"
+	LinkStatusExpired LinkStatus = "expired"
+	LinkStatusRevoked LinkStatus = "revoked"
+)
+
+// ListLinksInput holds the caller-supplied filters, order and page of a link listing.
+type ListLinksInput struct {
+	// Domain is a destination host, or "*.example.com" for its subdomains.
+	Domain        string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Status        LinkStatus
+	MinClicks     *int64
+
+	Sort      LinkSort
+	Ascending bool
+	Limit     int
+	// Cursor is the NextCursor of the previous page.
+	Cursor string
+}
+
+// LinkQuery is a validated listing of one owner's links, as run against storage.
+type LinkQuery struct {
+	OwnerID       string
+	Domain        string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Status        LinkStatus
+	MinClicks     *int64
+	Now           time.Time
+
+	Sort      LinkSort
+	Ascending bool
+	Limit     int
+	// After continues the listing past this position.
+	After *LinkCursor
+}
+
+// LinkCursor is the position of the last link of a page: its sort value and
+// analytics row ID. Time is used by the time sorts, Count by the click count sort.
+type LinkCursor struct {
+	Time  time.Time
+	Count int64
+	ID    int64
+}
+
+// LinkPage is one page of a link listing. NextCursor is empty on the last page.
+type LinkPage struct {
+	Links      []*ListedLink
+	NextCursor string
+}
+
+// ListedLink is a link of a listing with its generated short code.
+type ListedLink struct {
+	*URLAnalytic
+	ShortCode string
+}
@@ -6,4 +6,11 @@ type URL struct {
 	ID        int64
 	LongURL   string
 	ExpiresAt time.Time
+
+	// NotBefore is set for links scheduled to go live later.
+	NotBefore *time.Time
+	// MaxClicks is set for links that stop redirecting after that many clicks.
+	MaxClicks *int64
+	// PasswordHash is the bcrypt hash of the link password, empty for unprotected links.
+	PasswordHash string
 }
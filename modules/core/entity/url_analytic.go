@@ -5,9 +5,23 @@ import "time"
 type URLAnalytic struct {
 	ID             int64
 	URLID          int64
+	Alias          *string
 	LongURL        string
 	CreatedAt      time.Time
 	ExpiresAt      time.Time
+	NotBefore      *time.Time
+	MaxClicks      *int64
 	ClickCount     int64
 	LastAccessedAt *time.Time
+
+	// OwnerID is the owner of the API key that created the link, nil for anonymous links.
+	OwnerID *string
+
+	// RevokedAt is set once the link has been revoked; RevokeReason is optional.
+	RevokedAt    *time.Time
+	RevokeReason *string
+
+	// PasswordHash is the bcrypt hash of the link password, empty for unprotected
+	// links. It is nil for links recorded before hashes were kept here.
+	PasswordHash *string
 }
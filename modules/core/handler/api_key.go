@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/service"
+)
+
+// APIKeyAuth identifies callers by the API key in "Authorization: Bearer <key>".
+type APIKeyAuth struct {
+	service       service.APIKeyAuthenticator
+	requireForNew bool
+	adminOwners   map[string]bool
+}
+
+// NewAPIKeyAuth creates the middleware. With requireForNew set, links can only be
+// created with a key; otherwise anonymous callers may create unowned links.
+func NewAPIKeyAuth(svc service.APIKeyAuthenticator, requireForNew bool) *APIKeyAuth {
+	return &APIKeyAuth{service: svc, requireForNew: requireForNew}
+}
+
+// WithAdminOwners makes the keys of these owners admin keys, which can edit and
+// revoke every link, including links created without a key.
+func (a *APIKeyAuth) WithAdminOwners(ownerIDs []string) *APIKeyAuth {
+	a.adminOwners = make(map[string]bool, len(ownerIDs))
+	for _, ownerID := range ownerIDs {
+		a.adminOwners[ownerID] = true
+	}
+	return a
+}
+
+// Resolve attaches the key's owner to the request context, marking admin owners as
+// such. Requests without a key continue anonymously; a key that is unknown or
+// revoked is refused with 401.
+func (a *APIKeyAuth) Resolve(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		header := c.Request().Header.Get(echo.HeaderAuthorization)
+		if header == "" {
+			return next(c)
+		}
+
+		rawKey, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			return unauthorized(c, "authorization must be a Bearer API key")
+		}
+
+		ownerID, err := a.service.Authenticate(c.Request().Context(), strings.TrimSpace(rawKey))
+		if err != nil {
+			if errors.Is(err, service.ErrInvalidAPIKey) {
+				return unauthorized(c, err.Error())
+			}
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+		}
+
+		ctx := service.WithOwner(c.Request().Context(), ownerID)
+		if a.adminOwners[ownerID] {
+			ctx = service.WithAdmin(ctx)
+		}
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
+}
+
+// RequireForCreate refuses anonymous link creation when the server is configured
+// to require an API key. It must run after Resolve.
+func (a *APIKeyAuth) RequireForCreate(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if _, ok := service.OwnerFromContext(c.Request().Context()); !ok && a.requireForNew {
+			return unauthorized(c, "an API key is required to create links")
+		}
+		return next(c)
+	}
+}
+
+func unauthorized(c echo.Context, message string) error {
+	c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+	return c.JSON(http.StatusUnauthorized, ErrorResponse{Error: message})
+}
@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/service"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		requireForNew bool
+		adminOwners   []string
+		mockOwner     string
+		mockError     error
+		expectCall    bool
+		expectStatus  int
+		expectOwner   string
+		expectAdmin   bool
+	}{
+		{
+			name:          "valid_key_sets_owner",
+			authorization: "Bearer doit_valid",
+			mockOwner:     "team-a",
+			expectCall:    true,
+			expectStatus:  http.StatusOK,
+			expectOwner:   "team-a",
+		},
+		{
+			name:          "admin_owner_key_is_marked_admin",
+			authorization: "Bearer doit_valid",
+			adminOwners:   []string{"ops"},
+			mockOwner:     "ops",
+			expectCall:    true,
+			expectStatus:  http.StatusOK,
+			expectOwner:   "ops",
+			expectAdmin:   true,
+		},
+		{
+			name:          "other_owner_key_is_not_admin",
+			authorization: "Bearer doit_valid",
+			adminOwners:   []string{"ops"},
+			mockOwner:     "team-a",
+			expectCall:    true,
+			expectStatus:  http.StatusOK,
+			expectOwner:   "team-a",
+		},
+		{
+			name:         "anonymous_request_passes_through",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:          "anonymous_request_refused_when_key_required",
+			requireForNew: true,
+			expectStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:          "valid_key_accepted_when_key_required",
+			authorization: "Bearer doit_valid",
+			requireForNew: true,
+			mockOwner:     "team-a",
+			expectCall:    true,
+			expectStatus:  http.StatusOK,
+			expectOwner:   "team-a",
+		},
+		{
+			name:          "invalid_key_returns_401",
+			authorization: "Bearer doit_unknown",
+			mockError:     service.ErrInvalidAPIKey,
+			expectCall:    true,
+			expectStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:          "non_bearer_scheme_returns_401",
+			authorization: "Basic dXNlcjpwYXNz",
+			expectStatus:  http.StatusUnauthorized,
+		},
+		{
+			name:          "lookup_failure_returns_500",
+			authorization: "Bearer doit_valid",
+			mockError:     errors.New("connection refused"),
+			expectCall:    true,
+			expectStatus:  http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockService := mocks.NewMockAPIKeyAuthenticator(ctrl)
+			if tt.expectCall {
+				mockService.EXPECT().
+					Authenticate(gomock.Any(), tt.authorization[len("Bearer "):]).
+					Return(tt.mockOwner, tt.mockError)
+			}
+			auth := NewAPIKeyAuth(mockService, tt.requireForNew).WithAdminOwners(tt.adminOwners)
+
+			var owner string
+			var admin bool
+			e := echo.New()
+			e.POST("/s", func(c echo.Context) error {
+				owner, _ = service.OwnerFromContext(c.Request().Context())
+				admin = service.IsAdmin(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			}, auth.Resolve, auth.RequireForCreate)
+
+			req := httptest.NewRequest(http.MethodPost, "/s", nil)
+			if tt.authorization != "" {
+				req.Header.Set(echo.HeaderAuthorization, tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			if owner != tt.expectOwner {
+				t.Errorf("expected owner %q, got %q", tt.expectOwner, owner)
+			}
+			if admin != tt.expectAdmin {
+				t.Errorf("expected admin=%v, got %v", tt.expectAdmin, admin)
+			}
+			if rec.Code == http.StatusUnauthorized && rec.Header().Get(echo.HeaderWWWAuthenticate) != "Bearer" {
+				t.Error("expected WWW-Authenticate: Bearer on 401")
+			}
+		})
+	}
+}
@@ -7,19 +7,35 @@ import (
 )
 
 type HealthzHandler struct {
-	checks []HealthCheck
+	checks   []HealthCheck
+	degraded []namedHealthCheck
 }
 
 type HealthCheck func() error
 
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+// HealthResponse is "healthy", "degraded" or "unhealthy". Failing lists the
+// degraded checks that failed.
 type HealthResponse struct {
-	Status string `json:"status"`
+	Status  string   `json:"status"`
+	Failing []string `json:"failing,omitempty"`
 }
 
 func NewHealthzHandler(checks ...HealthCheck) *HealthzHandler {
 	return &HealthzHandler{checks: checks}
 }
 
+// WithDegradedCheck adds a check whose failure is reported by name but keeps the
+// status at 200, for failures that leave redirects working.
+func (h *HealthzHandler) WithDegradedCheck(name string, check HealthCheck) *HealthzHandler {
+	h.degraded = append(h.degraded, namedHealthCheck{name: name, check: check})
+	return h
+}
+
 func (h *HealthzHandler) Handle(c echo.Context) error {
 	for _, check := range h.checks {
 		if err := check(); err != nil {
@@ -27,5 +43,15 @@ func (h *HealthzHandler) Handle(c echo.Context) error {
 		}
 	}
 
+	var failing []string
+	for _, degraded := range h.degraded {
+		if err := degraded.check(); err != nil {
+			failing = append(failing, degraded.name)
+		}
+	}
+	if len(failing) > 0 {
+		return c.JSON(http.StatusOK, HealthResponse{Status: "degraded", Failing: failing})
+	}
+
 	return c.JSON(http.StatusOK, HealthResponse{Status: "healthy"})
 }
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/labstack/echo/v4"
@@ -49,3 +50,59 @@ func TestHealthzHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthzHandler_DegradedChecks(t *testing.T) {
+	tests := []struct {
+		name         string
+		checkError   error
+		degraded     map[string]error
+		expectStatus int
+		expectBody   string
+	}{
+		{
+			name:         "passing_degraded_check_is_healthy",
+			degraded:     map[string]error{"id_sequence": nil},
+			expectStatus: http.StatusOK,
+			expectBody:   `{"status":"healthy"}`,
+		},
+		{
+			name:         "failing_degraded_check_is_named",
+			degraded:     map[string]error{"id_sequence": errors.New("unverified")},
+			expectStatus: http.StatusOK,
+			expectBody:   `{"status":"degraded","failing":["id_sequence"]}`,
+		},
+		{
+			name:         "failing_required_check_wins",
+			checkError:   errors.New("db connection failed"),
+			degraded:     map[string]error{"id_sequence": errors.New("unverified")},
+			expectStatus: http.StatusServiceUnavailable,
+			expectBody:   `{"status":"unhealthy"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+
+			handler := NewHealthzHandler(func() error {
+				return tt.checkError
+			})
+			for name, err := range tt.degraded {
+				handler.WithDegradedCheck(name, func() error { return err })
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = handler.Handle(c)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			if body := strings.TrimSpace(rec.Body.String()); body != tt.expectBody {
+				t.Errorf("expected body %s, got %s", tt.expectBody, body)
+			}
+		})
+	}
+}
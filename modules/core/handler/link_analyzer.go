@@ -12,9 +12,13 @@ import (
 type AnalyzeResponse struct {
 	LongURL        string  `json:"long_url"`
 	CreatedAt      string  `json:"created_at"`
+	NotBefore      *string `json:"not_before"`
 	ExpiresAt      string  `json:"expires_at"`
+	MaxClicks      *int64  `json:"max_clicks"`
 	ClickCount     int64   `json:"click_count"`
 	LastAccessedAt *string `json:"last_accessed_at"`
+	RevokedAt      *string `json:"revoked_at"`
+	RevokeReason   *string `json:"revoke_reason"`
 }
 
 type LinkAnalyzerHandler struct {
@@ -34,22 +38,21 @@ func (h *LinkAnalyzerHandler) Handle(c echo.Context) error {
 	analytic, err := h.service.Analyze(c.Request().Context(), shortCode)
 	if err != nil {
 		if errors.Is(err, service.ErrNotFound) {
+			recordChecksumFailure(err, "stats")
 			return c.NoContent(http.StatusNotFound)
 		}
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
 	}
 
-	var lastAccessedAt *string
-	if analytic.LastAccessedAt != nil {
-		formatted := analytic.LastAccessedAt.Format(time.RFC3339)
-		lastAccessedAt = &formatted
-	}
-
 	return c.JSON(http.StatusOK, AnalyzeResponse{
 		LongURL:        analytic.LongURL,
 		CreatedAt:      analytic.CreatedAt.Format(time.RFC3339),
+		NotBefore:      formatOptionalTime(analytic.NotBefore),
 		ExpiresAt:      analytic.ExpiresAt.Format(time.RFC3339),
+		MaxClicks:      analytic.MaxClicks,
 		ClickCount:     analytic.ClickCount,
-		LastAccessedAt: lastAccessedAt,
+		LastAccessedAt: formatOptionalTime(analytic.LastAccessedAt),
+		RevokedAt:      formatOptionalTime(analytic.RevokedAt),
+		RevokeReason:   analytic.RevokeReason,
 	})
 }
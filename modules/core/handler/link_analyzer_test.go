@@ -62,6 +62,53 @@ func TestLinkAnalyzerHandler(t *testing.T) {
 			mockError:      nil,
 			expectContains: ptr("42"),
 		},
+		{
+			name:      "scheduled_link_reports_both_bounds",
+			shortCode: "abc123",
+			mockReturn: &entity.URLAnalytic{
+				LongURL:   "https://example.com",
+				CreatedAt: fixedTime,
+				NotBefore: &lastAccessed,
+				ExpiresAt: fixedTime.Add(24 * time.Hour),
+			},
+			mockError:      nil,
+			expectContains: ptr(`"not_before":"2024-01-01T13:00:00Z","expires_at":"2024-01-02T12:00:00Z"`),
+		},
+		{
+			name:      "immediate_link_reports_null_not_before",
+			shortCode: "abc123",
+			mockReturn: &entity.URLAnalytic{
+				LongURL:   "https://example.com",
+				CreatedAt: fixedTime,
+				ExpiresAt: fixedTime.Add(24 * time.Hour),
+			},
+			mockError:      nil,
+			expectContains: ptr(`"not_before":null`),
+		},
+		{
+			name:      "revoked_link_reports_time_and_reason",
+			shortCode: "abc123",
+			mockReturn: &entity.URLAnalytic{
+				LongURL:      "https://example.com",
+				CreatedAt:    fixedTime,
+				ExpiresAt:    fixedTime.Add(24 * time.Hour),
+				RevokedAt:    &lastAccessed,
+				RevokeReason: ptr("phishing"),
+			},
+			mockError:      nil,
+			expectContains: ptr(`"revoked_at":"2024-01-01T13:00:00Z","revoke_reason":"phishing"`),
+		},
+		{
+			name:      "live_link_reports_null_revoked_at",
+			shortCode: "abc123",
+			mockReturn: &entity.URLAnalytic{
+				LongURL:   "https://example.com",
+				CreatedAt: fixedTime,
+				ExpiresAt: fixedTime.Add(24 * time.Hour),
+			},
+			mockError:      nil,
+			expectContains: ptr(`"revoked_at":null`),
+		},
 		{
 			name:         "not_found_returns_404",
 			shortCode:    "notfound",
@@ -1,32 +1,98 @@
 package handler
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/entity"
 	"github.com/nanda/doit/modules/core/service"
 )
 
+// CreateLinkRequest is the body of POST /s. ExpiresAt and NotBefore are RFC3339
+// timestamps; ExpiresAt is an alternative to TTLSeconds.
 type CreateLinkRequest struct {
+	LongURL    string     `json:"long_url"`
+	TTLSeconds *int64     `json:"ttl_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	NotBefore  *time.Time `json:"not_before,omitempty"`
+	MaxClicks  *int64     `json:"max_clicks,omitempty"`
+	Password   *string    `json:"password,omitempty"`
+	Alias      *string    `json:"alias,omitempty"`
+	Dedupe     *bool      `json:"dedupe,omitempty"`
+}
+
+type CreateLinkResponse struct {
+	ShortCode string  `json:"short_code"`
+	ShortURL  string  `json:"short_url"`
+	LongURL   string  `json:"long_url"`
+	CreatedAt string  `json:"created_at"`
+	ExpiresAt string  `json:"expires_at"`
+	NotBefore *string `json:"not_before,omitempty"`
+	MaxClicks *int64  `json:"max_clicks,omitempty"`
+	Reused    bool    `json:"reused"`
+
+	PasswordProtected bool `json:"password_protected,omitempty"`
+}
+
+// BatchCreateLinkItem is one entry of the POST /s/batch request array.
+type BatchCreateLinkItem struct {
 	LongURL    string `json:"long_url"`
 	TTLSeconds *int64 `json:"ttl_seconds,omitempty"`
 }
 
-type CreateLinkResponse struct {
-	ShortCode string `json:"short_code"`
+// BatchCreateLinkResult reports either the short code or the validation error for one item.
+type BatchCreateLinkResult struct {
+	ShortCode string `json:"short_code,omitempty"`
+	Error     string `json:"error,omitempty"`
+	Reason    string `json:"reason,omitempty"`
 }
 
+type BatchCreateLinkResponse struct {
+	Results []BatchCreateLinkResult `json:"results"`
+}
+
+// ErrorResponse is the body of every error. Reason is a machine-readable code set
+// when a destination policy refuses the URL.
 type ErrorResponse struct {
-	Error string `json:"error"`
+	Error  string `json:"error"`
+	Reason string `json:"reason,omitempty"`
 }
 
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
 type LinkCreatorHandler struct {
-	service service.LinkCreator
+	service        service.LinkCreator
+	idempotency    service.IdempotencyGuard
+	baseURL        string
+	fingerprintKey []byte
+}
+
+// NewLinkCreatorHandler creates the handler. baseURL is the public origin used to
+// build short URLs. A nil idempotency guard disables Idempotency-Key support.
+// Passwords are fingerprinted with a random per-process key until
+// WithFingerprintKey sets a shared one.
+func NewLinkCreatorHandler(svc service.LinkCreator, idempotency service.IdempotencyGuard, baseURL string) *LinkCreatorHandler {
+	key := make([]byte, sha256.Size)
+	_, _ = rand.Read(key)
+	return &LinkCreatorHandler{service: svc, idempotency: idempotency, baseURL: baseURL, fingerprintKey: key}
 }
 
-func NewLinkCreatorHandler(svc service.LinkCreator) *LinkCreatorHandler {
-	return &LinkCreatorHandler{service: svc}
+// WithFingerprintKey sets the secret that keys the password in request
+// fingerprints. Instances behind one idempotency store must share it, or a retry
+// that reaches another instance is rejected as a different request.
+func (h *LinkCreatorHandler) WithFingerprintKey(key []byte) *LinkCreatorHandler {
+	h.fingerprintKey = key
+	return h
 }
 
 func (h *LinkCreatorHandler) Handle(c echo.Context) error {
@@ -39,23 +105,234 @@ func (h *LinkCreatorHandler) Handle(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "long_url is required"})
 	}
 
-	shortCode, err := h.service.Create(c.Request().Context(), req.LongURL, req.TTLSeconds)
+	key := c.Request().Header.Get(HeaderIdempotencyKey)
+	if key != "" && h.idempotency != nil {
+		return h.handleIdempotent(c, key, req)
+	}
+
+	status, body := h.create(c, req)
+	h.setLocation(c, body)
+	return c.JSON(status, body)
+}
+
+// handleIdempotent runs the create at most once per key. Replays with the same body
+// get the stored response; replays with a different body are rejected.
+func (h *LinkCreatorHandler) handleIdempotent(c echo.Context, key string, req CreateLinkRequest) error {
+	ctx := c.Request().Context()
+	fingerprint, err := h.requestFingerprint(req)
 	if err != nil {
-		return handleServiceError(c, err)
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
 	}
 
-	return c.JSON(http.StatusOK, CreateLinkResponse{ShortCode: shortCode})
+	record, err := h.idempotency.Begin(ctx, key, fingerprint)
+	if err != nil {
+		return c.JSON(idempotencyErrorResponse(err))
+	}
+	if record != nil {
+		return h.replay(c, record)
+	}
+
+	status, body := h.create(c, req)
+	h.setLocation(c, body)
+	encoded, err := json.Marshal(body)
+	if err != nil || status >= http.StatusInternalServerError {
+		// Server-side failures are not final; free the key so a retry can run again
+		_ = h.idempotency.Abort(ctx, key)
+		return c.JSON(status, body)
+	}
+
+	if err := h.idempotency.Complete(ctx, key, fingerprint, status, encoded); err != nil {
+		c.Logger().Errorf("failed to store idempotent response: %v", err)
+	}
+
+	return c.JSONBlob(status, encoded)
 }
 
-func handleServiceError(c echo.Context, err error) error {
+// idempotencyErrorResponse maps a failure to begin an idempotent request to a
+// response status and body.
+func idempotencyErrorResponse(err error) (int, ErrorResponse) {
 	switch {
-	case errors.Is(err, service.ErrInvalidURL):
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
-	case errors.Is(err, service.ErrURLTooLong):
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
-	case errors.Is(err, service.ErrInvalidTTL):
-		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
-	default:
+	case errors.Is(err, service.ErrInvalidIdempotencyKey):
+		return http.StatusBadRequest, ErrorResponse{Error: err.Error()}
+	case errors.Is(err, service.ErrIdempotencyInProgress):
+		return http.StatusConflict, ErrorResponse{Error: err.Error()}
+	case errors.Is(err, service.ErrIdempotencyKeyReused):
+		return http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error()}
+	}
+	return http.StatusInternalServerError, ErrorResponse{Error: "internal server error"}
+}
+
+// replay answers with the response stored for an idempotency key.
+func (h *LinkCreatorHandler) replay(c echo.Context, record *entity.IdempotencyRecord) error {
+	var replayed CreateLinkResponse
+	if json.Unmarshal(record.Body, &replayed) == nil {
+		h.setLocation(c, replayed)
+	}
+	c.Response().Header().Set(HeaderIdempotentReplayed, "true")
+	return c.JSONBlob(record.StatusCode, record.Body)
+}
+
+// create calls the service and returns the response status and body.
+func (h *LinkCreatorHandler) create(c echo.Context, req CreateLinkRequest) (int, any) {
+	link, err := h.service.Create(c.Request().Context(), entity.CreateLinkInput{
+		LongURL:    req.LongURL,
+		TTLSeconds: req.TTLSeconds,
+		ExpiresAt:  req.ExpiresAt,
+		NotBefore:  req.NotBefore,
+		MaxClicks:  req.MaxClicks,
+		Password:   req.Password,
+		Alias:      req.Alias,
+		Dedupe:     req.Dedupe,
+	})
+	if err != nil {
+		return serviceErrorResponse(err)
+	}
+
+	// A reused link already existed, so nothing was created
+	status := http.StatusCreated
+	if link.Reused {
+		status = http.StatusOK
+	}
+
+	return status, newLinkResponse(h.baseURL, link)
+}
+
+// newLinkResponse describes a link with short URLs built from baseURL.
+func newLinkResponse(baseURL string, link *entity.Link) CreateLinkResponse {
+	return CreateLinkResponse{
+		ShortCode: link.ShortCode,
+		ShortURL:  baseURL + "/s/" + link.ShortCode,
+		LongURL:   link.LongURL,
+		CreatedAt: link.CreatedAt.Format(time.RFC3339),
+		ExpiresAt: link.ExpiresAt.Format(time.RFC3339),
+		NotBefore: formatOptionalTime(link.NotBefore),
+		MaxClicks: link.MaxClicks,
+		Reused:    link.Reused,
+
+		PasswordProtected: link.PasswordProtected,
+	}
+}
+
+// formatOptionalTime formats t as RFC3339, or returns nil when t is unset.
+func formatOptionalTime(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	formatted := t.Format(time.RFC3339)
+	return &formatted
+}
+
+// setLocation points the Location header at the stats resource of a created link.
+func (h *LinkCreatorHandler) setLocation(c echo.Context, body any) {
+	if resp, ok := body.(CreateLinkResponse); ok {
+		c.Response().Header().Set(echo.HeaderLocation, h.baseURL+"/stats/"+resp.ShortCode)
+	}
+}
+
+// requestFingerprint hashes the decoded request so formatting differences in the
+// raw body do not count as a different request. The fingerprint is stored, so the
+// password goes in as an HMAC under a server secret: a plain fast hash of it would
+// undo the bcrypt.
+func (h *LinkCreatorHandler) requestFingerprint(req CreateLinkRequest) (string, error) {
+	if req.Password != nil {
+		mac := hmac.New(sha256.New, h.fingerprintKey)
+		mac.Write([]byte(*req.Password))
+		keyed := hex.EncodeToString(mac.Sum(nil))
+		req.Password = &keyed
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// HandleBatch creates many links in one request. Results are returned in request order.
+func (h *LinkCreatorHandler) HandleBatch(c echo.Context) error {
+	var items []BatchCreateLinkItem
+	if err := c.Bind(&items); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+	}
+
+	inputs := make([]entity.CreateLinkInput, len(items))
+	for i, item := range items {
+		inputs[i] = entity.CreateLinkInput{
+			LongURL:    item.LongURL,
+			TTLSeconds: item.TTLSeconds,
+		}
+	}
+
+	results, err := h.service.CreateBatch(c.Request().Context(), inputs)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidBatchSize):
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, service.ErrIDSequenceUnverified):
+			return c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+		}
 		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
 	}
+
+	resp := BatchCreateLinkResponse{Results: make([]BatchCreateLinkResult, len(results))}
+	for i, result := range results {
+		if result.Err != nil {
+			resp.Results[i].Error = result.Err.Error()
+			resp.Results[i].Reason = rejectionReason(result.Err)
+			continue
+		}
+		resp.Results[i].ShortCode = result.ShortCode
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// badRequestErrors are the service errors that reject the request body.
+var badRequestErrors = []error{
+	service.ErrInvalidURL,
+	service.ErrURLTooLong,
+	service.ErrInvalidTTL,
+	service.ErrConflictingExpiry,
+	service.ErrNotBeforeTooLate,
+	service.ErrEmptyEdit,
+	service.ErrInvalidMaxClicks,
+	service.ErrInvalidPassword,
+	service.ErrInvalidAlias,
+	service.ErrBlockedAlias,
+}
+
+// serviceErrorResponse maps a service error to its HTTP status and body.
+func serviceErrorResponse(err error) (int, ErrorResponse) {
+	switch {
+	case errors.Is(err, service.ErrDestinationRejected):
+		return http.StatusUnprocessableEntity, ErrorResponse{Error: err.Error(), Reason: rejectionReason(err)}
+	case errors.Is(err, service.ErrAliasTaken):
+		return http.StatusConflict, ErrorResponse{Error: err.Error()}
+	case errors.Is(err, service.ErrForbidden):
+		return http.StatusForbidden, ErrorResponse{Error: err.Error()}
+	case errors.Is(err, service.ErrIDSequenceUnverified):
+		return http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()}
+	case isAnyError(err, badRequestErrors):
+		return http.StatusBadRequest, ErrorResponse{Error: err.Error()}
+	default:
+		return http.StatusInternalServerError, ErrorResponse{Error: "internal server error"}
+	}
+}
+
+func isAnyError(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectionReason returns the machine-readable reason of a destination policy rejection.
+func rejectionReason(err error) string {
+	var violation *service.PolicyViolation
+	if errors.As(err, &violation) {
+		return violation.Reason
+	}
+	return ""
 }
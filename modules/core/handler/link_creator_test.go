@@ -1,12 +1,16 @@
 package handler
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/entity"
 	"github.com/nanda/doit/modules/core/internal/test/mocks"
 	"github.com/nanda/doit/modules/core/service"
 	"go.uber.org/mock/gomock"
@@ -20,58 +24,157 @@ func TestLinkCreatorHandler(t *testing.T) {
 	tests := []struct {
 		name           string
 		requestBody    string
-		mockReturn     string
+		mockReturn     *entity.Link
 		mockError      error
 		expectStatus   *int
 		expectContains *string
 		expectTTL      *int64
+		expectLocation *string
 	}{
 		{
-			name:         "successful_creation_returns_200",
+			name:         "successful_creation_returns_201",
 			requestBody:  `{"long_url":"https://example.com"}`,
-			mockReturn:   "abc123",
+			mockReturn:   &entity.Link{ShortCode: "abc123"},
+			mockError:    nil,
+			expectStatus: ptr(http.StatusCreated),
+		},
+		{
+			name:         "reused_link_returns_200",
+			requestBody:  `{"long_url":"https://example.com","dedupe":true}`,
+			mockReturn:   &entity.Link{ShortCode: "abc123", Reused: true},
 			mockError:    nil,
 			expectStatus: ptr(http.StatusOK),
 		},
+		{
+			name:           "successful_creation_returns_short_url",
+			requestBody:    `{"long_url":"https://example.com"}`,
+			mockReturn:     &entity.Link{ShortCode: "abc123"},
+			mockError:      nil,
+			expectContains: ptr(`"short_url":"https://sho.rt/s/abc123"`),
+		},
+		{
+			name:           "successful_creation_sets_location_to_stats",
+			requestBody:    `{"long_url":"https://example.com"}`,
+			mockReturn:     &entity.Link{ShortCode: "abc123"},
+			mockError:      nil,
+			expectLocation: ptr("https://sho.rt/stats/abc123"),
+		},
 		{
 			name:           "successful_creation_returns_short_code",
 			requestBody:    `{"long_url":"https://example.com"}`,
-			mockReturn:     "abc123",
+			mockReturn:     &entity.Link{ShortCode: "abc123"},
 			mockError:      nil,
 			expectContains: ptr("abc123"),
 		},
 		{
 			name:         "missing_long_url_returns_400",
 			requestBody:  `{}`,
-			mockReturn:   "",
+			mockReturn:   nil,
 			mockError:    nil,
 			expectStatus: ptr(http.StatusBadRequest),
 		},
 		{
 			name:         "invalid_url_error_returns_400",
 			requestBody:  `{"long_url":"not-a-url"}`,
-			mockReturn:   "",
+			mockReturn:   nil,
 			mockError:    service.ErrInvalidURL,
 			expectStatus: ptr(http.StatusBadRequest),
 		},
 		{
 			name:         "url_too_long_error_returns_400",
 			requestBody:  `{"long_url":"https://example.com"}`,
-			mockReturn:   "",
+			mockReturn:   nil,
 			mockError:    service.ErrURLTooLong,
 			expectStatus: ptr(http.StatusBadRequest),
 		},
 		{
 			name:         "invalid_ttl_error_returns_400",
 			requestBody:  `{"long_url":"https://example.com","ttl_seconds":60}`,
-			mockReturn:   "",
+			mockReturn:   nil,
 			mockError:    service.ErrInvalidTTL,
 			expectStatus: ptr(http.StatusBadRequest),
 		},
+		{
+			name:         "invalid_alias_error_returns_400",
+			requestBody:  `{"long_url":"https://example.com","alias":"cafe"}`,
+			mockReturn:   nil,
+			mockError:    service.ErrInvalidAlias,
+			expectStatus: ptr(http.StatusBadRequest),
+		},
+		{
+			name:         "alias_taken_error_returns_409",
+			requestBody:  `{"long_url":"https://example.com","alias":"spring-promo"}`,
+			mockReturn:   nil,
+			mockError:    service.ErrAliasTaken,
+			expectStatus: ptr(http.StatusConflict),
+		},
+		{
+			name:         "unverified_id_sequence_returns_503",
+			requestBody:  `{"long_url":"https://example.com"}`,
+			mockReturn:   nil,
+			mockError:    service.ErrIDSequenceUnverified,
+			expectStatus: ptr(http.StatusServiceUnavailable),
+		},
+		{
+			name:         "rejected_destination_returns_422",
+			requestBody:  `{"long_url":"http://localhost/admin"}`,
+			mockReturn:   nil,
+			mockError:    &service.PolicyViolation{Reason: service.ReasonInternalHost},
+			expectStatus: ptr(http.StatusUnprocessableEntity),
+		},
+		{
+			name:           "rejected_destination_returns_reason",
+			requestBody:    `{"long_url":"https://phish.example"}`,
+			mockReturn:     nil,
+			mockError:      &service.PolicyViolation{Reason: service.ReasonDomainDenied},
+			expectContains: ptr(`"reason":"domain_denied"`),
+		},
+		{
+			name:         "conflicting_expiry_returns_400",
+			requestBody:  `{"long_url":"https://example.com","ttl_seconds":7200,"expires_at":"2030-01-01T00:00:00Z"}`,
+			mockReturn:   nil,
+			mockError:    service.ErrConflictingExpiry,
+			expectStatus: ptr(http.StatusBadRequest),
+		},
+		{
+			name:           "scheduled_link_returns_not_before",
+			requestBody:    `{"long_url":"https://example.com","not_before":"2030-01-01T00:00:00Z"}`,
+			mockReturn:     &entity.Link{ShortCode: "abc123", NotBefore: ptr(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC))},
+			mockError:      nil,
+			expectContains: ptr(`"not_before":"2030-01-01T00:00:00Z"`),
+		},
+		{
+			name:           "one_time_link_returns_max_clicks",
+			requestBody:    `{"long_url":"https://example.com","max_clicks":1}`,
+			mockReturn:     &entity.Link{ShortCode: "abc123", MaxClicks: ptr(int64(1))},
+			mockError:      nil,
+			expectContains: ptr(`"max_clicks":1`),
+		},
+		{
+			name:           "protected_link_reports_password_protected",
+			requestBody:    `{"long_url":"https://example.com","password":"s3cret"}`,
+			mockReturn:     &entity.Link{ShortCode: "abc123", PasswordProtected: true},
+			mockError:      nil,
+			expectContains: ptr(`"password_protected":true`),
+		},
+		{
+			name:         "invalid_password_returns_400",
+			requestBody:  `{"long_url":"https://example.com","password":""}`,
+			mockReturn:   nil,
+			mockError:    service.ErrInvalidPassword,
+			expectStatus: ptr(http.StatusBadRequest),
+		},
+		{
+			name:         "invalid_max_clicks_returns_400",
+			requestBody:  `{"long_url":"https://example.com","max_clicks":0}`,
+			mockReturn:   nil,
+			mockError:    service.ErrInvalidMaxClicks,
+			expectStatus: ptr(http.StatusBadRequest),
+		},
 		{
 			name:        "custom_ttl_is_passed_to_service",
 			requestBody: `{"long_url":"https://example.com","ttl_seconds":7200}`,
-			mockReturn:  "def456",
+			mockReturn:  &entity.Link{ShortCode: "def456"},
 			mockError:   nil,
 			expectTTL:   ptr(int64(7200)),
 		},
@@ -86,21 +189,24 @@ func TestLinkCreatorHandler(t *testing.T) {
 			mockService := mocks.NewMockLinkCreator(ctrl)
 
 			// Setup expectations based on test case
-			if tt.mockReturn != "" || tt.mockError != nil {
+			if tt.mockReturn != nil || tt.mockError != nil {
 				if tt.expectTTL != nil {
 					mockService.EXPECT().
-						Create(gomock.Any(), gomock.Any(), gomock.Eq(tt.expectTTL)).
+						Create(gomock.Any(), gomock.Eq(entity.CreateLinkInput{
+							LongURL:    "https://example.com",
+							TTLSeconds: tt.expectTTL,
+						})).
 						Return(tt.mockReturn, tt.mockError).
 						Times(1)
 				} else {
 					mockService.EXPECT().
-						Create(gomock.Any(), gomock.Any(), gomock.Any()).
+						Create(gomock.Any(), gomock.Any()).
 						Return(tt.mockReturn, tt.mockError).
 						MaxTimes(1)
 				}
 			}
 
-			handler := NewLinkCreatorHandler(mockService)
+			handler := NewLinkCreatorHandler(mockService, nil, "https://sho.rt")
 
 			req := httptest.NewRequest(http.MethodPost, "/s", strings.NewReader(tt.requestBody))
 			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
@@ -120,6 +226,305 @@ func TestLinkCreatorHandler(t *testing.T) {
 					t.Errorf("expected body to contain %s", *tt.expectContains)
 				}
 			}
+
+			if tt.expectLocation != nil {
+				if location := rec.Header().Get(echo.HeaderLocation); location != *tt.expectLocation {
+					t.Errorf("expected Location %s, got %s", *tt.expectLocation, location)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkCreatorHandler_HandleBatch(t *testing.T) {
+	tests := []struct {
+		name           string
+		requestBody    string
+		mockReturn     []entity.BatchLinkResult
+		mockError      error
+		expectCall     bool
+		expectStatus   int
+		expectContains []string
+	}{
+		{
+			name:        "mixed_results_are_reported_per_item",
+			requestBody: `[{"long_url":"https://example.com"},{"long_url":"ftp://example.com"}]`,
+			mockReturn: []entity.BatchLinkResult{
+				{ShortCode: "abc"},
+				{Err: service.ErrInvalidURL},
+			},
+			expectCall:     true,
+			expectStatus:   http.StatusOK,
+			expectContains: []string{`"short_code":"abc"`, service.ErrInvalidURL.Error()},
+		},
+		{
+			name:        "rejected_destination_reports_reason",
+			requestBody: `[{"long_url":"http://10.0.0.1/"}]`,
+			mockReturn: []entity.BatchLinkResult{
+				{Err: &service.PolicyViolation{Reason: service.ReasonInternalHost}},
+			},
+			expectCall:     true,
+			expectStatus:   http.StatusOK,
+			expectContains: []string{`"reason":"internal_host"`},
+		},
+		{
+			name:         "invalid_batch_size_returns_400",
+			requestBody:  `[]`,
+			mockError:    service.ErrInvalidBatchSize,
+			expectCall:   true,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "unverified_id_sequence_returns_503",
+			requestBody:  `[{"long_url":"https://example.com"}]`,
+			mockError:    service.ErrIDSequenceUnverified,
+			expectCall:   true,
+			expectStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:         "non_array_body_returns_400",
+			requestBody:  `{"long_url":"https://example.com"}`,
+			expectStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			e := echo.New()
+			mockService := mocks.NewMockLinkCreator(ctrl)
+			if tt.expectCall {
+				mockService.EXPECT().
+					CreateBatch(gomock.Any(), gomock.Any()).
+					Return(tt.mockReturn, tt.mockError).
+					Times(1)
+			}
+
+			handler := NewLinkCreatorHandler(mockService, nil, "https://sho.rt")
+
+			req := httptest.NewRequest(http.MethodPost, "/s/batch", strings.NewReader(tt.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = handler.HandleBatch(c)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			for _, want := range tt.expectContains {
+				if !strings.Contains(rec.Body.String(), want) {
+					t.Errorf("expected body to contain %s, got %s", want, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestLinkCreatorHandler_Idempotency(t *testing.T) {
+	tests := []struct {
+		name           string
+		beginRecord    *entity.IdempotencyRecord
+		beginError     error
+		createError    error
+		expectCreate   bool
+		expectComplete bool
+		expectAbort    bool
+		expectStatus   int
+		expectBody     *string
+		expectReplayed bool
+	}{
+		{
+			name:           "first_request_stores_response",
+			expectCreate:   true,
+			expectComplete: true,
+			expectStatus:   http.StatusCreated,
+		},
+		{
+			name: "replay_returns_stored_response",
+			beginRecord: &entity.IdempotencyRecord{
+				Completed:  true,
+				StatusCode: http.StatusCreated,
+				Body:       []byte(`{"short_code":"abc"}`),
+			},
+			expectStatus:   http.StatusCreated,
+			expectBody:     ptr(`{"short_code":"abc"}`),
+			expectReplayed: true,
+		},
+		{
+			name:         "different_body_returns_422",
+			beginError:   service.ErrIdempotencyKeyReused,
+			expectStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:         "in_progress_returns_409",
+			beginError:   service.ErrIdempotencyInProgress,
+			expectStatus: http.StatusConflict,
+		},
+		{
+			name:           "validation_error_is_stored",
+			createError:    service.ErrInvalidTTL,
+			expectCreate:   true,
+			expectComplete: true,
+			expectStatus:   http.StatusBadRequest,
+		},
+		{
+			name:         "server_error_releases_key",
+			createError:  errors.New("redis down"),
+			expectCreate: true,
+			expectAbort:  true,
+			expectStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			e := echo.New()
+			mockService := mocks.NewMockLinkCreator(ctrl)
+			mockGuard := mocks.NewMockIdempotencyGuard(ctrl)
+
+			mockGuard.EXPECT().
+				Begin(gomock.Any(), "retry-key", gomock.Any()).
+				Return(tt.beginRecord, tt.beginError)
+			if tt.expectCreate {
+				var link *entity.Link
+				if tt.createError == nil {
+					link = &entity.Link{ShortCode: "abc"}
+				}
+				mockService.EXPECT().
+					Create(gomock.Any(), gomock.Any()).
+					Return(link, tt.createError)
+			}
+			if tt.expectComplete {
+				mockGuard.EXPECT().
+					Complete(gomock.Any(), "retry-key", gomock.Any(), tt.expectStatus, gomock.Any()).
+					Return(nil)
+			}
+			if tt.expectAbort {
+				mockGuard.EXPECT().Abort(gomock.Any(), "retry-key").Return(nil)
+			}
+
+			handler := NewLinkCreatorHandler(mockService, mockGuard, "https://sho.rt")
+
+			req := httptest.NewRequest(http.MethodPost, "/s", strings.NewReader(`{"long_url":"https://example.com"}`))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			req.Header.Set(HeaderIdempotencyKey, "retry-key")
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = handler.Handle(c)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			if tt.expectBody != nil && strings.TrimSpace(rec.Body.String()) != *tt.expectBody {
+				t.Errorf("expected body %s, got %s", *tt.expectBody, rec.Body.String())
+			}
+			if replayed := rec.Header().Get(HeaderIdempotentReplayed) == "true"; replayed != tt.expectReplayed {
+				t.Errorf("expected replayed=%v, got %v", tt.expectReplayed, replayed)
+			}
 		})
 	}
 }
+
+func TestLinkCreatorHandler_FingerprintKeysPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockLinkCreator(ctrl)
+	mockGuard := mocks.NewMockIdempotencyGuard(ctrl)
+
+	var fingerprints []string
+	mockGuard.EXPECT().
+		Begin(gomock.Any(), "retry-key", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, fingerprint string) (*entity.IdempotencyRecord, error) {
+			fingerprints = append(fingerprints, fingerprint)
+			return nil, service.ErrIdempotencyInProgress
+		}).
+		Times(4)
+
+	e := echo.New()
+	send := func(handler *LinkCreatorHandler, body string) {
+		req := httptest.NewRequest(http.MethodPost, "/s", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIdempotencyKey, "retry-key")
+		_ = handler.Handle(e.NewContext(req, httptest.NewRecorder()))
+	}
+	handler := NewLinkCreatorHandler(mockService, mockGuard, "https://sho.rt").WithFingerprintKey([]byte("secret"))
+	send(handler, `{"long_url":"https://example.com","password":"correct horse"}`)
+	send(handler, `{"long_url":"https://example.com","password":"correct horse"}`)
+	send(handler, `{"long_url":"https://example.com","password":"battery staple"}`)
+	send(NewLinkCreatorHandler(mockService, mockGuard, "https://sho.rt").WithFingerprintKey([]byte("other")),
+		`{"long_url":"https://example.com","password":"correct horse"}`)
+
+	if fingerprints[0] != fingerprints[1] {
+		t.Error("expected the same password to give the same fingerprint")
+	}
+	if fingerprints[0] == fingerprints[2] {
+		t.Error("expected a different password to give a different fingerprint")
+	}
+	// Without the secret the fingerprint does not work as a fast hash of the password
+	if fingerprints[0] == fingerprints[3] {
+		t.Error("expected the fingerprint to depend on the key")
+	}
+}
+
+func TestLinkCreatorHandler_ReplayWithDifferentPasswordReturns422(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockLinkCreator(ctrl)
+	mockGuard := mocks.NewMockIdempotencyGuard(ctrl)
+
+	// The guard behaves like IdempotencyService: a stored fingerprint must match
+	var stored *entity.IdempotencyRecord
+	mockGuard.EXPECT().
+		Begin(gomock.Any(), "retry-key", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, fingerprint string) (*entity.IdempotencyRecord, error) {
+			switch {
+			case stored == nil:
+				return nil, nil
+			case stored.Fingerprint != fingerprint:
+				return nil, service.ErrIdempotencyKeyReused
+			default:
+				return stored, nil
+			}
+		}).
+		Times(3)
+	mockGuard.EXPECT().
+		Complete(gomock.Any(), "retry-key", gomock.Any(), http.StatusCreated, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, fingerprint string, status int, body []byte) error {
+			stored = &entity.IdempotencyRecord{Fingerprint: fingerprint, Completed: true, StatusCode: status, Body: body}
+			return nil
+		})
+	mockService.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(&entity.Link{ShortCode: "abc", PasswordProtected: true}, nil)
+
+	handler := NewLinkCreatorHandler(mockService, mockGuard, "https://sho.rt")
+	e := echo.New()
+	for _, step := range []struct {
+		password     string
+		expectStatus int
+	}{
+		{password: "correct horse", expectStatus: http.StatusCreated},
+		{password: "battery staple", expectStatus: http.StatusUnprocessableEntity},
+		{password: "correct horse", expectStatus: http.StatusCreated},
+	} {
+		body := `{"long_url":"https://example.com","password":"` + step.password + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/s", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(HeaderIdempotencyKey, "retry-key")
+		rec := httptest.NewRecorder()
+		_ = handler.Handle(e.NewContext(req, rec))
+
+		if rec.Code != step.expectStatus {
+			t.Errorf("password %q: expected status %d, got %d", step.password, step.expectStatus, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/service"
+)
+
+// EditLinkRequest is the body of PATCH /s/{code}. Omitted fields are left unchanged.
+type EditLinkRequest struct {
+	LongURL    *string    `json:"long_url,omitempty"`
+	TTLSeconds *int64     `json:"ttl_seconds,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+}
+
+// LinkChangeResponse is one entry of GET /stats/{code}/history.
+type LinkChangeResponse struct {
+	OldLongURL   string `json:"old_long_url"`
+	NewLongURL   string `json:"new_long_url"`
+	OldExpiresAt string `json:"old_expires_at"`
+	NewExpiresAt string `json:"new_expires_at"`
+	ChangedAt    string `json:"changed_at"`
+}
+
+type LinkHistoryResponse struct {
+	Changes []LinkChangeResponse `json:"changes"`
+}
+
+type LinkEditorHandler struct {
+	service service.LinkEditor
+	baseURL string
+}
+
+func NewLinkEditorHandler(svc service.LinkEditor, baseURL string) *LinkEditorHandler {
+	return &LinkEditorHandler{service: svc, baseURL: baseURL}
+}
+
+func (h *LinkEditorHandler) Handle(c echo.Context) error {
+	shortCode := c.Param("short_code")
+	if shortCode == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "short_code is required"})
+	}
+
+	var req EditLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+	}
+
+	link, err := h.service.Edit(c.Request().Context(), shortCode, entity.EditLinkInput{
+		LongURL:    req.LongURL,
+		TTLSeconds: req.TTLSeconds,
+		ExpiresAt:  req.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.JSON(serviceErrorResponse(err))
+	}
+
+	return c.JSON(http.StatusOK, newLinkResponse(h.baseURL, link))
+}
+
+// HandleHistory lists the edits of a link, oldest first.
+func (h *LinkEditorHandler) HandleHistory(c echo.Context) error {
+	shortCode := c.Param("short_code")
+	if shortCode == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "short_code is required"})
+	}
+
+	changes, err := h.service.History(c.Request().Context(), shortCode)
+	if err != nil {
+		if errors.Is(err, service.ErrNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+	}
+
+	resp := LinkHistoryResponse{Changes: make([]LinkChangeResponse, len(changes))}
+	for i, change := range changes {
+		resp.Changes[i] = LinkChangeResponse{
+			OldLongURL:   change.OldLongURL,
+			NewLongURL:   change.NewLongURL,
+			OldExpiresAt: change.OldExpiresAt.Format(time.RFC3339),
+			NewExpiresAt: change.NewExpiresAt.Format(time.RFC3339),
+			ChangedAt:    change.ChangedAt.Format(time.RFC3339),
+		}
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
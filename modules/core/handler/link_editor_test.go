@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/lib"
+	"github.com/nanda/doit/modules/core/service"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLinkEditorHandler(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		mockReturn     *entity.Link
+		mockError      error
+		expectCall     bool
+		expectInput    entity.EditLinkInput
+		expectStatus   int
+		expectContains string
+	}{
+		{
+			name:        "edit_returns_updated_link",
+			requestBody: `{"long_url":"https://example.com/new","ttl_seconds":7200}`,
+			mockReturn: &entity.Link{
+				ShortCode: "abc123",
+				LongURL:   "https://example.com/new",
+				CreatedAt: fixedTime,
+				ExpiresAt: fixedTime.Add(2 * time.Hour),
+			},
+			expectCall:     true,
+			expectInput:    entity.EditLinkInput{LongURL: ptr("https://example.com/new"), TTLSeconds: ptr(int64(7200))},
+			expectStatus:   http.StatusOK,
+			expectContains: `"long_url":"https://example.com/new","created_at":"2024-01-01T12:00:00Z","expires_at":"2024-01-01T14:00:00Z"`,
+		},
+		{
+			name:         "invalid_body_returns_400",
+			requestBody:  `{"long_url":`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "empty_edit_returns_400",
+			requestBody:  `{}`,
+			mockError:    service.ErrEmptyEdit,
+			expectCall:   true,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid_ttl_returns_400",
+			requestBody:  `{"ttl_seconds":60}`,
+			mockError:    service.ErrInvalidTTL,
+			expectCall:   true,
+			expectInput:  entity.EditLinkInput{TTLSeconds: ptr(int64(60))},
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "rejected_destination_returns_422_with_reason",
+			requestBody:    `{"long_url":"http://localhost/"}`,
+			mockError:      &service.PolicyViolation{Reason: service.ReasonInternalHost},
+			expectCall:     true,
+			expectInput:    entity.EditLinkInput{LongURL: ptr("http://localhost/")},
+			expectStatus:   http.StatusUnprocessableEntity,
+			expectContains: `"reason":"internal_host"`,
+		},
+		{
+			name:         "missing_link_returns_404",
+			requestBody:  `{"ttl_seconds":7200}`,
+			mockError:    service.ErrNotFound,
+			expectCall:   true,
+			expectInput:  entity.EditLinkInput{TTLSeconds: ptr(int64(7200))},
+			expectStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			e := echo.New()
+			mockService := mocks.NewMockLinkEditor(ctrl)
+			if tt.expectCall {
+				mockService.EXPECT().
+					Edit(gomock.Any(), "abc123", gomock.Eq(tt.expectInput)).
+					Return(tt.mockReturn, tt.mockError)
+			}
+
+			handler := NewLinkEditorHandler(mockService, "http://localhost:8080")
+
+			req := httptest.NewRequest(http.MethodPatch, "/s/abc123", strings.NewReader(tt.requestBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("short_code")
+			c.SetParamValues("abc123")
+
+			_ = handler.Handle(c)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectContains) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectContains, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLinkEditorHandler_History(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	changedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockService := mocks.NewMockLinkEditor(ctrl)
+	mockService.EXPECT().
+		History(gomock.Any(), "abc123").
+		Return([]*entity.LinkChange{{
+			OldLongURL:   "https://example.com/old",
+			NewLongURL:   "https://example.com/new",
+			OldExpiresAt: changedAt.Add(time.Hour),
+			NewExpiresAt: changedAt.Add(2 * time.Hour),
+			ChangedAt:    changedAt,
+		}}, nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/stats/abc123/history", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("short_code")
+	c.SetParamValues("abc123")
+
+	_ = NewLinkEditorHandler(mockService, "http://localhost:8080").HandleHistory(c)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	expected := `{"changes":[{"old_long_url":"https://example.com/old","new_long_url":"https://example.com/new",` +
+		`"old_expires_at":"2024-01-01T13:00:00Z","new_expires_at":"2024-01-01T14:00:00Z","changed_at":"2024-01-01T12:00:00Z"}]}`
+	if strings.TrimSpace(rec.Body.String()) != expected {
+		t.Errorf("expected body %s, got %s", expected, rec.Body.String())
+	}
+}
+
+func TestLinkEditorHandler_AnonymousLinkIsForbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The real service decides, so no write may reach the repositories
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(&entity.URL{ID: 7, LongURL: "https://example.com"}, nil)
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(7)).
+		Return(&entity.URLAnalytic{URLID: 7, LongURL: "https://example.com", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	svc := service.NewLinkEditorService(cacheRepo, analyticRepo, service.NewLinkCreatorService(cacheRepo, analyticRepo))
+	handler := NewLinkEditorHandler(svc, "http://localhost:8080")
+
+	shortCode := lib.HexEncode(7)
+	req := httptest.NewRequest(http.MethodPatch, "/s/"+shortCode, strings.NewReader(`{"long_url":"https://attacker.example"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetParamNames("short_code")
+	c.SetParamValues(shortCode)
+
+	_ = handler.Handle(c)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+}
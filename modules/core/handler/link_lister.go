@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/service"
+)
+
+// LinkListItem is one link of GET /links. ShortCode is the generated code, which
+// also resolves for aliased links.
+type LinkListItem struct {
+	ShortCode      string  `json:"short_code"`
+	ShortURL       string  `json:"short_url"`
+	Alias          *string `json:"alias,omitempty"`
+	LongURL        string  `json:"long_url"`
+	Status         string  `json:"status"`
+	CreatedAt      string  `json:"created_at"`
+	ExpiresAt      string  `json:"expires_at"`
+	ClickCount     int64   `json:"click_count"`
+	LastAccessedAt *string `json:"last_accessed_at"`
+}
+
+type LinkListResponse struct {
+	Links      []LinkListItem `json:"links"`
+	NextCursor *string        `json:"next_cursor"`
+}
+
+// listBadRequestErrors are the service errors that reject the listing parameters.
+var listBadRequestErrors = []error{
+	service.ErrInvalidSort,
+	service.ErrInvalidStatus,
+	service.ErrInvalidLimit,
+	service.ErrInvalidCursor,
+	service.ErrInvalidDomain,
+	service.ErrInvalidMinClicks,
+}
+
+type LinkListerHandler struct {
+	service service.LinkLister
+	baseURL string
+}
+
+func NewLinkListerHandler(svc service.LinkLister, baseURL string) *LinkListerHandler {
+	return &LinkListerHandler{service: svc, baseURL: baseURL}
+}
+
+func (h *LinkListerHandler) Handle(c echo.Context) error {
+	input, err := listLinksInput(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	}
+
+	page, err := h.service.List(c.Request().Context(), input)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrOwnerRequired):
+			return unauthorized(c, err.Error())
+		case isAnyError(err, listBadRequestErrors):
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+		}
+	}
+
+	now := time.Now()
+	resp := LinkListResponse{Links: make([]LinkListItem, len(page.Links))}
+	for i, link := range page.Links {
+		resp.Links[i] = LinkListItem{
+			ShortCode:      link.ShortCode,
+			ShortURL:       h.baseURL + "/s/" + link.ShortCode,
+			Alias:          link.Alias,
+			LongURL:        link.LongURL,
+			Status:         string(linkStatus(link.URLAnalytic, now)),
+			CreatedAt:      link.CreatedAt.Format(time.RFC3339),
+			ExpiresAt:      link.ExpiresAt.Format(time.RFC3339),
+			ClickCount:     link.ClickCount,
+			LastAccessedAt: formatOptionalTime(link.LastAccessedAt),
+		}
+	}
+	if page.NextCursor != "" {
+		resp.NextCursor = &page.NextCursor
+	}
+
+	return c.JSON(http.StatusOK, resp)
+}
+
+// listLinksInput reads the query parameters of GET /links.
+func listLinksInput(c echo.Context) (entity.ListLinksInput, error) {
+	input := entity.ListLinksInput{
+		Domain: c.QueryParam("domain"),
+		Status: entity.LinkStatus(c.QueryParam("status")),
+		Sort:   entity.LinkSort(c.QueryParam("sort")),
+		Cursor: c.QueryParam("cursor"),
+	}
+
+	switch c.QueryParam("order") {
+	case "", "desc":
+	case "asc":
+		input.Ascending = true
+	default:
+		return input, errors.New("invalid order: must be asc or desc")
+	}
+
+	var err error
+	if input.CreatedAfter, err = optionalTimeParam(c, "created_after"); err != nil {
+		return input, err
+	}
+	if input.CreatedBefore, err = optionalTimeParam(c, "created_before"); err != nil {
+		return input, err
+	}
+	if input.MinClicks, err = optionalIntParam(c, "min_clicks"); err != nil {
+		return input, err
+	}
+	limit, err := optionalIntParam(c, "limit")
+	if err != nil {
+		return input, err
+	}
+	if limit != nil {
+		// Zero means the default page size to the service, so refuse it here
+		if *limit < 1 || *limit > service.MaxListLimit {
+			return input, service.ErrInvalidLimit
+		}
+		input.Limit = int(*limit)
+	}
+	return input, nil
+}
+
+func optionalTimeParam(c echo.Context, name string) (*time.Time, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, errors.New("invalid " + name + ": must be an RFC3339 timestamp")
+	}
+	return &parsed, nil
+}
+
+func optionalIntParam(c echo.Context, name string) (*int64, error) {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return nil, nil
+	}
+	parsed, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid " + name + ": must be an integer")
+	}
+	return &parsed, nil
+}
+
+// linkStatus reports whether a link is active, expired or revoked at now.
+func linkStatus(link *entity.URLAnalytic, now time.Time) entity.LinkStatus {
+	switch {
+	case link.RevokedAt != nil:
+		return entity.LinkStatusRevoked
+	case !link.ExpiresAt.After(now):
+		return entity.LinkStatusExpired
+	default:
+		return entity.LinkStatusActive
+	}
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/service"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLinkListerHandler(t *testing.T) {
+	fixedTime := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	createdAfter := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		query          string
+		mockReturn     *entity.LinkPage
+		mockError      error
+		expectCall     bool
+		expectInput    entity.ListLinksInput
+		expectStatus   int
+		expectContains string
+	}{
+		{
+			name:  "lists_links_with_short_codes",
+			query: "",
+			mockReturn: &entity.LinkPage{
+				Links: []*entity.ListedLink{{
+					URLAnalytic: &entity.URLAnalytic{
+						URLID:      255,
+						LongURL:    "https://example.com",
+						CreatedAt:  fixedTime,
+						ExpiresAt:  fixedTime.Add(-time.Hour),
+						ClickCount: 3,
+					},
+					ShortCode: "ff",
+				}},
+				NextCursor: "abc",
+			},
+			expectCall:     true,
+			expectStatus:   http.StatusOK,
+			expectContains: `"short_code":"ff","short_url":"http://localhost:8080/s/ff","long_url":"https://example.com","status":"expired"`,
+		},
+		{
+			name:           "next_cursor_is_returned",
+			mockReturn:     &entity.LinkPage{NextCursor: "abc"},
+			expectCall:     true,
+			expectStatus:   http.StatusOK,
+			expectContains: `"links":[],"next_cursor":"abc"`,
+		},
+		{
+			name:           "last_page_has_null_cursor",
+			mockReturn:     &entity.LinkPage{},
+			expectCall:     true,
+			expectStatus:   http.StatusOK,
+			expectContains: `"next_cursor":null`,
+		},
+		{
+			name:       "query_parameters_are_parsed",
+			query:      "?domain=example.com&created_after=2024-01-01T00:00:00Z&status=active&min_clicks=5&sort=click_count&order=asc&limit=10&cursor=abc",
+			mockReturn: &entity.LinkPage{},
+			expectCall: true,
+			expectInput: entity.ListLinksInput{
+				Domain:       "example.com",
+				CreatedAfter: &createdAfter,
+				Status:       entity.LinkStatusActive,
+				MinClicks:    ptr(int64(5)),
+				Sort:         entity.SortClickCount,
+				Ascending:    true,
+				Limit:        10,
+				Cursor:       "abc",
+			},
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "invalid_timestamp_returns_400",
+			query:        "?created_before=yesterday",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid_order_returns_400",
+			query:        "?order=random",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "zero_limit_returns_400",
+			query:        "?limit=0",
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "invalid_sort_returns_400",
+			query:        "?sort=long_url",
+			mockError:    service.ErrInvalidSort,
+			expectCall:   true,
+			expectInput:  entity.ListLinksInput{Sort: "long_url"},
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "anonymous_caller_returns_401",
+			mockError:    service.ErrOwnerRequired,
+			expectCall:   true,
+			expectStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			e := echo.New()
+			mockService := mocks.NewMockLinkLister(ctrl)
+			if tt.expectCall {
+				mockService.EXPECT().
+					List(gomock.Any(), gomock.Eq(tt.expectInput)).
+					Return(tt.mockReturn, tt.mockError)
+			}
+
+			handler := NewLinkListerHandler(mockService, "http://localhost:8080")
+
+			req := httptest.NewRequest(http.MethodGet, "/links"+tt.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			_ = handler.Handle(c)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectContains) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectContains, rec.Body.String())
+			}
+		})
+	}
+}
@@ -1,13 +1,24 @@
 package handler
 
 import (
+	"context"
 	"errors"
+	"math"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 	"github.com/nanda/doit/modules/core/service"
 )
 
+// HeaderLinkPassword carries the password of a protected link on GET /s/{code}.
+const HeaderLinkPassword = "X-Link-Password"
+
+// HeaderCacheFallback is set to "postgres" on redirect responses answered from
+// analytics because Redis was unavailable.
+const HeaderCacheFallback = "X-Cache-Fallback"
+
 type LinkRedirectorHandler struct {
 	service service.LinkRedirector
 }
@@ -22,13 +33,106 @@ func (h *LinkRedirectorHandler) Handle(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "short_code is required"})
 	}
 
-	longURL, err := h.service.Redirect(c.Request().Context(), shortCode)
+	ctx, done := trackCacheFallback(c)
+	defer done()
+
+	longURL, err := h.service.Redirect(ctx, shortCode, c.Request().Header.Get(HeaderLinkPassword))
 	if err != nil {
-		if errors.Is(err, service.ErrNotFound) {
-			return c.NoContent(http.StatusNotFound)
+		var nonCanonical *service.NonCanonicalCodeError
+		if errors.As(err, &nonCanonical) {
+			return c.Redirect(http.StatusMovedPermanently, "/s/"+nonCanonical.Canonical)
 		}
-		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+		return redirectError(c, shortCode, err, acceptsHTML(c))
 	}
 
 	return c.Redirect(http.StatusFound, longURL)
 }
+
+// HandleUnlock accepts the password form served for a protected link.
+func (h *LinkRedirectorHandler) HandleUnlock(c echo.Context) error {
+	shortCode := c.Param("short_code")
+	if shortCode == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "short_code is required"})
+	}
+
+	ctx, done := trackCacheFallback(c)
+	defer done()
+
+	longURL, err := h.service.Redirect(ctx, shortCode, c.FormValue("password"))
+	if err != nil {
+		// Permanent Redirect keeps the method and the posted password
+		var nonCanonical *service.NonCanonicalCodeError
+		if errors.As(err, &nonCanonical) {
+			return c.Redirect(http.StatusPermanentRedirect, "/s/"+nonCanonical.Canonical+"/unlock")
+		}
+		return redirectError(c, shortCode, err, true)
+	}
+
+	// See Other makes the browser follow the form POST with a GET
+	return c.Redirect(http.StatusSeeOther, longURL)
+}
+
+// trackCacheFallback returns the request context with a fallback recorder. A
+// response answered from Postgres gets HeaderCacheFallback, and done counts it
+// once the response has been written.
+func trackCacheFallback(c echo.Context) (ctx context.Context, done func()) {
+	fallback := &service.CacheFallback{}
+	c.Response().Before(func() {
+		if fallback.Used() {
+			c.Response().Header().Set(HeaderCacheFallback, "postgres")
+		}
+	})
+	return service.WithCacheFallbackRecorder(c.Request().Context(), fallback), func() {
+		if fallback.Used() {
+			CacheFallbackRedirectsTotal.WithLabelValues(strconv.Itoa(c.Response().Status)).Inc()
+		}
+	}
+}
+
+// redirectError writes the response for a failed redirect. Password errors are
+// rendered as the unlock form when html is set.
+func redirectError(c echo.Context, shortCode string, err error, html bool) error {
+	switch {
+	case errors.Is(err, service.ErrNotFound):
+		recordChecksumFailure(err, "redirect")
+		return c.NoContent(http.StatusNotFound)
+	case errors.Is(err, service.ErrNotYetActive):
+		return c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrLinkExhausted), errors.Is(err, service.ErrLinkRevoked):
+		return c.JSON(http.StatusGone, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, service.ErrPasswordRequired),
+		errors.Is(err, service.ErrWrongPassword),
+		errors.Is(err, service.ErrTooManyAttempts):
+		return unlockError(c, shortCode, err, html)
+	case errors.Is(err, service.ErrCacheUnavailable):
+		return c.JSON(http.StatusServiceUnavailable, ErrorResponse{Error: err.Error()})
+	default:
+		return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+	}
+}
+
+// unlockError answers a missing or rejected password with 401, or 429 and
+// Retry-After once the link's attempts are used up.
+func unlockError(c echo.Context, shortCode string, err error, html bool) error {
+	status := http.StatusUnauthorized
+	var tooMany *service.TooManyAttemptsError
+	if errors.As(err, &tooMany) {
+		status = http.StatusTooManyRequests
+		retryAfter := int64(math.Ceil(tooMany.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.FormatInt(retryAfter, 10))
+	}
+
+	if !html {
+		return c.JSON(status, ErrorResponse{Error: err.Error()})
+	}
+
+	message := err.Error()
+	if errors.Is(err, service.ErrPasswordRequired) {
+		message = ""
+	}
+	return renderUnlockForm(c, status, shortCode, message)
+}
+
+func acceptsHTML(c echo.Context) bool {
+	return strings.Contains(c.Request().Header.Get(echo.HeaderAccept), echo.MIMETextHTML)
+}
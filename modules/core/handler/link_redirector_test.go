@@ -1,13 +1,18 @@
 package handler
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/nanda/doit/modules/core/internal/test/mocks"
 	"github.com/nanda/doit/modules/core/service"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"go.uber.org/mock/gomock"
 )
 
@@ -41,6 +46,48 @@ func TestLinkRedirectorHandler(t *testing.T) {
 			mockError:    service.ErrNotFound,
 			expectStatus: ptr(http.StatusNotFound),
 		},
+		{
+			name:           "non_canonical_code_returns_301_to_canonical_code",
+			shortCode:      "FF",
+			mockError:      &service.NonCanonicalCodeError{Canonical: "ff"},
+			expectStatus:   ptr(http.StatusMovedPermanently),
+			expectLocation: ptr("/s/ff"),
+		},
+		{
+			name:         "bad_checksum_returns_404",
+			shortCode:    "K23456789ABCxx",
+			mockReturn:   "",
+			mockError:    service.ErrBadChecksum,
+			expectStatus: ptr(http.StatusNotFound),
+		},
+		{
+			name:         "not_yet_active_returns_403",
+			shortCode:    "abc123",
+			mockReturn:   "",
+			mockError:    &service.NotYetActiveError{NotBefore: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)},
+			expectStatus: ptr(http.StatusForbidden),
+		},
+		{
+			name:         "exhausted_link_returns_410",
+			shortCode:    "abc123",
+			mockReturn:   "",
+			mockError:    service.ErrLinkExhausted,
+			expectStatus: ptr(http.StatusGone),
+		},
+		{
+			name:         "revoked_link_returns_410",
+			shortCode:    "abc123",
+			mockReturn:   "",
+			mockError:    service.ErrLinkRevoked,
+			expectStatus: ptr(http.StatusGone),
+		},
+		{
+			name:         "link_unavailable_without_cache_returns_503",
+			shortCode:    "abc123",
+			mockReturn:   "",
+			mockError:    service.ErrCacheUnavailable,
+			expectStatus: ptr(http.StatusServiceUnavailable),
+		},
 	}
 
 	for _, tt := range tests {
@@ -53,7 +100,7 @@ func TestLinkRedirectorHandler(t *testing.T) {
 
 			// Setup expectations
 			mockService.EXPECT().
-				Redirect(gomock.Any(), tt.shortCode).
+				Redirect(gomock.Any(), tt.shortCode, "").
 				Return(tt.mockReturn, tt.mockError).
 				Times(1)
 
@@ -83,3 +130,186 @@ func TestLinkRedirectorHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestLinkRedirectorHandler_Password(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		header         http.Header
+		form           url.Values
+		expectPassword string
+		mockError      error
+		expectStatus   int
+		expectContains string
+		expectHeader   map[string]string
+	}{
+		{
+			name:           "header_password_unlocks_redirect",
+			method:         http.MethodGet,
+			header:         http.Header{HeaderLinkPassword: {"s3cret"}},
+			expectPassword: "s3cret",
+			expectStatus:   http.StatusFound,
+		},
+		{
+			name:           "missing_password_returns_401_json",
+			method:         http.MethodGet,
+			mockError:      service.ErrPasswordRequired,
+			expectStatus:   http.StatusUnauthorized,
+			expectContains: `"error":"short code is password protected"`,
+		},
+		{
+			name:           "missing_password_serves_form_to_browsers",
+			method:         http.MethodGet,
+			header:         http.Header{echo.HeaderAccept: {"text/html,application/xhtml+xml"}},
+			mockError:      service.ErrPasswordRequired,
+			expectStatus:   http.StatusUnauthorized,
+			expectContains: `action="/s/abc123/unlock"`,
+			expectHeader:   map[string]string{echo.HeaderCacheControl: "no-store"},
+		},
+		{
+			name:           "locked_link_returns_429_with_retry_after",
+			method:         http.MethodGet,
+			header:         http.Header{HeaderLinkPassword: {"guess"}},
+			expectPassword: "guess",
+			mockError:      &service.TooManyAttemptsError{RetryAfter: 90500 * time.Millisecond},
+			expectStatus:   http.StatusTooManyRequests,
+			expectHeader:   map[string]string{"Retry-After": "91"},
+		},
+		{
+			name:           "form_password_redirects_with_303",
+			method:         http.MethodPost,
+			form:           url.Values{"password": {"s3cret"}},
+			expectPassword: "s3cret",
+			expectStatus:   http.StatusSeeOther,
+		},
+		{
+			name:           "wrong_form_password_shows_form_with_error",
+			method:         http.MethodPost,
+			form:           url.Values{"password": {"guess"}},
+			expectPassword: "guess",
+			mockError:      service.ErrWrongPassword,
+			expectStatus:   http.StatusUnauthorized,
+			expectContains: "incorrect password",
+		},
+		{
+			name:           "wrong_form_password_form_posts_back_to_unlock_route",
+			method:         http.MethodPost,
+			form:           url.Values{"password": {"guess"}},
+			expectPassword: "guess",
+			mockError:      service.ErrWrongPassword,
+			expectStatus:   http.StatusUnauthorized,
+			expectContains: `action="/s/abc123/unlock"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			e := echo.New()
+			mockService := mocks.NewMockLinkRedirector(ctrl)
+			mockService.EXPECT().
+				Redirect(gomock.Any(), "abc123", tt.expectPassword).
+				Return("https://example.com", tt.mockError)
+
+			handler := NewLinkRedirectorHandler(mockService)
+
+			target := "/s/abc123"
+			if tt.method == http.MethodPost {
+				target += "/unlock"
+			}
+			req := httptest.NewRequest(tt.method, target, strings.NewReader(tt.form.Encode()))
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+			if tt.form != nil {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationForm)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("short_code")
+			c.SetParamValues("abc123")
+
+			if tt.method == http.MethodPost {
+				_ = handler.HandleUnlock(c)
+			} else {
+				_ = handler.Handle(c)
+			}
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectContains) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectContains, rec.Body.String())
+			}
+			for key, value := range tt.expectHeader {
+				if got := rec.Header().Get(key); got != value {
+					t.Errorf("expected %s %q, got %q", key, value, got)
+				}
+			}
+		})
+	}
+}
+
+func TestLinkRedirectorHandler_ChecksumMetric(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockLinkRedirector(ctrl)
+	mockService.EXPECT().Redirect(gomock.Any(), "K23456789ABCxx", "").Return("", service.ErrBadChecksum)
+	mockService.EXPECT().Redirect(gomock.Any(), "notfound", "").Return("", service.ErrNotFound)
+	handler := NewLinkRedirectorHandler(mockService)
+
+	failures := ChecksumFailuresTotal.WithLabelValues("redirect")
+	before := testutil.ToFloat64(failures)
+
+	// Only checksum failures are counted, not every unknown code
+	e := echo.New()
+	for _, shortCode := range []string{"K23456789ABCxx", "notfound"} {
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/s/"+shortCode, nil), httptest.NewRecorder())
+		c.SetParamNames("short_code")
+		c.SetParamValues(shortCode)
+		_ = handler.Handle(c)
+	}
+
+	if got := testutil.ToFloat64(failures) - before; got != 1 {
+		t.Errorf("expected 1 checksum failure to be counted, got %v", got)
+	}
+}
+
+func TestLinkRedirectorHandler_CacheFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockService := mocks.NewMockLinkRedirector(ctrl)
+	mockService.EXPECT().Redirect(gomock.Any(), "abc123", "").
+		DoAndReturn(func(ctx context.Context, _, _ string) (string, error) {
+			fallback, _ := service.CacheFallbackFromContext(ctx)
+			fallback.Record()
+			return "https://example.com", nil
+		})
+	mockService.EXPECT().Redirect(gomock.Any(), "def456", "").Return("https://example.com", nil)
+	handler := NewLinkRedirectorHandler(mockService)
+
+	served := CacheFallbackRedirectsTotal.WithLabelValues("302")
+	before := testutil.ToFloat64(served)
+
+	e := echo.New()
+	for shortCode, expectHeader := range map[string]string{"abc123": "postgres", "def456": ""} {
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/s/"+shortCode, nil), rec)
+		c.SetParamNames("short_code")
+		c.SetParamValues(shortCode)
+		_ = handler.Handle(c)
+
+		if got := rec.Header().Get(HeaderCacheFallback); got != expectHeader {
+			t.Errorf("%s: expected %s %q, got %q", shortCode, HeaderCacheFallback, expectHeader, got)
+		}
+	}
+
+	// Only the redirect answered from Postgres is counted
+	if got := testutil.ToFloat64(served) - before; got != 1 {
+		t.Errorf("expected 1 fallback redirect to be counted, got %v", got)
+	}
+}
@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/service"
+)
+
+// RevokeLinkRequest is the optional body of DELETE /s/{code}.
+type RevokeLinkRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
+
+type RevokeLinkResponse struct {
+	ShortCode    string  `json:"short_code"`
+	RevokedAt    string  `json:"revoked_at"`
+	RevokeReason *string `json:"revoke_reason"`
+}
+
+type LinkRevokerHandler struct {
+	service service.LinkRevoker
+}
+
+func NewLinkRevokerHandler(svc service.LinkRevoker) *LinkRevokerHandler {
+	return &LinkRevokerHandler{service: svc}
+}
+
+// Handle revokes a link. Repeating the request returns the original revocation.
+func (h *LinkRevokerHandler) Handle(c echo.Context) error {
+	shortCode := c.Param("short_code")
+	if shortCode == "" {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "short_code is required"})
+	}
+
+	var req RevokeLinkRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request body"})
+	}
+
+	analytic, err := h.service.Revoke(c.Request().Context(), shortCode, req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrNotFound):
+			return c.NoContent(http.StatusNotFound)
+		case errors.Is(err, service.ErrForbidden):
+			return c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
+		case errors.Is(err, service.ErrInvalidRevokeReason):
+			return c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		default:
+			return c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "internal server error"})
+		}
+	}
+
+	return c.JSON(http.StatusOK, RevokeLinkResponse{
+		ShortCode:    shortCode,
+		RevokedAt:    analytic.RevokedAt.Format(time.RFC3339),
+		RevokeReason: analytic.RevokeReason,
+	})
+}
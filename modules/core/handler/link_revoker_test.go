@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/lib"
+	"github.com/nanda/doit/modules/core/service"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLinkRevokerHandler(t *testing.T) {
+	revokedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		requestBody    string
+		mockReturn     *entity.URLAnalytic
+		mockError      error
+		expectCall     bool
+		expectReason   *string
+		expectStatus   int
+		expectContains string
+	}{
+		{
+			name:        "revoke_with_reason_returns_200",
+			requestBody: `{"reason":"phishing"}`,
+			mockReturn: &entity.URLAnalytic{
+				RevokedAt:    &revokedAt,
+				RevokeReason: ptr("phishing"),
+			},
+			expectCall:     true,
+			expectReason:   ptr("phishing"),
+			expectStatus:   http.StatusOK,
+			expectContains: `{"short_code":"abc123","revoked_at":"2024-01-01T12:00:00Z","revoke_reason":"phishing"}`,
+		},
+		{
+			name:           "revoke_without_body_returns_null_reason",
+			mockReturn:     &entity.URLAnalytic{RevokedAt: &revokedAt},
+			expectCall:     true,
+			expectStatus:   http.StatusOK,
+			expectContains: `"revoke_reason":null`,
+		},
+		{
+			name:         "invalid_body_returns_400",
+			requestBody:  `{"reason":`,
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "reason_too_long_returns_400",
+			requestBody:  `{"reason":"x"}`,
+			mockError:    service.ErrInvalidRevokeReason,
+			expectCall:   true,
+			expectReason: ptr("x"),
+			expectStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "unknown_link_returns_404",
+			mockError:    service.ErrNotFound,
+			expectCall:   true,
+			expectStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			e := echo.New()
+			mockService := mocks.NewMockLinkRevoker(ctrl)
+			if tt.expectCall {
+				mockService.EXPECT().
+					Revoke(gomock.Any(), "abc123", gomock.Eq(tt.expectReason)).
+					Return(tt.mockReturn, tt.mockError)
+			}
+
+			handler := NewLinkRevokerHandler(mockService)
+
+			req := httptest.NewRequest(http.MethodDelete, "/s/abc123", strings.NewReader(tt.requestBody))
+			if tt.requestBody != "" {
+				req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("short_code")
+			c.SetParamValues("abc123")
+
+			_ = handler.Handle(c)
+
+			if rec.Code != tt.expectStatus {
+				t.Errorf("expected status %d, got %d", tt.expectStatus, rec.Code)
+			}
+			if !strings.Contains(rec.Body.String(), tt.expectContains) {
+				t.Errorf("expected body to contain %s, got %s", tt.expectContains, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestLinkRevokerHandler_AnonymousLinkIsForbidden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The real service decides, so no write may reach the repositories
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(7)).
+		Return(&entity.URLAnalytic{URLID: 7, LongURL: "https://example.com", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	handler := NewLinkRevokerHandler(service.NewLinkRevokerService(cacheRepo, analyticRepo, lib.DefaultCodeSet()))
+
+	shortCode := lib.HexEncode(7)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodDelete, "/s/"+shortCode, nil), rec)
+	c.SetParamNames("short_code")
+	c.SetParamValues(shortCode)
+
+	_ = handler.Handle(c)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("expected status %d, got %d", http.StatusForbidden, rec.Code)
+	}
+}
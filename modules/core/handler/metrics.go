@@ -1,6 +1,9 @@
 package handler
 
 import (
+	"errors"
+
+	"github.com/nanda/doit/modules/core/service"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 )
@@ -40,3 +43,49 @@ var InFlightRequests = promauto.NewGauge(
 		Help: "Current number of HTTP requests being processed",
 	},
 )
+
+// ChecksumFailuresTotal is a counter of generated codes refused for wrong check characters
+var ChecksumFailuresTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "short_code_checksum_failures_total",
+		Help: "Total number of short codes refused because their check characters did not match",
+	},
+	[]string{"endpoint"},
+)
+
+// CacheFallbackRedirectsTotal is a counter of redirects answered from Postgres because Redis was unavailable
+var CacheFallbackRedirectsTotal = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "redirect_cache_fallback_total",
+		Help: "Total number of redirect requests answered from Postgres because Redis was unavailable",
+	},
+	[]string{"status"},
+)
+
+// CircuitBreakerOpen is a gauge that is 1 while a circuit breaker keeps requests off a dependency
+var CircuitBreakerOpen = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "circuit_breaker_open",
+		Help: "Whether a circuit breaker is open (1) or closed (0)",
+	},
+	[]string{"breaker"},
+)
+
+// RecordCircuitBreaker returns a state change hook that reports the named breaker.
+func RecordCircuitBreaker(name string) func(open bool) {
+	gauge := CircuitBreakerOpen.WithLabelValues(name)
+	return func(open bool) {
+		if open {
+			gauge.Set(1)
+		} else {
+			gauge.Set(0)
+		}
+	}
+}
+
+// recordChecksumFailure counts err if it refused a code for its check characters.
+func recordChecksumFailure(err error, endpoint string) {
+	if errors.Is(err, service.ErrBadChecksum) {
+		ChecksumFailuresTotal.WithLabelValues(endpoint).Inc()
+	}
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+
+	"github.com/labstack/echo/v4"
+)
+
+// unlockForm asks for the password of a protected link and posts it to
+// /s/{code}/unlock. The action is absolute because the form is served from both
+// /s/{code} and /s/{code}/unlock, where a relative action would resolve differently.
+var unlockForm = template.Must(template.New("unlock").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Password required</title>
+</head>
+<body>
+<form method="post" action="/s/{{.ShortCode}}/unlock">
+<p>This link is password protected.</p>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<label>Password <input type="password" name="password" autocomplete="off" required autofocus></label>
+<button type="submit">Continue</button>
+</form>
+</body>
+</html>
+`))
+
+type unlockFormData struct {
+	ShortCode string
+	Error     string
+}
+
+func renderUnlockForm(c echo.Context, status int, shortCode, message string) error {
+	var buf bytes.Buffer
+	if err := unlockForm.Execute(&buf, unlockFormData{ShortCode: shortCode, Error: message}); err != nil {
+		return err
+	}
+	c.Response().Header().Set(echo.HeaderCacheControl, "no-store")
+	return c.HTMLBlob(status, buf.Bytes())
+}
@@ -0,0 +1,71 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/nanda/doit/modules/core/internal/repo/cache"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestIsUnavailable(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		expect bool
+	}{
+		{
+			name:   "refused_connection",
+			err:    fmt.Errorf("failed to get URL from cache: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}),
+			expect: true,
+		},
+		{
+			name:   "dropped_connection",
+			err:    fmt.Errorf("failed to consume click: %w", io.EOF),
+			expect: true,
+		},
+		{
+			name:   "exhausted_pool",
+			err:    redis.ErrPoolTimeout,
+			expect: true,
+		},
+		{
+			name:   "closed_client",
+			err:    redis.ErrClosed,
+			expect: true,
+		},
+		{
+			name:   "canceled_request",
+			err:    fmt.Errorf("failed to get URL from cache: %w", context.Canceled),
+			expect: false,
+		},
+		{
+			name:   "request_deadline",
+			err:    context.DeadlineExceeded,
+			expect: false,
+		},
+		{
+			name:   "unparsable_settings",
+			err:    fmt.Errorf("invalid max_clicks in URL settings: %w", &strconv.NumError{Func: "ParseInt", Num: "x", Err: strconv.ErrSyntax}),
+			expect: false,
+		},
+		{
+			name:   "missing_key",
+			err:    redis.Nil,
+			expect: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cache.IsUnavailable(tt.err); got != tt.expect {
+				t.Errorf("expected %v, got %v", tt.expect, got)
+			}
+		})
+	}
+}
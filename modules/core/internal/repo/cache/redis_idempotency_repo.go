@@ -0,0 +1,70 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/redis/go-redis/v9"
+)
+
+const idempotencyKeyPrefix = "idempotency:"
+
+type RedisIdempotencyRepo struct {
+	client *redis.Client
+}
+
+func NewRedisIdempotencyRepo(client *redis.Client) *RedisIdempotencyRepo {
+	return &RedisIdempotencyRepo{client: client}
+}
+
+// Reserve claims the key with SET NX so only one request can hold it at a time.
+func (r *RedisIdempotencyRepo) Reserve(ctx context.Context, key string, record *entity.IdempotencyRecord, ttl time.Duration) (bool, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return false, fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	ok, err := r.client.SetNX(ctx, idempotencyKeyPrefix+key, data, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	return ok, nil
+}
+
+func (r *RedisIdempotencyRepo) Get(ctx context.Context, key string) (*entity.IdempotencyRecord, error) {
+	data, err := r.client.Get(ctx, idempotencyKeyPrefix+key).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency record: %w", err)
+	}
+
+	var record entity.IdempotencyRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, fmt.Errorf("failed to decode idempotency record: %w", err)
+	}
+	return &record, nil
+}
+
+func (r *RedisIdempotencyRepo) Complete(ctx context.Context, key string, record *entity.IdempotencyRecord, ttl time.Duration) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode idempotency record: %w", err)
+	}
+
+	if err := r.client.Set(ctx, idempotencyKeyPrefix+key, data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to store idempotency record: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisIdempotencyRepo) Release(ctx context.Context, key string) error {
+	if err := r.client.Del(ctx, idempotencyKeyPrefix+key).Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/config"
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/repo/cache"
+)
+
+func TestRedisIdempotencyRepo_Lifecycle(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisIdempotencyRepo(testRedis.Client)
+	ctx := context.Background()
+
+	record, err := repo.Get(ctx, "missing")
+	if err != nil || record != nil {
+		t.Fatalf("expected nil record for missing key, got %+v, %v", record, err)
+	}
+
+	reserved, err := repo.Reserve(ctx, "key-1", &entity.IdempotencyRecord{Fingerprint: "fp"}, time.Minute)
+	if err != nil || !reserved {
+		t.Fatalf("expected first reserve to succeed, got %v, %v", reserved, err)
+	}
+
+	reserved, err = repo.Reserve(ctx, "key-1", &entity.IdempotencyRecord{Fingerprint: "fp"}, time.Minute)
+	if err != nil || reserved {
+		t.Fatalf("expected second reserve to fail, got %v, %v", reserved, err)
+	}
+
+	final := &entity.IdempotencyRecord{Fingerprint: "fp", Completed: true, StatusCode: 200, Body: []byte(`{"short_code":"abc"}`)}
+	if err := repo.Complete(ctx, "key-1", final, time.Hour); err != nil {
+		t.Fatalf("failed to complete: %v", err)
+	}
+
+	record, err = repo.Get(ctx, "key-1")
+	if err != nil {
+		t.Fatalf("failed to get record: %v", err)
+	}
+	if !record.Completed || record.StatusCode != 200 || string(record.Body) != string(final.Body) {
+		t.Errorf("expected %+v, got %+v", final, record)
+	}
+
+	if err := repo.Release(ctx, "key-1"); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+	record, _ = repo.Get(ctx, "key-1")
+	if record != nil {
+		t.Errorf("expected released key to be gone, got %+v", record)
+	}
+}
+
+func TestRedisIdempotencyRepo_ConcurrentReserve(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisIdempotencyRepo(testRedis.Client)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var winners atomic.Int32
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.Reserve(ctx, "contended", &entity.IdempotencyRecord{Fingerprint: "fp"}, time.Minute)
+			if err == nil && ok {
+				winners.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if winners.Load() != 1 {
+		t.Errorf("expected exactly one reserve to win, got %d", winners.Load())
+	}
+}
@@ -0,0 +1,74 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const unlockAttemptKeyPrefix = "unlock_attempts:"
+
+// reserveUnlockAttemptScript counts an unlock attempt in a fixed window that starts
+// with the first attempt.
+// KEYS[1] = attempts key, ARGV[1] = limit, ARGV[2] = window in milliseconds
+// Returns 0 if the attempt is allowed, otherwise the milliseconds until the window ends.
+var reserveUnlockAttemptScript = redis.NewScript(`
+local attempts = redis.call('INCR', KEYS[1])
+if attempts == 1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+if attempts <= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('DECR', KEYS[1])
+return math.max(redis.call('PTTL', KEYS[1]), 1)
+`)
+
+// releaseUnlockAttemptScript gives back an attempt without outliving the window.
+// KEYS[1] = attempts key
+var releaseUnlockAttemptScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	redis.call('DECR', KEYS[1])
+end
+return 0
+`)
+
+type RedisUnlockAttemptRepo struct {
+	client *redis.Client
+}
+
+func NewRedisUnlockAttemptRepo(client *redis.Client) *RedisUnlockAttemptRepo {
+	return &RedisUnlockAttemptRepo{client: client}
+}
+
+// Reserve takes one of the link's unlock attempts for the current window. Counting
+// happens before the password is checked, so concurrent guesses cannot overrun the limit.
+func (r *RedisUnlockAttemptRepo) Reserve(ctx context.Context, id int64, limit int64, window time.Duration) (bool, time.Duration, error) {
+	ms, err := reserveUnlockAttemptScript.Run(
+		ctx,
+		r.client,
+		[]string{unlockAttemptKey(id)},
+		limit,
+		window.Milliseconds(),
+	).Int64()
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to reserve unlock attempt: %w", err)
+	}
+	if ms > 0 {
+		return false, time.Duration(ms) * time.Millisecond, nil
+	}
+	return true, 0, nil
+}
+
+func (r *RedisUnlockAttemptRepo) Release(ctx context.Context, id int64) error {
+	if err := releaseUnlockAttemptScript.Run(ctx, r.client, []string{unlockAttemptKey(id)}).Err(); err != nil {
+		return fmt.Errorf("failed to release unlock attempt: %w", err)
+	}
+	return nil
+}
+
+func unlockAttemptKey(id int64) string {
+	return fmt.Sprintf("%s%d", unlockAttemptKeyPrefix, id)
+}
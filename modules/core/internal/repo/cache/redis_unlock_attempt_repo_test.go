@@ -0,0 +1,89 @@
+package cache_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/config"
+	"github.com/nanda/doit/modules/core/internal/repo/cache"
+)
+
+func TestRedisUnlockAttemptRepo_LimitsConcurrentAttempts(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisUnlockAttemptRepo(testRedis.Client)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	var allowed atomic.Int64
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, _, err := repo.Reserve(ctx, 1, 5, time.Minute)
+			if err != nil {
+				t.Errorf("failed to reserve attempt: %v", err)
+			}
+			if ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if allowed.Load() != 5 {
+		t.Errorf("expected exactly 5 attempts, got %d", allowed.Load())
+	}
+
+	ok, retryAfter, err := repo.Reserve(ctx, 1, 5, time.Minute)
+	if err != nil || ok {
+		t.Fatalf("expected locked link, got ok=%v err=%v", ok, err)
+	}
+	if retryAfter <= 0 || retryAfter > time.Minute {
+		t.Errorf("expected retry within the window, got %v", retryAfter)
+	}
+
+	// Other links have their own budget
+	if ok, _, err := repo.Reserve(ctx, 2, 5, time.Minute); err != nil || !ok {
+		t.Errorf("expected other link to be allowed, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestRedisUnlockAttemptRepo_ReleaseAndExpiry(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisUnlockAttemptRepo(testRedis.Client)
+	ctx := context.Background()
+
+	if ok, _, err := repo.Reserve(ctx, 1, 1, 100*time.Millisecond); err != nil || !ok {
+		t.Fatalf("expected first attempt to be allowed, got ok=%v err=%v", ok, err)
+	}
+	if err := repo.Release(ctx, 1); err != nil {
+		t.Fatalf("failed to release attempt: %v", err)
+	}
+	if ok, _, err := repo.Reserve(ctx, 1, 1, 100*time.Millisecond); err != nil || !ok {
+		t.Fatalf("expected released attempt to be reusable, got ok=%v err=%v", ok, err)
+	}
+	if ok, _, _ := repo.Reserve(ctx, 1, 1, 100*time.Millisecond); ok {
+		t.Fatal("expected limit to be reached")
+	}
+
+	time.Sleep(150 * time.Millisecond)
+	if ok, _, err := repo.Reserve(ctx, 1, 1, 100*time.Millisecond); err != nil || !ok {
+		t.Errorf("expected a new window after expiry, got ok=%v err=%v", ok, err)
+	}
+
+	// Releasing after the window ended must not leave a counter without a TTL
+	time.Sleep(150 * time.Millisecond)
+	if err := repo.Release(ctx, 1); err != nil {
+		t.Fatalf("failed to release attempt: %v", err)
+	}
+	if exists := testRedis.Client.Exists(ctx, "unlock_attempts:1").Val(); exists != 0 {
+		t.Error("expected no attempts key after release of an expired window")
+	}
+}
@@ -2,17 +2,72 @@ package cache
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
+	"github.com/nanda/doit/modules/core/entity"
 	"github.com/redis/go-redis/v9"
 )
 
 const (
 	urlIDSequenceKey = "url_id_sequence"
 	urlKeyPrefix     = "url:"
+	aliasKeyPrefix   = "alias:"
+	dedupeKeyPrefix  = "dedupe:"
+	revokedKeyPrefix = "revoked:"
+
+	// New links stay pending until their analytics record is written: a sorted set
+	// scores their IDs by creation time in milliseconds, and a hash maps the IDs of
+	// aliased and deduplicated links to the key that indexes them.
+	pendingLinksKey = "pending_links"
+	pendingIndexKey = "pending_link_index"
+
+	// Per-link settings live in a url:{id}:meta hash with the same TTL as url:{id}.
+	metaKeySuffix       = ":meta"
+	metaFieldNotBefore  = "not_before"
+	metaFieldMaxClicks  = "max_clicks"
+	metaFieldClicksLeft = "clicks_left"
+	metaFieldPassword   = "password_hash"
 )
 
+// createWithAliasScript reserves the alias, allocates an ID and stores the URL
+// in a single server-side step so two creators can never claim the same alias.
+// Like createScript it returns -1 rather than reuse an ID whose keys still exist,
+// and marks the link pending.
+// KEYS[1] = sequence key, KEYS[2] = alias key, KEYS[3] = pending links key,
+// KEYS[4] = pending index key
+// ARGV[1] = url key prefix, ARGV[2] = long URL, ARGV[3] = TTL in milliseconds,
+// ARGV[4] = meta key suffix, ARGV[5] = creation time in milliseconds,
+// ARGV[6..] = meta field/value pairs
+var createWithAliasScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[2]) == 1 then
+	return 0
+end
+local id = redis.call('INCR', KEYS[1])
+local key = ARGV[1] .. id
+local meta = key .. ARGV[4]
+if redis.call('EXISTS', key, meta) > 0 then
+	return -1
+end
+redis.call('SET', key, ARGV[2], 'PX', ARGV[3])
+redis.call('SET', KEYS[2], id, 'PX', ARGV[3])
+if #ARGV > 5 then
+	redis.call('HSET', meta, unpack(ARGV, 6))
+	redis.call('PEXPIRE', meta, ARGV[3])
+end
+redis.call('ZADD', KEYS[3], ARGV[5], id)
+redis.call('HSET', KEYS[4], id, KEYS[2])
+return id
+`)
+
+// ErrIDInUse means the allocated ID already holds a link, so the ID sequence is
+// behind the stored links. The ID is skipped and nothing is written.
+var ErrIDInUse = errors.New("allocated URL ID is already in use")
+
 type RedisURLCacheRepo struct {
 	client *redis.Client
 }
@@ -21,29 +76,257 @@ func NewRedisURLCacheRepo(client *redis.Client) *RedisURLCacheRepo {
 	return &RedisURLCacheRepo{client: client}
 }
 
-// Create generates a new ID using INCR and stores the URL using pipeline for atomicity.
-func (r *RedisURLCacheRepo) Create(ctx context.Context, longURL string, ttl time.Duration) (int64, error) {
-	// First, get the ID using INCR
-	id, err := r.client.Incr(ctx, urlIDSequenceKey).Result()
+// createScript allocates an ID and stores the URL and its settings in a single
+// server-side step, so a failure can neither burn an ID nor leave a URL without
+// its settings. It refuses to reuse an ID whose keys still exist, which happens
+// only if the sequence fell behind the stored links, and returns 0 instead.
+// The new link is marked pending in the same step.
+// KEYS[1] = sequence key, KEYS[2] = pending links key
+// ARGV[1] = url key prefix, ARGV[2] = long URL, ARGV[3] = TTL in milliseconds,
+// ARGV[4] = meta key suffix, ARGV[5] = creation time in milliseconds,
+// ARGV[6..] = meta field/value pairs
+var createScript = redis.NewScript(`
+local id = redis.call('INCR', KEYS[1])
+local key = ARGV[1] .. id
+local meta = key .. ARGV[4]
+if redis.call('EXISTS', key, meta) > 0 then
+	return 0
+end
+redis.call('SET', key, ARGV[2], 'PX', ARGV[3])
+if #ARGV > 5 then
+	redis.call('HSET', meta, unpack(ARGV, 6))
+	redis.call('PEXPIRE', meta, ARGV[3])
+end
+redis.call('ZADD', KEYS[2], ARGV[5], id)
+return id
+`)
+
+// Create allocates a new ID and stores the URL and its settings atomically, marking
+// the link pending. The TTL is derived from ExpiresAt. It returns ErrIDInUse rather
+// than overwrite a link stored under the allocated ID.
+func (r *RedisURLCacheRepo) Create(ctx context.Context, link *entity.URL) (int64, error) {
+	ttl := time.Until(link.ExpiresAt).Milliseconds()
+	// Checked here because Redis would reject the SET only after INCR ran
+	if ttl <= 0 {
+		return 0, fmt.Errorf("failed to create URL: expiry is not in the future")
+	}
+
+	args := append([]interface{}{
+		urlKeyPrefix,
+		link.LongURL,
+		ttl,
+		metaKeySuffix,
+		time.Now().UnixMilli(),
+	}, metaFields(link)...)
+
+	id, err := createScript.Run(ctx, r.client, []string{urlIDSequenceKey, pendingLinksKey}, args...).Int64()
 	if err != nil {
-		return 0, fmt.Errorf("failed to generate ID: %w", err)
+		return 0, fmt.Errorf("failed to create URL: %w", err)
 	}
+	if id == 0 {
+		return 0, ErrIDInUse
+	}
+	return id, nil
+}
 
-	// Use pipeline to SET the URL with TTL atomically
-	key := fmt.Sprintf("%s%d", urlKeyPrefix, id)
-	pipe := r.client.Pipeline()
-	pipe.Set(ctx, key, longURL, ttl)
+// dedupeExpirySlack is how much sooner than requested a reused link may expire,
+// so requests for the same TTL made moments apart still share a link.
+const dedupeExpirySlack = time.Minute
+
+// createDedupedScript reuses the link indexed for a destination if it is still live,
+// unchanged and lives at least the requested TTL, otherwise allocates a new ID and
+// indexes it. The index key shares the link's TTL so both expire together. Like
+// createScript it returns ID 0 rather than reuse an ID whose keys still exist, and
+// marks a new link pending.
+// KEYS[1] = sequence key, KEYS[2] = dedupe index key, KEYS[3] = pending links key,
+// KEYS[4] = pending index key
+// ARGV[1] = url key prefix, ARGV[2] = long URL, ARGV[3] = TTL in milliseconds,
+// ARGV[4] = meta key suffix, ARGV[5] = creation time in milliseconds,
+// ARGV[6] = shortest TTL in milliseconds a reused link may have left
+var createDedupedScript = redis.NewScript(`
+local existing = redis.call('GET', KEYS[2])
+if existing then
+	local current = ARGV[1] .. existing
+	if redis.call('GET', current) == ARGV[2] and redis.call('PTTL', current) >= tonumber(ARGV[6]) then
+		return {tonumber(existing), 1}
+	end
+end
+local id = redis.call('INCR', KEYS[1])
+local key = ARGV[1] .. id
+if redis.call('EXISTS', key, key .. ARGV[4]) > 0 then
+	return {0, 0}
+end
+redis.call('SET', key, ARGV[2], 'PX', ARGV[3])
+redis.call('SET', KEYS[2], id, 'PX', ARGV[3])
+redis.call('ZADD', KEYS[3], ARGV[5], id)
+redis.call('HSET', KEYS[4], id, KEYS[2])
+return {id, 0}
+`)
 
-	_, err = pipe.Exec(ctx)
+// CreateDeduped returns the active link for the canonical LongURL or creates and indexes a new one.
+// A link that would expire more than dedupeExpirySlack before link.ExpiresAt is not reused.
+// Deduplicated links carry no settings, so no meta hash is written.
+func (r *RedisURLCacheRepo) CreateDeduped(ctx context.Context, link *entity.URL) (int64, bool, error) {
+	sum := sha256.Sum256([]byte(link.LongURL))
+	ttl := time.Until(link.ExpiresAt)
+	result, err := createDedupedScript.Run(
+		ctx,
+		r.client,
+		[]string{urlIDSequenceKey, dedupeKeyPrefix + hex.EncodeToString(sum[:]), pendingLinksKey, pendingIndexKey},
+		urlKeyPrefix,
+		link.LongURL,
+		ttl.Milliseconds(),
+		metaKeySuffix,
+		time.Now().UnixMilli(),
+		(ttl - dedupeExpirySlack).Milliseconds(),
+	).Int64Slice()
 	if err != nil {
-		// If SET fails, we've already incremented the counter
-		// This creates a gap in the sequence, but that's acceptable
-		return 0, fmt.Errorf("failed to set URL in cache: %w", err)
+		return 0, false, fmt.Errorf("failed to create deduped URL: %w", err)
 	}
+	if result[0] == 0 {
+		return 0, false, ErrIDInUse
+	}
+	return result[0], result[1] == 1, nil
+}
 
+// createBatchScript allocates an ID range and stores every URL of a batch in a
+// single server-side step. Like createScript it refuses to reuse IDs whose keys
+// still exist: it writes nothing and returns 0, leaving the range skipped.
+// KEYS[1] = sequence key, KEYS[2] = pending links key
+// ARGV[1] = url key prefix, ARGV[2] = meta key suffix,
+// ARGV[3] = creation time in milliseconds, ARGV[4..] = long URL/TTL in milliseconds pairs
+var createBatchScript = redis.NewScript(`
+local count = (#ARGV - 3) / 2
+local last = redis.call('INCRBY', KEYS[1], count)
+local first = last - count + 1
+for i = 0, count - 1 do
+	local key = ARGV[1] .. (first + i)
+	if redis.call('EXISTS', key, key .. ARGV[2]) > 0 then
+		return 0
+	end
+end
+for i = 0, count - 1 do
+	redis.call('SET', ARGV[1] .. (first + i), ARGV[4 + 2 * i], 'PX', ARGV[5 + 2 * i])
+	redis.call('ZADD', KEYS[2], ARGV[3], first + i)
+end
+return last
+`)
+
+// CreateBatch reserves a whole ID range and stores the URLs, marking them pending,
+// in one atomic step. It returns ErrIDInUse rather than overwrite a link stored
+// under any ID of the range.
+func (r *RedisURLCacheRepo) CreateBatch(ctx context.Context, urls []*entity.URL) error {
+	if len(urls) == 0 {
+		return nil
+	}
+
+	args := make([]interface{}, 0, 3+2*len(urls))
+	args = append(args, urlKeyPrefix, metaKeySuffix, time.Now().UnixMilli())
+	for _, u := range urls {
+		ttl := time.Until(u.ExpiresAt).Milliseconds()
+		// Checked here because Redis would reject the SET only after INCRBY ran
+		if ttl <= 0 {
+			return fmt.Errorf("failed to create URL batch: expiry is not in the future")
+		}
+		args = append(args, u.LongURL, ttl)
+	}
+
+	lastID, err := createBatchScript.Run(ctx, r.client, []string{urlIDSequenceKey, pendingLinksKey}, args...).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to set URL batch in cache: %w", err)
+	}
+	if lastID == 0 {
+		return ErrIDInUse
+	}
+
+	firstID := lastID - int64(len(urls)) + 1
+	for i, u := range urls {
+		u.ID = firstID + int64(i)
+	}
+	return nil
+}
+
+// CreateWithAlias reserves the alias and stores the URL and its settings atomically,
+// marking the link pending.
+// It returns false without allocating an ID if the alias is already taken.
+func (r *RedisURLCacheRepo) CreateWithAlias(ctx context.Context, alias string, link *entity.URL) (int64, bool, error) {
+	args := append([]interface{}{
+		urlKeyPrefix,
+		link.LongURL,
+		time.Until(link.ExpiresAt).Milliseconds(),
+		metaKeySuffix,
+		time.Now().UnixMilli(),
+	}, metaFields(link)...)
+
+	id, err := createWithAliasScript.Run(
+		ctx,
+		r.client,
+		[]string{urlIDSequenceKey, aliasKeyPrefix + alias, pendingLinksKey, pendingIndexKey},
+		args...,
+	).Int64()
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to create aliased URL: %w", err)
+	}
+	switch id {
+	case 0:
+		return 0, false, nil
+	case -1:
+		return 0, false, ErrIDInUse
+	}
+	return id, true, nil
+}
+
+func (r *RedisURLCacheRepo) ResolveAlias(ctx context.Context, alias string) (int64, error) {
+	id, err := r.client.Get(ctx, aliasKeyPrefix+alias).Int64()
+	if err == redis.Nil {
+		return 0, fmt.Errorf("alias not found or expired")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to resolve alias: %w", err)
+	}
 	return id, nil
 }
 
+// updateScript rewrites a live link and moves the expiry of its settings and alias.
+// An expired link is left alone so an edit cannot bring it back.
+// KEYS[1] = url key, KEYS[2] = meta key, KEYS[3] = alias key (optional)
+// ARGV[1] = long URL, ARGV[2] = TTL in milliseconds, ARGV[3] = id
+var updateScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[2])
+if KEYS[3] and redis.call('GET', KEYS[3]) == ARGV[3] then
+	redis.call('PEXPIRE', KEYS[3], ARGV[2])
+end
+return 1
+`)
+
+// Update replaces the long URL and TTL of a live link in one server-side step.
+func (r *RedisURLCacheRepo) Update(ctx context.Context, link *entity.URL, alias *string) error {
+	keys := []string{fmt.Sprintf("%s%d", urlKeyPrefix, link.ID), metaKey(link.ID)}
+	if alias != nil {
+		keys = append(keys, aliasKeyPrefix+*alias)
+	}
+
+	updated, err := updateScript.Run(
+		ctx,
+		r.client,
+		keys,
+		link.LongURL,
+		time.Until(link.ExpiresAt).Milliseconds(),
+		link.ID,
+	).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to update URL in cache: %w", err)
+	}
+	if updated == 0 {
+		return fmt.Errorf("URL not found or expired")
+	}
+	return nil
+}
+
 func (r *RedisURLCacheRepo) Set(ctx context.Context, id int64, longURL string, ttl time.Duration) error {
 	key := fmt.Sprintf("%s%d", urlKeyPrefix, id)
 	err := r.client.Set(ctx, key, longURL, ttl).Err()
@@ -53,23 +336,306 @@ func (r *RedisURLCacheRepo) Set(ctx context.Context, id int64, longURL string, t
 	return nil
 }
 
-func (r *RedisURLCacheRepo) Get(ctx context.Context, id int64) (string, error) {
+// restoreScript writes a recorded link's keys unless it already has some, so a
+// link that is live in Redis is never overwritten. The alias is only taken if free.
+// KEYS[1] = url key, KEYS[2] = meta key, KEYS[3] = alias key (optional)
+// ARGV[1] = long URL, ARGV[2] = TTL in milliseconds, ARGV[3] = id,
+// ARGV[4..] = meta field/value pairs
+// Returns 1 if the link was restored, 0 if it already had keys.
+var restoreScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1], KEYS[2]) > 0 then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+if #ARGV > 3 then
+	redis.call('HSET', KEYS[2], unpack(ARGV, 4))
+	redis.call('PEXPIRE', KEYS[2], ARGV[2])
+end
+if KEYS[3] then
+	redis.call('SET', KEYS[3], ARGV[3], 'PX', ARGV[2], 'NX')
+end
+return 1
+`)
+
+// Restore writes the keys of recorded links in one pipelined round trip. Links
+// that have expired by now are skipped.
+func (r *RedisURLCacheRepo) Restore(ctx context.Context, links []*entity.URLAnalytic, now time.Time) (int, error) {
+	// Scripts cannot fall back to EVAL inside a pipeline, so load it first
+	if err := restoreScript.Load(ctx, r.client).Err(); err != nil {
+		return 0, fmt.Errorf("failed to load restore script: %w", err)
+	}
+
+	pipe := r.client.Pipeline()
+	var cmds []*redis.Cmd
+	for _, link := range links {
+		ttl := link.ExpiresAt.Sub(now).Milliseconds()
+		if ttl <= 0 {
+			continue
+		}
+		keys := []string{fmt.Sprintf("%s%d", urlKeyPrefix, link.URLID), metaKey(link.URLID)}
+		if link.Alias != nil {
+			keys = append(keys, aliasKeyPrefix+*link.Alias)
+		}
+		args := append([]interface{}{link.LongURL, ttl, link.URLID}, restoreFields(link)...)
+		cmds = append(cmds, restoreScript.EvalSha(ctx, pipe, keys, args...))
+	}
+	if len(cmds) == 0 {
+		return 0, nil
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, fmt.Errorf("failed to restore URLs in cache: %w", err)
+	}
+
+	restored := 0
+	for _, cmd := range cmds {
+		if n, _ := cmd.Int64(); n == 1 {
+			restored++
+		}
+	}
+	return restored, nil
+}
+
+// advanceSequenceScript raises the sequence to a floor without ever lowering it,
+// so IDs allocated concurrently are not handed out again.
+// KEYS[1] = sequence key, ARGV[1] = floor
+// Returns the previous value of the sequence.
+var advanceSequenceScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return current
+`)
+
+// AdvanceSequence moves the ID sequence up to floor if it is lower and returns its previous value.
+func (r *RedisURLCacheRepo) AdvanceSequence(ctx context.Context, floor int64) (int64, error) {
+	previous, err := advanceSequenceScript.Run(ctx, r.client, []string{urlIDSequenceKey}, floor).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("failed to advance ID sequence: %w", err)
+	}
+	return previous, nil
+}
+
+// Get reads the URL and its settings in one round trip. ExpiresAt is not loaded.
+func (r *RedisURLCacheRepo) Get(ctx context.Context, id int64) (*entity.URL, error) {
 	key := fmt.Sprintf("%s%d", urlKeyPrefix, id)
-	longURL, err := r.client.Get(ctx, key).Result()
+	pipe := r.client.Pipeline()
+	urlCmd := pipe.Get(ctx, key)
+	metaCmd := pipe.HGetAll(ctx, metaKey(id))
+	// Exec reports a missing url key as redis.Nil; each command is inspected below
+	_, _ = pipe.Exec(ctx)
+
+	longURL, err := urlCmd.Result()
 	if err == redis.Nil {
-		return "", fmt.Errorf("URL not found or expired")
+		return nil, fmt.Errorf("URL not found or expired")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get URL from cache: %w", err)
 	}
+	meta, err := metaCmd.Result()
 	if err != nil {
-		return "", fmt.Errorf("failed to get URL from cache: %w", err)
+		return nil, fmt.Errorf("failed to get URL settings from cache: %w", err)
+	}
+
+	link := &entity.URL{ID: id, LongURL: longURL}
+	if err := applyMeta(link, meta); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// consumeClickScript spends one click of a click-limited link. Checking and
+// decrementing in one script keeps the count exact under concurrent redirects.
+// KEYS[1] = meta key, ARGV[1] = clicks left field
+// Returns -1 if the link has no click budget, 0 if it is spent, 1 if a click was taken.
+var consumeClickScript = redis.NewScript(`
+local left = redis.call('HGET', KEYS[1], ARGV[1])
+if not left then
+	return -1
+end
+if tonumber(left) <= 0 then
+	return 0
+end
+redis.call('HINCRBY', KEYS[1], ARGV[1], -1)
+return 1
+`)
+
+// ConsumeClick takes one click from a click-limited link. It returns false once the
+// limit has been reached. Exhausted links keep their keys until the TTL runs out.
+func (r *RedisURLCacheRepo) ConsumeClick(ctx context.Context, id int64) (bool, error) {
+	result, err := consumeClickScript.Run(ctx, r.client, []string{metaKey(id)}, metaFieldClicksLeft).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to consume click: %w", err)
+	}
+	if result < 0 {
+		return false, fmt.Errorf("URL not found or expired")
 	}
-	return longURL, nil
+	return result == 1, nil
 }
 
+// Delete removes the URL and its settings, and drops the link from the pending links.
 func (r *RedisURLCacheRepo) Delete(ctx context.Context, id int64) error {
 	key := fmt.Sprintf("%s%d", urlKeyPrefix, id)
-	err := r.client.Del(ctx, key).Err()
-	if err != nil {
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, key, metaKey(id))
+	pipe.ZRem(ctx, pendingLinksKey, id)
+	pipe.HDel(ctx, pendingIndexKey, strconv.FormatInt(id, 10))
+	if _, err := pipe.Exec(ctx); err != nil {
 		return fmt.Errorf("failed to delete URL from cache: %w", err)
 	}
 	return nil
 }
+
+// ListPending returns up to limit IDs of pending links created before the given
+// time, oldest first.
+func (r *RedisURLCacheRepo) ListPending(ctx context.Context, before time.Time, limit int64) ([]int64, error) {
+	members, err := r.client.ZRangeByScore(ctx, pendingLinksKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   "(" + strconv.FormatInt(before.UnixMilli(), 10),
+		Count: limit,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending URLs: %w", err)
+	}
+
+	ids := make([]int64, len(members))
+	for i, member := range members {
+		if ids[i], err = strconv.ParseInt(member, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid pending URL ID in cache: %w", err)
+		}
+	}
+	return ids, nil
+}
+
+// Confirm drops links from the pending links, keeping their keys.
+func (r *RedisURLCacheRepo) Confirm(ctx context.Context, ids ...int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	members := make([]interface{}, len(ids))
+	fields := make([]string, len(ids))
+	for i, id := range ids {
+		members[i] = id
+		fields[i] = strconv.FormatInt(id, 10)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.ZRem(ctx, pendingLinksKey, members...)
+	pipe.HDel(ctx, pendingIndexKey, fields...)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to confirm URLs: %w", err)
+	}
+	return nil
+}
+
+// discardScript removes a pending link with its settings and the alias or dedupe
+// key that indexes it, unless the key was claimed by another link since. A link
+// that is no longer pending is left alone.
+// KEYS[1] = url key, KEYS[2] = meta key, KEYS[3] = pending links key,
+// KEYS[4] = pending index key
+// ARGV[1] = id
+// Returns 1 if the link was removed, 0 if it was not pending.
+var discardScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[3], ARGV[1]) then
+	return 0
+end
+local index = redis.call('HGET', KEYS[4], ARGV[1])
+if index and redis.call('GET', index) == ARGV[1] then
+	redis.call('DEL', index)
+end
+redis.call('DEL', KEYS[1], KEYS[2])
+redis.call('ZREM', KEYS[3], ARGV[1])
+redis.call('HDEL', KEYS[4], ARGV[1])
+return 1
+`)
+
+// Discard removes a pending link entirely in one server-side step. It returns
+// false if the link is not pending, so a confirmed link is never removed.
+func (r *RedisURLCacheRepo) Discard(ctx context.Context, id int64) (bool, error) {
+	removed, err := discardScript.Run(
+		ctx,
+		r.client,
+		[]string{fmt.Sprintf("%s%d", urlKeyPrefix, id), metaKey(id), pendingLinksKey, pendingIndexKey},
+		id,
+	).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to discard URL: %w", err)
+	}
+	return removed == 1, nil
+}
+
+// MarkRevoked leaves a tombstone so redirects can tell a revoked link from an expired one.
+func (r *RedisURLCacheRepo) MarkRevoked(ctx context.Context, id int64, ttl time.Duration) error {
+	key := fmt.Sprintf("%s%d", revokedKeyPrefix, id)
+	if err := r.client.Set(ctx, key, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to mark URL revoked: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisURLCacheRepo) IsRevoked(ctx context.Context, id int64) (bool, error) {
+	key := fmt.Sprintf("%s%d", revokedKeyPrefix, id)
+	n, err := r.client.Exists(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to check URL revocation: %w", err)
+	}
+	return n == 1, nil
+}
+
+func metaKey(id int64) string {
+	return fmt.Sprintf("%s%d%s", urlKeyPrefix, id, metaKeySuffix)
+}
+
+// metaFields flattens the link's optional settings into HSET field/value pairs.
+func metaFields(link *entity.URL) []interface{} {
+	var fields []interface{}
+	if link.NotBefore != nil {
+		fields = append(fields, metaFieldNotBefore, link.NotBefore.UnixMilli())
+	}
+	if link.MaxClicks != nil {
+		fields = append(fields, metaFieldMaxClicks, *link.MaxClicks, metaFieldClicksLeft, *link.MaxClicks)
+	}
+	if link.PasswordHash != "" {
+		fields = append(fields, metaFieldPassword, link.PasswordHash)
+	}
+	return fields
+}
+
+// restoreFields flattens the settings recorded for a link into HSET field/value
+// pairs. Clicks already counted in analytics are taken off its click budget.
+func restoreFields(link *entity.URLAnalytic) []interface{} {
+	var fields []interface{}
+	if link.NotBefore != nil {
+		fields = append(fields, metaFieldNotBefore, link.NotBefore.UnixMilli())
+	}
+	if link.MaxClicks != nil {
+		clicksLeft := max(*link.MaxClicks-link.ClickCount, 0)
+		fields = append(fields, metaFieldMaxClicks, *link.MaxClicks, metaFieldClicksLeft, clicksLeft)
+	}
+	if link.PasswordHash != nil && *link.PasswordHash != "" {
+		fields = append(fields, metaFieldPassword, *link.PasswordHash)
+	}
+	return fields
+}
+
+// applyMeta copies the settings stored in the meta hash onto the link.
+func applyMeta(link *entity.URL, meta map[string]string) error {
+	if value, ok := meta[metaFieldNotBefore]; ok {
+		ms, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid not_before in cache: %w", err)
+		}
+		notBefore := time.UnixMilli(ms)
+		link.NotBefore = &notBefore
+	}
+	if value, ok := meta[metaFieldMaxClicks]; ok {
+		maxClicks, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid max_clicks in cache: %w", err)
+		}
+		link.MaxClicks = &maxClicks
+	}
+	link.PasswordHash = meta[metaFieldPassword]
+	return nil
+}
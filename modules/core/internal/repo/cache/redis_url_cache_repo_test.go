@@ -2,11 +2,17 @@ package cache_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/nanda/doit/config"
+	"github.com/nanda/doit/modules/core/entity"
 	"github.com/nanda/doit/modules/core/internal/repo/cache"
+	"github.com/redis/go-redis/v9"
 )
 
 func TestRedisURLCacheRepo_Create(t *testing.T) {
@@ -41,7 +47,7 @@ func TestRedisURLCacheRepo_Create(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			id, err := repo.Create(ctx, tt.longURL, tt.ttl)
+			id, err := repo.Create(ctx, &entity.URL{LongURL: tt.longURL, ExpiresAt: time.Now().Add(tt.ttl)})
 
 			if tt.expectErr && err == nil {
 				t.Error("expected error, got nil")
@@ -65,7 +71,7 @@ func TestRedisURLCacheRepo_Get(t *testing.T) {
 
 	// Create a URL first
 	longURL := "https://example.com/get-test"
-	id, err := repo.Create(ctx, longURL, 1*time.Hour)
+	id, err := repo.Create(ctx, &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(1 * time.Hour)})
 	if err != nil {
 		t.Fatalf("failed to create URL: %v", err)
 	}
@@ -100,14 +106,181 @@ func TestRedisURLCacheRepo_Get(t *testing.T) {
 				if err != nil {
 					t.Errorf("expected no error, got %v", err)
 				}
-				if result != tt.expectLongURL {
-					t.Errorf("expected %s, got %s", tt.expectLongURL, result)
+				if result.LongURL != tt.expectLongURL {
+					t.Errorf("expected %s, got %s", tt.expectLongURL, result.LongURL)
 				}
 			}
 		})
 	}
 }
 
+func TestRedisURLCacheRepo_NotBefore(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	notBefore := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	link := &entity.URL{LongURL: "https://example.com/launch", ExpiresAt: notBefore.Add(2 * time.Hour), NotBefore: &notBefore}
+
+	for name, create := range map[string]func() (int64, error){
+		"create": func() (int64, error) { return repo.Create(ctx, link) },
+		"create_with_alias": func() (int64, error) {
+			id, _, err := repo.CreateWithAlias(ctx, "launch-promo", link)
+			return id, err
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			id, err := create()
+			if err != nil {
+				t.Fatalf("failed to create URL: %v", err)
+			}
+
+			result, err := repo.Get(ctx, id)
+			if err != nil {
+				t.Fatalf("failed to get URL: %v", err)
+			}
+			if result.NotBefore == nil || !result.NotBefore.Equal(notBefore) {
+				t.Errorf("expected not_before %v, got %v", notBefore, result.NotBefore)
+			}
+
+			ttl := testRedis.Client.PTTL(ctx, fmt.Sprintf("url:%d:meta", id)).Val()
+			if ttl <= 2*time.Hour || ttl > 3*time.Hour {
+				t.Errorf("expected meta to expire with the link, got ttl %v", ttl)
+			}
+		})
+	}
+}
+
+func TestRedisURLCacheRepo_ConsumeClick(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	maxClicks := int64(100)
+	id, err := repo.Create(ctx, &entity.URL{LongURL: "https://example.com/limited", ExpiresAt: time.Now().Add(time.Hour), MaxClicks: &maxClicks})
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+
+	result, err := repo.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to get URL: %v", err)
+	}
+	if result.MaxClicks == nil || *result.MaxClicks != maxClicks {
+		t.Errorf("expected max_clicks %d, got %v", maxClicks, result.MaxClicks)
+	}
+
+	// Twice as many concurrent redirects as the limit allows
+	var wg sync.WaitGroup
+	var consumed atomic.Int64
+	for range 2 * maxClicks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := repo.ConsumeClick(ctx, id)
+			if err != nil {
+				t.Errorf("failed to consume click: %v", err)
+			}
+			if ok {
+				consumed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if consumed.Load() != maxClicks {
+		t.Errorf("expected exactly %d clicks, got %d", maxClicks, consumed.Load())
+	}
+
+	// The exhausted link stays readable so it can be reported as gone
+	if _, err := repo.Get(ctx, id); err != nil {
+		t.Errorf("expected exhausted URL to remain until expiry, got %v", err)
+	}
+
+	unlimited, err := repo.Create(ctx, &entity.URL{LongURL: "https://example.com/unlimited", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+	if _, err := repo.ConsumeClick(ctx, unlimited); err == nil {
+		t.Error("expected error for link without a click limit, got nil")
+	}
+}
+
+func TestRedisURLCacheRepo_PasswordHash(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	hash := "$2a$10$abcdefghijklmnopqrstuvCDEFGHIJKLMNOPQRSTUVWXYZ012345"
+	id, err := repo.Create(ctx, &entity.URL{LongURL: "https://example.com/private", ExpiresAt: time.Now().Add(time.Hour), PasswordHash: hash})
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+
+	result, err := repo.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to get URL: %v", err)
+	}
+	if result.PasswordHash != hash {
+		t.Errorf("expected password hash %s, got %s", hash, result.PasswordHash)
+	}
+}
+
+func TestRedisURLCacheRepo_Update(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	maxClicks := int64(3)
+	link := &entity.URL{LongURL: "https://example.com/old", ExpiresAt: time.Now().Add(time.Hour), MaxClicks: &maxClicks}
+	id, _, err := repo.CreateWithAlias(ctx, "edit-me", link)
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+
+	alias := "edit-me"
+	updated := &entity.URL{ID: id, LongURL: "https://example.com/new", ExpiresAt: time.Now().Add(5 * time.Hour)}
+	if err := repo.Update(ctx, updated, &alias); err != nil {
+		t.Fatalf("failed to update URL: %v", err)
+	}
+
+	result, err := repo.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to get URL: %v", err)
+	}
+	if result.LongURL != "https://example.com/new" {
+		t.Errorf("expected updated long URL, got %s", result.LongURL)
+	}
+	if result.MaxClicks == nil || *result.MaxClicks != maxClicks {
+		t.Errorf("expected settings to survive the update, got %v", result.MaxClicks)
+	}
+
+	// The link, its settings and its alias all expire together
+	for _, key := range []string{fmt.Sprintf("url:%d", id), fmt.Sprintf("url:%d:meta", id), "alias:edit-me"} {
+		ttl := testRedis.Client.PTTL(ctx, key).Val()
+		if ttl <= 4*time.Hour || ttl > 5*time.Hour {
+			t.Errorf("expected %s to expire in ~5h, got %v", key, ttl)
+		}
+	}
+
+	// An expired link is not brought back
+	missing := &entity.URL{ID: id + 100, LongURL: "https://example.com/ghost", ExpiresAt: time.Now().Add(time.Hour)}
+	if err := repo.Update(ctx, missing, nil); err == nil {
+		t.Error("expected error updating a missing URL, got nil")
+	}
+	if _, err := repo.Get(ctx, id+100); err == nil {
+		t.Error("expected missing URL to stay missing")
+	}
+}
+
 func TestRedisURLCacheRepo_Expiration(t *testing.T) {
 	testRedis := config.SetupTestRedis(t)
 	defer testRedis.Cleanup()
@@ -117,7 +290,7 @@ func TestRedisURLCacheRepo_Expiration(t *testing.T) {
 
 	// Create a URL with very short TTL
 	longURL := "https://example.com/expiration-test"
-	id, err := repo.Create(ctx, longURL, 100*time.Millisecond)
+	id, err := repo.Create(ctx, &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(100 * time.Millisecond)})
 	if err != nil {
 		t.Fatalf("failed to create URL: %v", err)
 	}
@@ -127,8 +300,8 @@ func TestRedisURLCacheRepo_Expiration(t *testing.T) {
 	if err != nil {
 		t.Errorf("expected URL to exist immediately, got error: %v", err)
 	}
-	if result != longURL {
-		t.Errorf("expected %s, got %s", longURL, result)
+	if result.LongURL != longURL {
+		t.Errorf("expected %s, got %s", longURL, result.LongURL)
 	}
 
 	// Wait for expiration
@@ -150,7 +323,7 @@ func TestRedisURLCacheRepo_Delete(t *testing.T) {
 
 	// Create a URL
 	longURL := "https://example.com/delete-test"
-	id, err := repo.Create(ctx, longURL, 1*time.Hour)
+	id, err := repo.Create(ctx, &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(1 * time.Hour)})
 	if err != nil {
 		t.Fatalf("failed to create URL: %v", err)
 	}
@@ -174,6 +347,34 @@ func TestRedisURLCacheRepo_Delete(t *testing.T) {
 	}
 }
 
+func TestRedisURLCacheRepo_MarkRevoked(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	revoked, err := repo.IsRevoked(ctx, 42)
+	if err != nil || revoked {
+		t.Fatalf("expected link not revoked, got %v, %v", revoked, err)
+	}
+
+	if err := repo.MarkRevoked(ctx, 42, 100*time.Millisecond); err != nil {
+		t.Fatalf("failed to mark revoked: %v", err)
+	}
+	revoked, err = repo.IsRevoked(ctx, 42)
+	if err != nil || !revoked {
+		t.Fatalf("expected link revoked, got %v, %v", revoked, err)
+	}
+
+	// The tombstone expires with its retention
+	time.Sleep(150 * time.Millisecond)
+	revoked, err = repo.IsRevoked(ctx, 42)
+	if err != nil || revoked {
+		t.Errorf("expected tombstone to expire, got %v, %v", revoked, err)
+	}
+}
+
 func TestRedisURLCacheRepo_Set(t *testing.T) {
 	testRedis := config.SetupTestRedis(t)
 	defer testRedis.Cleanup()
@@ -194,7 +395,537 @@ func TestRedisURLCacheRepo_Set(t *testing.T) {
 	if err != nil {
 		t.Errorf("expected no error on get, got %v", err)
 	}
-	if result != longURL {
-		t.Errorf("expected %s, got %s", longURL, result)
+	if result.LongURL != longURL {
+		t.Errorf("expected %s, got %s", longURL, result.LongURL)
+	}
+}
+
+func TestRedisURLCacheRepo_CreateWithAlias(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	longURL := "https://example.com/alias-test"
+	id, reserved, err := repo.CreateWithAlias(ctx, "spring-promo", &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create aliased URL: %v", err)
+	}
+	if !reserved || id <= 0 {
+		t.Fatalf("expected alias to be reserved with positive id, got reserved=%v id=%d", reserved, id)
+	}
+
+	resolved, err := repo.ResolveAlias(ctx, "spring-promo")
+	if err != nil {
+		t.Fatalf("failed to resolve alias: %v", err)
+	}
+	if resolved != id {
+		t.Errorf("expected alias to resolve to %d, got %d", id, resolved)
+	}
+
+	result, err := repo.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("failed to get aliased URL: %v", err)
+	}
+	if result.LongURL != longURL {
+		t.Errorf("expected %s, got %s", longURL, result.LongURL)
+	}
+
+	// A second claim must fail without allocating a new ID
+	_, reserved, err = repo.CreateWithAlias(ctx, "spring-promo", &entity.URL{LongURL: "https://other.com", ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected error on duplicate alias: %v", err)
+	}
+	if reserved {
+		t.Error("expected duplicate alias to be rejected")
+	}
+
+	nextID, err := repo.Create(ctx, &entity.URL{LongURL: "https://example.com/next", ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+	if nextID != id+1 {
+		t.Errorf("expected rejected alias not to burn an ID, got next id %d after %d", nextID, id)
+	}
+}
+
+func TestRedisURLCacheRepo_CreateBatch(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(1 * time.Hour)
+	urls := []*entity.URL{
+		{LongURL: "https://example.com/batch-1", ExpiresAt: expiresAt},
+		{LongURL: "https://example.com/batch-2", ExpiresAt: expiresAt},
+		{LongURL: "https://example.com/batch-3", ExpiresAt: expiresAt},
+	}
+
+	if err := repo.CreateBatch(ctx, urls); err != nil {
+		t.Fatalf("failed to create batch: %v", err)
+	}
+
+	for i, u := range urls {
+		if i > 0 && u.ID != urls[i-1].ID+1 {
+			t.Errorf("expected contiguous IDs, got %d after %d", u.ID, urls[i-1].ID)
+		}
+
+		result, err := repo.Get(ctx, u.ID)
+		if err != nil {
+			t.Fatalf("failed to get batch URL %d: %v", u.ID, err)
+		}
+		if result.LongURL != u.LongURL {
+			t.Errorf("expected %s, got %s", u.LongURL, result.LongURL)
+		}
+	}
+
+	// The sequence continues after the reserved range
+	nextID, err := repo.Create(ctx, &entity.URL{LongURL: "https://example.com/after-batch", ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+	if nextID != urls[len(urls)-1].ID+1 {
+		t.Errorf("expected next id %d, got %d", urls[len(urls)-1].ID+1, nextID)
+	}
+}
+
+func TestRedisURLCacheRepo_CreateBatchRefusesUsedIDs(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	// A sequence that fell behind would hand out the ID of a stored link
+	testRedis.Client.Set(ctx, "url:2", "https://example.com/older", time.Hour)
+
+	expiresAt := time.Now().Add(1 * time.Hour)
+	urls := []*entity.URL{
+		{LongURL: "https://example.com/batch-1", ExpiresAt: expiresAt},
+		{LongURL: "https://example.com/batch-2", ExpiresAt: expiresAt},
+		{LongURL: "https://example.com/batch-3", ExpiresAt: expiresAt},
+	}
+	if err := repo.CreateBatch(ctx, urls); !errors.Is(err, cache.ErrIDInUse) {
+		t.Fatalf("expected ErrIDInUse, got %v", err)
+	}
+
+	// The stored link is kept and nothing of the batch is written
+	if value := testRedis.Client.Get(ctx, "url:2").Val(); value != "https://example.com/older" {
+		t.Errorf("expected stored link to be kept, got %q", value)
+	}
+	if exists := testRedis.Client.Exists(ctx, "url:1", "url:3").Val(); exists != 0 {
+		t.Errorf("expected no batch keys, got %d", exists)
+	}
+	if pending := testRedis.Client.ZCard(ctx, "pending_links").Val(); pending != 0 {
+		t.Errorf("expected no pending links, got %d", pending)
+	}
+
+	// The refused range is skipped
+	if err := repo.CreateBatch(ctx, urls[:1]); err != nil {
+		t.Fatalf("failed to create batch: %v", err)
+	}
+	if urls[0].ID != 4 {
+		t.Errorf("expected the first free ID 4, got %d", urls[0].ID)
+	}
+}
+
+func TestRedisURLCacheRepo_CreateDeduped(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+
+	longURL := "https://example.com/dedupe"
+	id, reused, err := repo.CreateDeduped(ctx, &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create deduped URL: %v", err)
+	}
+	if reused {
+		t.Error("expected first create not to reuse")
+	}
+
+	again, reused, err := repo.CreateDeduped(ctx, &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create deduped URL: %v", err)
+	}
+	if !reused || again != id {
+		t.Errorf("expected reuse of id %d, got id=%d reused=%v", id, again, reused)
+	}
+
+	// A link that would expire before the requested expiry is not reused
+	longer, reused, err := repo.CreateDeduped(ctx, &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(30 * 24 * time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create deduped URL: %v", err)
+	}
+	if reused || longer == id {
+		t.Errorf("expected a new id for a longer expiry, got id=%d reused=%v", longer, reused)
+	}
+	// and the index then points at the link that lives longer
+	shorter, reused, err := repo.CreateDeduped(ctx, &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create deduped URL: %v", err)
+	}
+	if !reused || shorter != longer {
+		t.Errorf("expected reuse of id %d, got id=%d reused=%v", longer, shorter, reused)
+	}
+	id = longer
+
+	// Once the link is gone the index no longer matches and a new ID is minted
+	if err := repo.Delete(ctx, id); err != nil {
+		t.Fatalf("failed to delete URL: %v", err)
+	}
+	fresh, reused, err := repo.CreateDeduped(ctx, &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(1 * time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create deduped URL: %v", err)
+	}
+	if reused || fresh == id {
+		t.Errorf("expected a new id after deletion, got id=%d reused=%v", fresh, reused)
+	}
+}
+
+func TestRedisURLCacheRepo_CreateIsAtomic(t *testing.T) {
+	link := func() *entity.URL {
+		maxClicks := int64(5)
+		return &entity.URL{LongURL: "https://example.com/atomic", ExpiresAt: time.Now().Add(time.Hour), MaxClicks: &maxClicks}
+	}
+
+	tests := []struct {
+		name string
+		// planted is a key stored in the way of the first ID
+		planted string
+		hook    redis.Hook
+		link    *entity.URL
+		// expectLink is whether url:1 and its settings are stored once Create returns
+		expectLink     bool
+		expectSequence int64
+		expectIDInUse  bool
+	}{
+		{
+			name:           "stored_url_is_not_overwritten",
+			planted:        "url:1",
+			link:           link(),
+			expectSequence: 1,
+			expectIDInUse:  true,
+		},
+		{
+			// HSET would fail on the wrong type after SET had already run
+			name:           "stored_settings_are_not_overwritten",
+			planted:        "url:1:meta",
+			link:           link(),
+			expectSequence: 1,
+			expectIDInUse:  true,
+		},
+		{
+			name:           "past_expiry_allocates_nothing",
+			link:           &entity.URL{LongURL: "https://example.com/atomic", ExpiresAt: time.Now().Add(-time.Hour)},
+			expectSequence: 0,
+		},
+		{
+			name:           "failed_request_writes_nothing",
+			hook:           scriptFailureHook{},
+			link:           link(),
+			expectSequence: 0,
+		},
+		{
+			name:           "lost_reply_leaves_whole_link",
+			hook:           scriptFailureHook{afterReply: true},
+			link:           link(),
+			expectLink:     true,
+			expectSequence: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testRedis := config.SetupTestRedis(t)
+			defer testRedis.Cleanup()
+			ctx := context.Background()
+
+			if tt.planted != "" {
+				testRedis.Client.Set(ctx, tt.planted, "planted", time.Hour)
+			}
+			client := testRedis.Client
+			if tt.hook != nil {
+				client = redis.NewClient(testRedis.Client.Options())
+				defer client.Close()
+				client.AddHook(tt.hook)
+			}
+
+			_, err := cache.NewRedisURLCacheRepo(client).Create(ctx, tt.link)
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+			if errors.Is(err, cache.ErrIDInUse) != tt.expectIDInUse {
+				t.Errorf("expected ErrIDInUse %v, got %v", tt.expectIDInUse, err)
+			}
+
+			sequence, _ := testRedis.Client.Get(ctx, "url_id_sequence").Int64()
+			if sequence != tt.expectSequence {
+				t.Errorf("expected sequence %d, got %d", tt.expectSequence, sequence)
+			}
+
+			switch {
+			case tt.planted != "":
+				// The planted key is kept as it was and nothing is written beside it
+				if value := testRedis.Client.Get(ctx, tt.planted).Val(); value != "planted" {
+					t.Errorf("expected planted key to be kept, got %q", value)
+				}
+				if exists := testRedis.Client.Exists(ctx, "url:1", "url:1:meta").Val(); exists != 1 {
+					t.Errorf("expected only the planted key, got %d keys", exists)
+				}
+			case tt.expectLink:
+				stored, err := cache.NewRedisURLCacheRepo(testRedis.Client).Get(ctx, 1)
+				if err != nil {
+					t.Fatalf("expected link to be stored: %v", err)
+				}
+				if stored.MaxClicks == nil || *stored.MaxClicks != 5 {
+					t.Errorf("expected link to be stored with its settings, got %+v", stored)
+				}
+				if ttl := testRedis.Client.PTTL(ctx, "url:1:meta").Val(); ttl <= 0 {
+					t.Errorf("expected settings to expire with the link, got TTL %v", ttl)
+				}
+			default:
+				if keys := testRedis.Client.Keys(ctx, "url:*").Val(); len(keys) != 0 {
+					t.Errorf("expected no URL keys, got %v", keys)
+				}
+			}
+		})
+	}
+}
+
+func TestRedisURLCacheRepo_CreateSkipsUsedIDs(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+	link := &entity.URL{LongURL: "https://example.com/next", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// A sequence that fell behind refuses each used ID once and moves past it
+	testRedis.Client.Set(ctx, "url:1", "https://example.com/older", time.Hour)
+	if _, _, err := repo.CreateWithAlias(ctx, "spring-promo", link); !errors.Is(err, cache.ErrIDInUse) {
+		t.Fatalf("expected ErrIDInUse for aliased create, got %v", err)
+	}
+	if _, err := testRedis.Client.Get(ctx, "alias:spring-promo").Result(); err != redis.Nil {
+		t.Errorf("expected refused alias not to be reserved, got %v", err)
+	}
+
+	testRedis.Client.Set(ctx, "url:2", "https://example.com/older", time.Hour)
+	if _, _, err := repo.CreateDeduped(ctx, link); !errors.Is(err, cache.ErrIDInUse) {
+		t.Fatalf("expected ErrIDInUse for deduped create, got %v", err)
+	}
+
+	id, err := repo.Create(ctx, link)
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+	if id != 3 {
+		t.Errorf("expected the first free ID 3, got %d", id)
+	}
+}
+
+var errInjected = errors.New("injected failure")
+
+// scriptFailureHook fails every script call, either before it reaches Redis or
+// after Redis ran it, as if the reply was lost.
+type scriptFailureHook struct {
+	afterReply bool
+}
+
+func (scriptFailureHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h scriptFailureHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() != "eval" && cmd.Name() != "evalsha" {
+			return next(ctx, cmd)
+		}
+		if !h.afterReply {
+			return errInjected
+		}
+		// Errors such as NOSCRIPT pass through so the script still gets to run
+		if err := next(ctx, cmd); err != nil {
+			return err
+		}
+		return errInjected
+	}
+}
+
+func (scriptFailureHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestRedisURLCacheRepo_Pending(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+	link := func(longURL string) *entity.URL {
+		return &entity.URL{LongURL: longURL, ExpiresAt: time.Now().Add(time.Hour)}
+	}
+
+	plainID, err := repo.Create(ctx, link("https://example.com/plain"))
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+	aliasID, _, err := repo.CreateWithAlias(ctx, "spring-promo", link("https://example.com/alias"))
+	if err != nil {
+		t.Fatalf("failed to create aliased URL: %v", err)
+	}
+	dedupedID, _, err := repo.CreateDeduped(ctx, link("https://example.com/deduped"))
+	if err != nil {
+		t.Fatalf("failed to create deduped URL: %v", err)
+	}
+	batch := []*entity.URL{link("https://example.com/batch")}
+	if err := repo.CreateBatch(ctx, batch); err != nil {
+		t.Fatalf("failed to create URL batch: %v", err)
+	}
+
+	pending, err := repo.ListPending(ctx, time.Now().Add(time.Second), 10)
+	if err != nil {
+		t.Fatalf("failed to list pending URLs: %v", err)
+	}
+	if fmt.Sprint(pending) != fmt.Sprint([]int64{plainID, aliasID, dedupedID, batch[0].ID}) {
+		t.Errorf("expected every new link to be pending, got %v", pending)
+	}
+	if pending, _ := repo.ListPending(ctx, time.Now().Add(-time.Minute), 10); len(pending) != 0 {
+		t.Errorf("expected no links pending from before they were created, got %v", pending)
+	}
+
+	// Confirmed links are no longer pending and can no longer be discarded
+	if err := repo.Confirm(ctx, plainID); err != nil {
+		t.Fatalf("failed to confirm URL: %v", err)
+	}
+	if discarded, err := repo.Discard(ctx, plainID); err != nil || discarded {
+		t.Errorf("expected confirmed link to be kept, got discarded=%v err=%v", discarded, err)
+	}
+	if _, err := repo.Get(ctx, plainID); err != nil {
+		t.Errorf("expected confirmed link to resolve: %v", err)
+	}
+
+	// Discarding a pending link also frees its alias and dedupe index
+	for _, id := range []int64{aliasID, dedupedID} {
+		if discarded, err := repo.Discard(ctx, id); err != nil || !discarded {
+			t.Errorf("expected link %d to be discarded, got discarded=%v err=%v", id, discarded, err)
+		}
+		if _, err := repo.Get(ctx, id); err == nil {
+			t.Errorf("expected discarded link %d to be gone", id)
+		}
+	}
+	if _, err := repo.ResolveAlias(ctx, "spring-promo"); err == nil {
+		t.Error("expected discarded alias to be freed")
+	}
+	if _, reused, _ := repo.CreateDeduped(ctx, link("https://example.com/deduped")); reused {
+		t.Error("expected discarded link not to be reused")
+	}
+
+	// Deleted links drop out too
+	if err := repo.Delete(ctx, batch[0].ID); err != nil {
+		t.Fatalf("failed to delete URL: %v", err)
+	}
+	pending, _ = repo.ListPending(ctx, time.Now().Add(time.Second), 10)
+	if len(pending) != 1 {
+		t.Errorf("expected only the re-created deduped link to be pending, got %v", pending)
+	}
+}
+
+func TestRedisURLCacheRepo_AdvanceSequence(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+	link := &entity.URL{LongURL: "https://example.com", ExpiresAt: time.Now().Add(time.Hour)}
+
+	// A lost sequence is restored to the floor
+	previous, err := repo.AdvanceSequence(ctx, 100)
+	if err != nil {
+		t.Fatalf("failed to advance sequence: %v", err)
+	}
+	if previous != 0 {
+		t.Errorf("expected missing sequence to read as 0, got %d", previous)
+	}
+	if id, _ := repo.Create(ctx, link); id != 101 {
+		t.Errorf("expected next ID 101, got %d", id)
+	}
+
+	// A sequence ahead of the floor is never moved back
+	previous, err = repo.AdvanceSequence(ctx, 50)
+	if err != nil {
+		t.Fatalf("failed to advance sequence: %v", err)
+	}
+	if previous != 101 {
+		t.Errorf("expected previous value 101, got %d", previous)
+	}
+	if id, _ := repo.Create(ctx, link); id != 102 {
+		t.Errorf("expected next ID 102, got %d", id)
+	}
+}
+
+func TestRedisURLCacheRepo_Restore(t *testing.T) {
+	testRedis := config.SetupTestRedis(t)
+	defer testRedis.Cleanup()
+
+	repo := cache.NewRedisURLCacheRepo(testRedis.Client)
+	ctx := context.Background()
+	now := time.Now()
+	none, hash, alias := "", "$2a$10$hash", "spring-promo"
+	maxClicks := int64(5)
+
+	live, err := repo.Create(ctx, &entity.URL{LongURL: "https://example.com/live", ExpiresAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("failed to create URL: %v", err)
+	}
+
+	restored, err := repo.Restore(ctx, []*entity.URLAnalytic{
+		{URLID: 100, LongURL: "https://example.com/a", ExpiresAt: now.Add(time.Hour), Alias: &alias,
+			MaxClicks: &maxClicks, ClickCount: 3, PasswordHash: &hash},
+		{URLID: 101, LongURL: "https://example.com/expired", ExpiresAt: now.Add(-time.Minute), PasswordHash: &none},
+		// A link still in Redis keeps its current state
+		{URLID: live, LongURL: "https://example.com/stale", ExpiresAt: now.Add(time.Hour), PasswordHash: &none},
+	}, now)
+	if err != nil {
+		t.Fatalf("failed to restore URLs: %v", err)
+	}
+	if restored != 1 {
+		t.Errorf("expected 1 link restored, got %d", restored)
+	}
+
+	link, err := repo.Get(ctx, 100)
+	if err != nil {
+		t.Fatalf("failed to get restored URL: %v", err)
+	}
+	if link.LongURL != "https://example.com/a" || link.PasswordHash != hash || link.MaxClicks == nil {
+		t.Errorf("expected restored settings, got %+v", link)
+	}
+	ttl := testRedis.Client.PTTL(ctx, "url:100").Val()
+	if ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Errorf("expected the remaining TTL, got %v", ttl)
+	}
+	if id, err := repo.ResolveAlias(ctx, alias); err != nil || id != 100 {
+		t.Errorf("expected alias to resolve to 100, got %d, %v", id, err)
+	}
+
+	// Clicks counted before the loss are spent
+	for i := 0; i < 2; i++ {
+		if ok, _ := repo.ConsumeClick(ctx, 100); !ok {
+			t.Fatalf("expected click %d to be allowed", i+1)
+		}
+	}
+	if ok, _ := repo.ConsumeClick(ctx, 100); ok {
+		t.Error("expected the click budget to be spent")
+	}
+
+	if _, err := repo.Get(ctx, 101); err == nil {
+		t.Error("expected expired link not to be restored")
+	}
+	if link, _ := repo.Get(ctx, live); link == nil || link.LongURL != "https://example.com/live" {
+		t.Errorf("expected live link to be left alone, got %+v", link)
 	}
 }
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+)
+
+type PostgresAPIKeyRepo struct {
+	db *sql.DB
+}
+
+func NewPostgresAPIKeyRepo(db *sql.DB) *PostgresAPIKeyRepo {
+	return &PostgresAPIKeyRepo{db: db}
+}
+
+func (r *PostgresAPIKeyRepo) Create(ctx context.Context, key *entity.APIKey) (int64, error) {
+	var id int64
+	err := r.db.QueryRowContext(
+		ctx,
+		`INSERT INTO api_keys (owner_id, name, key_hash, created_at) VALUES ($1, $2, $3, $4) RETURNING id`,
+		key.OwnerID,
+		key.Name,
+		key.KeyHash,
+		key.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *PostgresAPIKeyRepo) GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	var key entity.APIKey
+	err := r.db.QueryRowContext(
+		ctx,
+		`SELECT id, owner_id, name, key_hash, created_at, revoked_at FROM api_keys WHERE key_hash = $1`,
+		keyHash,
+	).Scan(&key.ID, &key.OwnerID, &key.Name, &key.KeyHash, &key.CreatedAt, &key.RevokedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *PostgresAPIKeyRepo) Revoke(ctx context.Context, id int64, now time.Time) error {
+	result, err := r.db.ExecContext(
+		ctx,
+		`UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND revoked_at IS NULL`,
+		now,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n > 0 {
+		return err
+	}
+
+	// Nothing changed: the key is unknown or was revoked already
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM api_keys WHERE id = $1)`, id).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return sql.ErrNoRows
+	}
+	return nil
+}
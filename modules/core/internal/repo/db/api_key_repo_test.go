@@ -0,0 +1,58 @@
+package db_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/config"
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/repo/db"
+)
+
+func TestPostgresAPIKeyRepo(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	repo := db.NewPostgresAPIKeyRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Microsecond)
+
+	id, err := repo.Create(ctx, &entity.APIKey{OwnerID: "team-a", Name: "ci", KeyHash: "hash-1", CreatedAt: now})
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+
+	key, err := repo.GetByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("failed to get key: %v", err)
+	}
+	if key.ID != id || key.OwnerID != "team-a" || key.Name != "ci" || key.RevokedAt != nil {
+		t.Errorf("unexpected key: %+v", key)
+	}
+
+	if _, err := repo.GetByHash(ctx, "hash-2"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for unknown hash, got %v", err)
+	}
+
+	// Revoking twice keeps the first time
+	if err := repo.Revoke(ctx, id, now); err != nil {
+		t.Fatalf("failed to revoke key: %v", err)
+	}
+	if err := repo.Revoke(ctx, id, now.Add(time.Hour)); err != nil {
+		t.Fatalf("failed to revoke key again: %v", err)
+	}
+	key, err = repo.GetByHash(ctx, "hash-1")
+	if err != nil {
+		t.Fatalf("failed to get key: %v", err)
+	}
+	if key.RevokedAt == nil || !key.RevokedAt.Equal(now) {
+		t.Errorf("expected revoked_at %v, got %v", now, key.RevokedAt)
+	}
+
+	if err := repo.Revoke(ctx, id+1, now); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for unknown key, got %v", err)
+	}
+}
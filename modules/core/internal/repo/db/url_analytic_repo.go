@@ -3,11 +3,19 @@ package db
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/nanda/doit/modules/core/entity"
 )
 
+// urlAnalyticColumns are the columns read by scanURLAnalytic, in order.
+const urlAnalyticColumns = `id, url_id, alias, long_url, created_at, expires_at, not_before, max_clicks, click_count,
+	last_accessed_at, revoked_at, revoke_reason, owner_id, password_hash`
+
 type PostgresURLAnalyticRepo struct {
 	db *sql.DB
 }
@@ -20,14 +28,20 @@ func (r *PostgresURLAnalyticRepo) Create(ctx context.Context, analytic *entity.U
 	var id int64
 	err := r.db.QueryRowContext(
 		ctx,
-		`INSERT INTO url_analytics (url_id, long_url, created_at, expires_at, click_count, last_accessed_at)
-		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		`INSERT INTO url_analytics (url_id, alias, long_url, created_at, expires_at, not_before, max_clicks, click_count, last_accessed_at,
+		                            owner_id, password_hash)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`,
 		analytic.URLID,
+		analytic.Alias,
 		analytic.LongURL,
 		analytic.CreatedAt,
 		analytic.ExpiresAt,
+		analytic.NotBefore,
+		analytic.MaxClicks,
 		analytic.ClickCount,
 		analytic.LastAccessedAt,
+		analytic.OwnerID,
+		analytic.PasswordHash,
 	).Scan(&id)
 	if err != nil {
 		return 0, err
@@ -35,26 +49,98 @@ func (r *PostgresURLAnalyticRepo) Create(ctx context.Context, analytic *entity.U
 	return id, nil
 }
 
+// CreateBatch inserts all analytics rows in a single statement by unnesting column arrays,
+// which avoids the bind-parameter limit of a multi-row VALUES list.
+func (r *PostgresURLAnalyticRepo) CreateBatch(ctx context.Context, analytics []*entity.URLAnalytic) error {
+	if len(analytics) == 0 {
+		return nil
+	}
+
+	urlIDs := make([]int64, len(analytics))
+	longURLs := make([]string, len(analytics))
+	// Timestamps travel as RFC3339 text; pq.Array has no native time.Time support
+	createdAts := make([]string, len(analytics))
+	expiresAts := make([]string, len(analytics))
+	ownerIDs := make([]sql.NullString, len(analytics))
+	for i, a := range analytics {
+		urlIDs[i] = a.URLID
+		longURLs[i] = a.LongURL
+		createdAts[i] = a.CreatedAt.Format(time.RFC3339Nano)
+		expiresAts[i] = a.ExpiresAt.Format(time.RFC3339Nano)
+		if a.OwnerID != nil {
+			ownerIDs[i] = sql.NullString{String: *a.OwnerID, Valid: true}
+		}
+	}
+
+	_, err := r.db.ExecContext(
+		ctx,
+		`INSERT INTO url_analytics (url_id, long_url, created_at, expires_at, owner_id, password_hash)
+		 SELECT *, '' FROM unnest($1::bigint[], $2::text[], $3::timestamptz[], $4::timestamptz[], $5::text[])`,
+		pq.Array(urlIDs),
+		pq.Array(longURLs),
+		pq.Array(createdAts),
+		pq.Array(expiresAts),
+		pq.Array(ownerIDs),
+	)
+	return err
+}
+
 func (r *PostgresURLAnalyticRepo) GetByURLID(ctx context.Context, urlID int64) (*entity.URLAnalytic, error) {
-	var analytic entity.URLAnalytic
-	err := r.db.QueryRowContext(
+	row := r.db.QueryRowContext(
 		ctx,
-		`SELECT id, url_id, long_url, created_at, expires_at, click_count, last_accessed_at
-		 FROM url_analytics WHERE url_id = $1`,
+		`SELECT `+urlAnalyticColumns+` FROM url_analytics WHERE url_id = $1`,
 		urlID,
-	).Scan(
-		&analytic.ID,
-		&analytic.URLID,
-		&analytic.LongURL,
-		&analytic.CreatedAt,
-		&analytic.ExpiresAt,
-		&analytic.ClickCount,
-		&analytic.LastAccessedAt,
+	)
+	return scanURLAnalytic(row)
+}
+
+// GetByAlias returns the most recent link created under the alias.
+// Aliases can be reused once they expire, so older rows are shadowed.
+func (r *PostgresURLAnalyticRepo) GetByAlias(ctx context.Context, alias string) (*entity.URLAnalytic, error) {
+	row := r.db.QueryRowContext(
+		ctx,
+		`SELECT `+urlAnalyticColumns+` FROM url_analytics WHERE alias = $1
+		 ORDER BY created_at DESC LIMIT 1`,
+		alias,
+	)
+	return scanURLAnalytic(row)
+}
+
+// ListLive returns up to limit links that are unexpired at now and not revoked,
+// with url_id above afterURLID, in url_id order.
+func (r *PostgresURLAnalyticRepo) ListLive(ctx context.Context, afterURLID int64, now time.Time, limit int) ([]*entity.URLAnalytic, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT `+urlAnalyticColumns+` FROM url_analytics
+		 WHERE url_id > $1 AND expires_at > $2 AND revoked_at IS NULL
+		 ORDER BY url_id LIMIT $3`,
+		afterURLID,
+		now,
+		limit,
 	)
 	if err != nil {
 		return nil, err
 	}
-	return &analytic, nil
+	defer func() { _ = rows.Close() }()
+
+	var analytics []*entity.URLAnalytic
+	for rows.Next() {
+		analytic, err := scanURLAnalytic(rows)
+		if err != nil {
+			return nil, err
+		}
+		analytics = append(analytics, analytic)
+	}
+	return analytics, rows.Err()
+}
+
+func (r *PostgresURLAnalyticRepo) MaxURLID(ctx context.Context) (int64, error) {
+	var maxID int64
+	err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(url_id), 0) FROM url_analytics`).Scan(&maxID)
+	if err != nil {
+		return 0, err
+	}
+	return maxID, nil
 }
 
 func (r *PostgresURLAnalyticRepo) UpdateStat(ctx context.Context, urlID int64, now time.Time) error {
@@ -66,3 +152,202 @@ func (r *PostgresURLAnalyticRepo) UpdateStat(ctx context.Context, urlID int64, n
 	)
 	return err
 }
+
+// ApplyChange updates the analytics row and appends to the change history in one transaction,
+// so the history never disagrees with the current destination.
+func (r *PostgresURLAnalyticRepo) ApplyChange(ctx context.Context, change *entity.LinkChange) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.ExecContext(
+		ctx,
+		`UPDATE url_analytics SET long_url = $1, expires_at = $2 WHERE url_id = $3`,
+		change.NewLongURL,
+		change.NewExpiresAt,
+		change.URLID,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(
+		ctx,
+		`INSERT INTO url_changes (url_id, old_long_url, new_long_url, old_expires_at, new_expires_at, changed_at)
+		 VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`,
+		change.URLID,
+		change.OldLongURL,
+		change.NewLongURL,
+		change.OldExpiresAt,
+		change.NewExpiresAt,
+		change.ChangedAt,
+	).Scan(&change.ID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (r *PostgresURLAnalyticRepo) ListChanges(ctx context.Context, urlID int64) ([]*entity.LinkChange, error) {
+	rows, err := r.db.QueryContext(
+		ctx,
+		`SELECT id, url_id, old_long_url, new_long_url, old_expires_at, new_expires_at, changed_at
+		 FROM url_changes WHERE url_id = $1
+		 ORDER BY changed_at, id`,
+		urlID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var changes []*entity.LinkChange
+	for rows.Next() {
+		var change entity.LinkChange
+		err := rows.Scan(
+			&change.ID,
+			&change.URLID,
+			&change.OldLongURL,
+			&change.NewLongURL,
+			&change.OldExpiresAt,
+			&change.NewExpiresAt,
+			&change.ChangedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		changes = append(changes, &change)
+	}
+	return changes, rows.Err()
+}
+
+func (r *PostgresURLAnalyticRepo) Revoke(ctx context.Context, urlID int64, reason *string, now time.Time) error {
+	_, err := r.db.ExecContext(
+		ctx,
+		`UPDATE url_analytics SET revoked_at = $1, revoke_reason = $2 WHERE url_id = $3 AND revoked_at IS NULL`,
+		now,
+		reason,
+		urlID,
+	)
+	return err
+}
+
+// linkSortExpressions are the ORDER BY expressions of each sort; they match the
+// expressions of the listing indexes. Never-accessed links sort as the oldest access.
+var linkSortExpressions = map[entity.LinkSort]string{
+	entity.SortCreatedAt:      "created_at",
+	entity.SortClickCount:     "click_count",
+	entity.SortLastAccessedAt: "COALESCE(last_accessed_at, 'epoch'::timestamptz)",
+}
+
+// ListByOwner pages through an owner's links with keyset pagination on the sort
+// expression and id, so deep pages cost the same as the first.
+func (r *PostgresURLAnalyticRepo) ListByOwner(ctx context.Context, query entity.LinkQuery) ([]*entity.URLAnalytic, error) {
+	sortExpr, ok := linkSortExpressions[query.Sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown link sort %q", query.Sort)
+	}
+
+	args := &queryArgs{}
+	where := append([]string{"owner_id = " + args.add(query.OwnerID)}, linkFilters(query, args)...)
+
+	direction, comparison := "DESC", "<"
+	if query.Ascending {
+		direction, comparison = "ASC", ">"
+	}
+	if after := query.After; after != nil {
+		var value any = after.Time
+		if query.Sort == entity.SortClickCount {
+			value = after.Count
+		}
+		where = append(where, fmt.Sprintf("(%s, id) %s (%s, %s)", sortExpr, comparison, args.add(value), args.add(after.ID)))
+	}
+
+	rows, err := r.db.QueryContext(
+		ctx,
+		fmt.Sprintf(
+			`SELECT %s FROM url_analytics WHERE %s ORDER BY %s %s, id %s LIMIT %s`,
+			urlAnalyticColumns, strings.Join(where, " AND "), sortExpr, direction, direction, args.add(query.Limit),
+		),
+		*args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var analytics []*entity.URLAnalytic
+	for rows.Next() {
+		analytic, err := scanURLAnalytic(rows)
+		if err != nil {
+			return nil, err
+		}
+		analytics = append(analytics, analytic)
+	}
+	return analytics, rows.Err()
+}
+
+// linkFilters returns the WHERE conditions for the optional filters of a listing.
+func linkFilters(query entity.LinkQuery, args *queryArgs) []string {
+	var where []string
+	if suffix, ok := strings.CutPrefix(query.Domain, "*."); ok {
+		param := args.add("." + suffix)
+		where = append(where, fmt.Sprintf("right(destination_host, length(%s)) = %s", param, param))
+	} else if query.Domain != "" {
+		where = append(where, "destination_host = "+args.add(query.Domain))
+	}
+	if query.CreatedAfter != nil {
+		where = append(where, "created_at >= "+args.add(*query.CreatedAfter))
+	}
+	if query.CreatedBefore != nil {
+		where = append(where, "created_at < "+args.add(*query.CreatedBefore))
+	}
+	switch query.Status {
+	case entity.LinkStatusActive:
+		where = append(where, "revoked_at IS NULL AND expires_at > "+args.add(query.Now))
+	case entity.LinkStatusExpired:
+		where = append(where, "revoked_at IS NULL AND expires_at <= "+args.add(query.Now))
+	case entity.LinkStatusRevoked:
+		where = append(where, "revoked_at IS NOT NULL")
+	}
+	if query.MinClicks != nil {
+		where = append(where, "click_count >= "+args.add(*query.MinClicks))
+	}
+	return where
+}
+
+// queryArgs collects the arguments of a query built from optional parts.
+type queryArgs []any
+
+// add appends an argument and returns its placeholder.
+func (a *queryArgs) add(value any) string {
+	*a = append(*a, value)
+	return "$" + strconv.Itoa(len(*a))
+}
+
+func scanURLAnalytic(row interface{ Scan(dest ...any) error }) (*entity.URLAnalytic, error) {
+	var analytic entity.URLAnalytic
+	err := row.Scan(
+		&analytic.ID,
+		&analytic.URLID,
+		&analytic.Alias,
+		&analytic.LongURL,
+		&analytic.CreatedAt,
+		&analytic.ExpiresAt,
+		&analytic.NotBefore,
+		&analytic.MaxClicks,
+		&analytic.ClickCount,
+		&analytic.LastAccessedAt,
+		&analytic.RevokedAt,
+		&analytic.RevokeReason,
+		&analytic.OwnerID,
+		&analytic.PasswordHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &analytic, nil
+}
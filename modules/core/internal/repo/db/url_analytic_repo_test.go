@@ -2,6 +2,7 @@ package db_test
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -49,6 +50,17 @@ func TestPostgresURLAnalyticRepo_Create(t *testing.T) {
 			expectID:  true,
 			expectErr: false,
 		},
+		{
+			// A reused link ID must fail so the creator discards its Redis link
+			name: "create_analytic_with_recorded_url_id_fails",
+			analytic: &entity.URLAnalytic{
+				URLID:     1,
+				LongURL:   "https://example.com/other",
+				CreatedAt: now,
+				ExpiresAt: now.Add(24 * time.Hour),
+			},
+			expectErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -139,6 +151,108 @@ func TestPostgresURLAnalyticRepo_GetByURLID(t *testing.T) {
 	}
 }
 
+func TestPostgresURLAnalyticRepo_CreateBatch(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now()
+
+	analytics := []*entity.URLAnalytic{
+		{URLID: 300, LongURL: "https://example.com/batch-1", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{URLID: 301, LongURL: "https://example.com/batch-\"quoted\"", CreatedAt: now, ExpiresAt: now.Add(2 * time.Hour)},
+	}
+
+	if err := analyticRepo.CreateBatch(ctx, analytics); err != nil {
+		t.Fatalf("failed to create batch: %v", err)
+	}
+
+	for _, want := range analytics {
+		got, err := analyticRepo.GetByURLID(ctx, want.URLID)
+		if err != nil {
+			t.Fatalf("failed to get analytic %d: %v", want.URLID, err)
+		}
+		if got.LongURL != want.LongURL {
+			t.Errorf("expected long url %s, got %s", want.LongURL, got.LongURL)
+		}
+		if !got.ExpiresAt.Equal(want.ExpiresAt.Truncate(time.Microsecond)) {
+			t.Errorf("expected expires_at %v, got %v", want.ExpiresAt, got.ExpiresAt)
+		}
+		if got.ClickCount != 0 {
+			t.Errorf("expected click count 0, got %d", got.ClickCount)
+		}
+	}
+}
+
+func TestPostgresURLAnalyticRepo_GetByAlias(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now()
+	alias := "spring-promo"
+
+	// An expired link and a newer one reusing the same alias
+	for i, longURL := range []string{"https://example.com/old", "https://example.com/new"} {
+		_, err := analyticRepo.Create(ctx, &entity.URLAnalytic{
+			URLID:     int64(200 + i),
+			Alias:     &alias,
+			LongURL:   longURL,
+			CreatedAt: now.Add(time.Duration(i) * time.Hour),
+			ExpiresAt: now.Add(time.Duration(i+1) * time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to create analytic: %v", err)
+		}
+	}
+
+	analytic, err := analyticRepo.GetByAlias(ctx, alias)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if analytic.URLID != 201 {
+		t.Errorf("expected latest url id 201, got %d", analytic.URLID)
+	}
+	if analytic.Alias == nil || *analytic.Alias != alias {
+		t.Errorf("expected alias %s, got %v", alias, analytic.Alias)
+	}
+
+	if _, err := analyticRepo.GetByAlias(ctx, "unknown-promo"); err == nil {
+		t.Error("expected error for unknown alias, got nil")
+	}
+}
+
+func TestPostgresURLAnalyticRepo_NotBefore(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now()
+	notBefore := now.Add(48 * time.Hour).Truncate(time.Microsecond)
+
+	_, err := analyticRepo.Create(ctx, &entity.URLAnalytic{
+		URLID:     400,
+		LongURL:   "https://example.com/launch",
+		CreatedAt: now,
+		ExpiresAt: notBefore.Add(24 * time.Hour),
+		NotBefore: &notBefore,
+	})
+	if err != nil {
+		t.Fatalf("failed to create analytic: %v", err)
+	}
+
+	analytic, err := analyticRepo.GetByURLID(ctx, 400)
+	if err != nil {
+		t.Fatalf("failed to get analytic: %v", err)
+	}
+	if analytic.NotBefore == nil || !analytic.NotBefore.Equal(notBefore) {
+		t.Errorf("expected not_before %v, got %v", notBefore, analytic.NotBefore)
+	}
+}
+
 func TestPostgresURLAnalyticRepo_UpdateStat(t *testing.T) {
 	testDB := config.SetupTestDB(t)
 	defer testDB.Cleanup()
@@ -237,3 +351,353 @@ func TestPostgresURLAnalyticRepo_UpdateStatMultipleTimes(t *testing.T) {
 		t.Errorf("expected click count 100, got %d", analytic.ClickCount)
 	}
 }
+
+func TestPostgresURLAnalyticRepo_MaxClicks(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now()
+	maxClicks := int64(1)
+
+	_, err := analyticRepo.Create(ctx, &entity.URLAnalytic{
+		URLID:     401,
+		LongURL:   "https://example.com/reset",
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+		MaxClicks: &maxClicks,
+	})
+	if err != nil {
+		t.Fatalf("failed to create analytic: %v", err)
+	}
+
+	analytic, err := analyticRepo.GetByURLID(ctx, 401)
+	if err != nil {
+		t.Fatalf("failed to get analytic: %v", err)
+	}
+	if analytic.MaxClicks == nil || *analytic.MaxClicks != maxClicks {
+		t.Errorf("expected max_clicks %d, got %v", maxClicks, analytic.MaxClicks)
+	}
+}
+
+func TestPostgresURLAnalyticRepo_ApplyChange(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Microsecond)
+
+	_, err := analyticRepo.Create(ctx, &entity.URLAnalytic{
+		URLID:     500,
+		LongURL:   "https://example.com/v1",
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create analytic: %v", err)
+	}
+
+	changes := []*entity.LinkChange{
+		{URLID: 500, OldLongURL: "https://example.com/v1", NewLongURL: "https://example.com/v2", OldExpiresAt: now.Add(time.Hour), NewExpiresAt: now.Add(time.Hour), ChangedAt: now.Add(time.Minute)},
+		{URLID: 500, OldLongURL: "https://example.com/v2", NewLongURL: "https://example.com/v2", OldExpiresAt: now.Add(time.Hour), NewExpiresAt: now.Add(3 * time.Hour), ChangedAt: now.Add(2 * time.Minute)},
+	}
+	for _, change := range changes {
+		if err := analyticRepo.ApplyChange(ctx, change); err != nil {
+			t.Fatalf("failed to apply change: %v", err)
+		}
+		if change.ID <= 0 {
+			t.Errorf("expected change ID to be set, got %d", change.ID)
+		}
+	}
+
+	analytic, err := analyticRepo.GetByURLID(ctx, 500)
+	if err != nil {
+		t.Fatalf("failed to get analytic: %v", err)
+	}
+	if analytic.LongURL != "https://example.com/v2" || !analytic.ExpiresAt.Equal(now.Add(3*time.Hour)) {
+		t.Errorf("expected analytics to follow the last change, got %s until %v", analytic.LongURL, analytic.ExpiresAt)
+	}
+
+	history, err := analyticRepo.ListChanges(ctx, 500)
+	if err != nil {
+		t.Fatalf("failed to list changes: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(history))
+	}
+	if history[0].NewLongURL != "https://example.com/v2" || !history[1].NewExpiresAt.Equal(now.Add(3*time.Hour)) {
+		t.Errorf("expected changes in order, got %+v, %+v", history[0], history[1])
+	}
+
+	if other, err := analyticRepo.ListChanges(ctx, 501); err != nil || len(other) != 0 {
+		t.Errorf("expected no changes for other link, got %d, %v", len(other), err)
+	}
+}
+
+func TestPostgresURLAnalyticRepo_Revoke(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Microsecond)
+
+	_, err := analyticRepo.Create(ctx, &entity.URLAnalytic{
+		URLID:     600,
+		LongURL:   "https://example.com/revoke",
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to create analytic: %v", err)
+	}
+
+	reason := "phishing"
+	if err := analyticRepo.Revoke(ctx, 600, &reason, now); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+	// A second revocation does not overwrite the first
+	other := "duplicate"
+	if err := analyticRepo.Revoke(ctx, 600, &other, now.Add(time.Minute)); err != nil {
+		t.Fatalf("failed to revoke again: %v", err)
+	}
+
+	analytic, err := analyticRepo.GetByURLID(ctx, 600)
+	if err != nil {
+		t.Fatalf("failed to get analytic: %v", err)
+	}
+	if analytic.RevokedAt == nil || !analytic.RevokedAt.Equal(now) {
+		t.Errorf("expected revoked_at %v, got %v", now, analytic.RevokedAt)
+	}
+	if analytic.RevokeReason == nil || *analytic.RevokeReason != reason {
+		t.Errorf("expected reason %q, got %v", reason, analytic.RevokeReason)
+	}
+}
+
+func TestPostgresURLAnalyticRepo_OwnerID(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now()
+	owner := "team-a"
+
+	_, err := analyticRepo.Create(ctx, &entity.URLAnalytic{
+		URLID: 700, LongURL: "https://example.com/owned", CreatedAt: now, ExpiresAt: now.Add(time.Hour), OwnerID: &owner,
+	})
+	if err != nil {
+		t.Fatalf("failed to create analytic: %v", err)
+	}
+	err = analyticRepo.CreateBatch(ctx, []*entity.URLAnalytic{
+		{URLID: 701, LongURL: "https://example.com/batch-owned", CreatedAt: now, ExpiresAt: now.Add(time.Hour), OwnerID: &owner},
+		{URLID: 702, LongURL: "https://example.com/batch-anonymous", CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	})
+	if err != nil {
+		t.Fatalf("failed to create batch: %v", err)
+	}
+
+	for urlID, expectOwner := range map[int64]*string{700: &owner, 701: &owner, 702: nil} {
+		analytic, err := analyticRepo.GetByURLID(ctx, urlID)
+		if err != nil {
+			t.Fatalf("failed to get analytic %d: %v", urlID, err)
+		}
+		if (expectOwner == nil) != (analytic.OwnerID == nil) || (expectOwner != nil && *analytic.OwnerID != *expectOwner) {
+			t.Errorf("url %d: expected owner %v, got %v", urlID, expectOwner, analytic.OwnerID)
+		}
+	}
+}
+
+func TestPostgresURLAnalyticRepo_ListByOwner(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Microsecond)
+	owner, other := "team-a", "team-b"
+
+	seed := []*entity.URLAnalytic{
+		{URLID: 801, LongURL: "https://example.com/a", CreatedAt: now.Add(-3 * time.Hour), ExpiresAt: now.Add(time.Hour), ClickCount: 5, OwnerID: &owner},
+		{URLID: 802, LongURL: "https://docs.example.com/b", CreatedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(-time.Minute), ClickCount: 1, OwnerID: &owner},
+		{URLID: 803, LongURL: "https://user@other.org:8443/c", CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour), ClickCount: 5, OwnerID: &owner},
+		{URLID: 804, LongURL: "https://example.com/d", CreatedAt: now, ExpiresAt: now.Add(time.Hour), ClickCount: 9, OwnerID: &other},
+		{URLID: 805, LongURL: "https://example.com/e", CreatedAt: now, ExpiresAt: now.Add(time.Hour), ClickCount: 9},
+	}
+	for _, a := range seed {
+		if _, err := analyticRepo.Create(ctx, a); err != nil {
+			t.Fatalf("failed to create analytic: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		query        entity.LinkQuery
+		expectURLIDs []int64
+	}{
+		{
+			name:         "only_the_owner_links_newest_first",
+			query:        entity.LinkQuery{Sort: entity.SortCreatedAt},
+			expectURLIDs: []int64{803, 802, 801},
+		},
+		{
+			name:         "exact_domain",
+			query:        entity.LinkQuery{Sort: entity.SortCreatedAt, Domain: "example.com"},
+			expectURLIDs: []int64{801},
+		},
+		{
+			name:         "wildcard_domain_matches_subdomains",
+			query:        entity.LinkQuery{Sort: entity.SortCreatedAt, Domain: "*.example.com"},
+			expectURLIDs: []int64{802},
+		},
+		{
+			name:         "host_is_read_past_userinfo_and_port",
+			query:        entity.LinkQuery{Sort: entity.SortCreatedAt, Domain: "other.org"},
+			expectURLIDs: []int64{803},
+		},
+		{
+			name:         "active_only",
+			query:        entity.LinkQuery{Sort: entity.SortCreatedAt, Status: entity.LinkStatusActive},
+			expectURLIDs: []int64{803, 801},
+		},
+		{
+			name:         "expired_only",
+			query:        entity.LinkQuery{Sort: entity.SortCreatedAt, Status: entity.LinkStatusExpired},
+			expectURLIDs: []int64{802},
+		},
+		{
+			name:         "min_clicks_and_creation_range",
+			query:        entity.LinkQuery{Sort: entity.SortCreatedAt, MinClicks: ptr(int64(2)), CreatedAfter: ptr(now.Add(-90 * time.Minute))},
+			expectURLIDs: []int64{803},
+		},
+		{
+			name:         "click_count_ascending",
+			query:        entity.LinkQuery{Sort: entity.SortClickCount, Ascending: true},
+			expectURLIDs: []int64{802, 801, 803},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.query.OwnerID = owner
+			tt.query.Now = now
+			tt.query.Limit = 10
+			links, err := analyticRepo.ListByOwner(ctx, tt.query)
+			if err != nil {
+				t.Fatalf("failed to list: %v", err)
+			}
+			got := make([]int64, len(links))
+			for i, link := range links {
+				got[i] = link.URLID
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.expectURLIDs) {
+				t.Errorf("expected %v, got %v", tt.expectURLIDs, got)
+			}
+		})
+	}
+
+	// Keyset pagination continues past ties on the sort value
+	first, err := analyticRepo.ListByOwner(ctx, entity.LinkQuery{OwnerID: owner, Sort: entity.SortClickCount, Limit: 2, Now: now})
+	if err != nil || len(first) != 2 {
+		t.Fatalf("expected first page of 2, got %d, %v", len(first), err)
+	}
+	last := first[1]
+	rest, err := analyticRepo.ListByOwner(ctx, entity.LinkQuery{
+		OwnerID: owner, Sort: entity.SortClickCount, Limit: 2, Now: now,
+		After: &entity.LinkCursor{Count: last.ClickCount, ID: last.ID},
+	})
+	if err != nil || len(rest) != 1 || rest[0].URLID != 802 {
+		t.Errorf("expected the remaining link 802, got %v, %v", rest, err)
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestPostgresURLAnalyticRepo_MaxURLID(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now()
+
+	maxID, err := analyticRepo.MaxURLID(ctx)
+	if err != nil {
+		t.Fatalf("failed to read max URL ID: %v", err)
+	}
+	if maxID != 0 {
+		t.Errorf("expected 0 without links, got %d", maxID)
+	}
+
+	for _, urlID := range []int64{7, 42, 9} {
+		if _, err := analyticRepo.Create(ctx, &entity.URLAnalytic{
+			URLID:     urlID,
+			LongURL:   "https://example.com",
+			CreatedAt: now,
+			ExpiresAt: now.Add(time.Hour),
+		}); err != nil {
+			t.Fatalf("failed to create analytic: %v", err)
+		}
+	}
+
+	maxID, err = analyticRepo.MaxURLID(ctx)
+	if err != nil {
+		t.Fatalf("failed to read max URL ID: %v", err)
+	}
+	if maxID != 42 {
+		t.Errorf("expected 42, got %d", maxID)
+	}
+}
+
+func TestPostgresURLAnalyticRepo_ListLive(t *testing.T) {
+	testDB := config.SetupTestDB(t)
+	defer testDB.Cleanup()
+
+	analyticRepo := db.NewPostgresURLAnalyticRepo(testDB.DB)
+	ctx := context.Background()
+	now := time.Now()
+
+	for _, link := range []struct {
+		urlID     int64
+		expiresAt time.Time
+	}{
+		{1, now.Add(time.Hour)},
+		{2, now.Add(-time.Minute)},
+		{3, now.Add(time.Hour)},
+		{4, now.Add(time.Hour)},
+		{5, now.Add(time.Hour)},
+	} {
+		if _, err := analyticRepo.Create(ctx, &entity.URLAnalytic{
+			URLID:        link.urlID,
+			LongURL:      "https://example.com",
+			CreatedAt:    now,
+			ExpiresAt:    link.expiresAt,
+			PasswordHash: ptr(""),
+		}); err != nil {
+			t.Fatalf("failed to create analytic: %v", err)
+		}
+	}
+	if err := analyticRepo.Revoke(ctx, 4, nil, now); err != nil {
+		t.Fatalf("failed to revoke: %v", err)
+	}
+
+	links, err := analyticRepo.ListLive(ctx, 0, now, 2)
+	if err != nil {
+		t.Fatalf("failed to list live links: %v", err)
+	}
+	if len(links) != 2 || links[0].URLID != 1 || links[1].URLID != 3 {
+		t.Fatalf("expected links 1 and 3, got %v", links)
+	}
+	if links[0].PasswordHash == nil || *links[0].PasswordHash != "" {
+		t.Errorf("expected an empty password hash, got %v", links[0].PasswordHash)
+	}
+
+	links, err = analyticRepo.ListLive(ctx, 3, now, 2)
+	if err != nil || len(links) != 1 || links[0].URLID != 5 {
+		t.Errorf("expected link 5 after 3, got %v, %v", links, err)
+	}
+}
@@ -0,0 +1,55 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: modules/core/service/destination_policy.go
+//
+// Generated by this command:
+//
+//	mockgen -source=modules/core/service/destination_policy.go -destination=modules/core/internal/test/mocks/mock_destination_policy.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockDestinationPolicy is a mock of DestinationPolicy interface.
+type MockDestinationPolicy struct {
+	ctrl     *gomock.Controller
+	recorder *MockDestinationPolicyMockRecorder
+	isgomock struct{}
+}
+
+// MockDestinationPolicyMockRecorder is the mock recorder for MockDestinationPolicy.
+type MockDestinationPolicyMockRecorder struct {
+	mock *MockDestinationPolicy
+}
+
+// NewMockDestinationPolicy creates a new mock instance.
+func NewMockDestinationPolicy(ctrl *gomock.Controller) *MockDestinationPolicy {
+	mock := &MockDestinationPolicy{ctrl: ctrl}
+	mock.recorder = &MockDestinationPolicyMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDestinationPolicy) EXPECT() *MockDestinationPolicyMockRecorder {
+	return m.recorder
+}
+
+// Check mocks base method.
+func (m *MockDestinationPolicy) Check(ctx context.Context, longURL string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Check", ctx, longURL)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Check indicates an expected call of Check.
+func (mr *MockDestinationPolicyMockRecorder) Check(ctx, longURL any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Check", reflect.TypeOf((*MockDestinationPolicy)(nil).Check), ctx, longURL)
+}
@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: modules/core/service/idempotency.go
+//
+// Generated by this command:
+//
+//	mockgen -source=modules/core/service/idempotency.go -destination=modules/core/internal/test/mocks/mock_idempotency.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/nanda/doit/modules/core/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIdempotencyGuard is a mock of IdempotencyGuard interface.
+type MockIdempotencyGuard struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyGuardMockRecorder
+	isgomock struct{}
+}
+
+// MockIdempotencyGuardMockRecorder is the mock recorder for MockIdempotencyGuard.
+type MockIdempotencyGuardMockRecorder struct {
+	mock *MockIdempotencyGuard
+}
+
+// NewMockIdempotencyGuard creates a new mock instance.
+func NewMockIdempotencyGuard(ctrl *gomock.Controller) *MockIdempotencyGuard {
+	mock := &MockIdempotencyGuard{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyGuardMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyGuard) EXPECT() *MockIdempotencyGuardMockRecorder {
+	return m.recorder
+}
+
+// Abort mocks base method.
+func (m *MockIdempotencyGuard) Abort(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Abort", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Abort indicates an expected call of Abort.
+func (mr *MockIdempotencyGuardMockRecorder) Abort(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abort", reflect.TypeOf((*MockIdempotencyGuard)(nil).Abort), ctx, key)
+}
+
+// Begin mocks base method.
+func (m *MockIdempotencyGuard) Begin(ctx context.Context, key, fingerprint string) (*entity.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", ctx, key, fingerprint)
+	ret0, _ := ret[0].(*entity.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockIdempotencyGuardMockRecorder) Begin(ctx, key, fingerprint any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockIdempotencyGuard)(nil).Begin), ctx, key, fingerprint)
+}
+
+// Complete mocks base method.
+func (m *MockIdempotencyGuard) Complete(ctx context.Context, key, fingerprint string, statusCode int, body []byte) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", ctx, key, fingerprint, statusCode, body)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockIdempotencyGuardMockRecorder) Complete(ctx, key, fingerprint, statusCode, body any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIdempotencyGuard)(nil).Complete), ctx, key, fingerprint, statusCode, body)
+}
@@ -13,6 +13,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	entity "github.com/nanda/doit/modules/core/entity"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -41,16 +42,31 @@ func (m *MockLinkCreator) EXPECT() *MockLinkCreatorMockRecorder {
 }
 
 // Create mocks base method.
-func (m *MockLinkCreator) Create(ctx context.Context, longURL string, ttlSeconds *int64) (string, error) {
+func (m *MockLinkCreator) Create(ctx context.Context, input entity.CreateLinkInput) (*entity.Link, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, longURL, ttlSeconds)
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "Create", ctx, input)
+	ret0, _ := ret[0].(*entity.Link)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockLinkCreatorMockRecorder) Create(ctx, longURL, ttlSeconds any) *gomock.Call {
+func (mr *MockLinkCreatorMockRecorder) Create(ctx, input any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockLinkCreator)(nil).Create), ctx, longURL, ttlSeconds)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockLinkCreator)(nil).Create), ctx, input)
+}
+
+// CreateBatch mocks base method.
+func (m *MockLinkCreator) CreateBatch(ctx context.Context, inputs []entity.CreateLinkInput) ([]entity.BatchLinkResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", ctx, inputs)
+	ret0, _ := ret[0].([]entity.BatchLinkResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockLinkCreatorMockRecorder) CreateBatch(ctx, inputs any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockLinkCreator)(nil).CreateBatch), ctx, inputs)
 }
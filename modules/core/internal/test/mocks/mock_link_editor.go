@@ -0,0 +1,72 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: modules/core/service/link_editor.go
+//
+// Generated by this command:
+//
+//	mockgen -source=modules/core/service/link_editor.go -destination=modules/core/internal/test/mocks/mock_link_editor.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/nanda/doit/modules/core/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLinkEditor is a mock of LinkEditor interface.
+type MockLinkEditor struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkEditorMockRecorder
+	isgomock struct{}
+}
+
+// MockLinkEditorMockRecorder is the mock recorder for MockLinkEditor.
+type MockLinkEditorMockRecorder struct {
+	mock *MockLinkEditor
+}
+
+// NewMockLinkEditor creates a new mock instance.
+func NewMockLinkEditor(ctrl *gomock.Controller) *MockLinkEditor {
+	mock := &MockLinkEditor{ctrl: ctrl}
+	mock.recorder = &MockLinkEditorMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkEditor) EXPECT() *MockLinkEditorMockRecorder {
+	return m.recorder
+}
+
+// Edit mocks base method.
+func (m *MockLinkEditor) Edit(ctx context.Context, shortCode string, input entity.EditLinkInput) (*entity.Link, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Edit", ctx, shortCode, input)
+	ret0, _ := ret[0].(*entity.Link)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Edit indicates an expected call of Edit.
+func (mr *MockLinkEditorMockRecorder) Edit(ctx, shortCode, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Edit", reflect.TypeOf((*MockLinkEditor)(nil).Edit), ctx, shortCode, input)
+}
+
+// History mocks base method.
+func (m *MockLinkEditor) History(ctx context.Context, shortCode string) ([]*entity.LinkChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "History", ctx, shortCode)
+	ret0, _ := ret[0].([]*entity.LinkChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// History indicates an expected call of History.
+func (mr *MockLinkEditorMockRecorder) History(ctx, shortCode any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "History", reflect.TypeOf((*MockLinkEditor)(nil).History), ctx, shortCode)
+}
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: modules/core/service/link_lister.go
+//
+// Generated by this command:
+//
+//	mockgen -source=modules/core/service/link_lister.go -destination=modules/core/internal/test/mocks/mock_link_lister.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/nanda/doit/modules/core/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLinkLister is a mock of LinkLister interface.
+type MockLinkLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkListerMockRecorder
+	isgomock struct{}
+}
+
+// MockLinkListerMockRecorder is the mock recorder for MockLinkLister.
+type MockLinkListerMockRecorder struct {
+	mock *MockLinkLister
+}
+
+// NewMockLinkLister creates a new mock instance.
+func NewMockLinkLister(ctrl *gomock.Controller) *MockLinkLister {
+	mock := &MockLinkLister{ctrl: ctrl}
+	mock.recorder = &MockLinkListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkLister) EXPECT() *MockLinkListerMockRecorder {
+	return m.recorder
+}
+
+// List mocks base method.
+func (m *MockLinkLister) List(ctx context.Context, input entity.ListLinksInput) (*entity.LinkPage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", ctx, input)
+	ret0, _ := ret[0].(*entity.LinkPage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockLinkListerMockRecorder) List(ctx, input any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockLinkLister)(nil).List), ctx, input)
+}
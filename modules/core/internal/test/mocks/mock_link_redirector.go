@@ -41,16 +41,16 @@ func (m *MockLinkRedirector) EXPECT() *MockLinkRedirectorMockRecorder {
 }
 
 // Redirect mocks base method.
-func (m *MockLinkRedirector) Redirect(ctx context.Context, shortCode string) (string, error) {
+func (m *MockLinkRedirector) Redirect(ctx context.Context, shortCode, password string) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Redirect", ctx, shortCode)
+	ret := m.ctrl.Call(m, "Redirect", ctx, shortCode, password)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Redirect indicates an expected call of Redirect.
-func (mr *MockLinkRedirectorMockRecorder) Redirect(ctx, shortCode any) *gomock.Call {
+func (mr *MockLinkRedirectorMockRecorder) Redirect(ctx, shortCode, password any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redirect", reflect.TypeOf((*MockLinkRedirector)(nil).Redirect), ctx, shortCode)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Redirect", reflect.TypeOf((*MockLinkRedirector)(nil).Redirect), ctx, shortCode, password)
 }
@@ -0,0 +1,57 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: modules/core/service/link_revoker.go
+//
+// Generated by this command:
+//
+//	mockgen -source=modules/core/service/link_revoker.go -destination=modules/core/internal/test/mocks/mock_link_revoker.go -package=mocks
+//
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	context "context"
+	reflect "reflect"
+
+	entity "github.com/nanda/doit/modules/core/entity"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLinkRevoker is a mock of LinkRevoker interface.
+type MockLinkRevoker struct {
+	ctrl     *gomock.Controller
+	recorder *MockLinkRevokerMockRecorder
+	isgomock struct{}
+}
+
+// MockLinkRevokerMockRecorder is the mock recorder for MockLinkRevoker.
+type MockLinkRevokerMockRecorder struct {
+	mock *MockLinkRevoker
+}
+
+// NewMockLinkRevoker creates a new mock instance.
+func NewMockLinkRevoker(ctrl *gomock.Controller) *MockLinkRevoker {
+	mock := &MockLinkRevoker{ctrl: ctrl}
+	mock.recorder = &MockLinkRevokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLinkRevoker) EXPECT() *MockLinkRevokerMockRecorder {
+	return m.recorder
+}
+
+// Revoke mocks base method.
+func (m *MockLinkRevoker) Revoke(ctx context.Context, shortCode string, reason *string) (*entity.URLAnalytic, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, shortCode, reason)
+	ret0, _ := ret[0].(*entity.URLAnalytic)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockLinkRevokerMockRecorder) Revoke(ctx, shortCode, reason any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockLinkRevoker)(nil).Revoke), ctx, shortCode, reason)
+}
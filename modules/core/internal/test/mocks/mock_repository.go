@@ -42,19 +42,114 @@ func (m *MockURLCacheRepo) EXPECT() *MockURLCacheRepoMockRecorder {
 	return m.recorder
 }
 
+// AdvanceSequence mocks base method.
+func (m *MockURLCacheRepo) AdvanceSequence(ctx context.Context, floor int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceSequence", ctx, floor)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdvanceSequence indicates an expected call of AdvanceSequence.
+func (mr *MockURLCacheRepoMockRecorder) AdvanceSequence(ctx, floor any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceSequence", reflect.TypeOf((*MockURLCacheRepo)(nil).AdvanceSequence), ctx, floor)
+}
+
+// Confirm mocks base method.
+func (m *MockURLCacheRepo) Confirm(ctx context.Context, ids ...int64) error {
+	m.ctrl.T.Helper()
+	varargs := []any{ctx}
+	for _, a := range ids {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Confirm", varargs...)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Confirm indicates an expected call of Confirm.
+func (mr *MockURLCacheRepoMockRecorder) Confirm(ctx any, ids ...any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]any{ctx}, ids...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Confirm", reflect.TypeOf((*MockURLCacheRepo)(nil).Confirm), varargs...)
+}
+
+// ConsumeClick mocks base method.
+func (m *MockURLCacheRepo) ConsumeClick(ctx context.Context, id int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeClick", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeClick indicates an expected call of ConsumeClick.
+func (mr *MockURLCacheRepoMockRecorder) ConsumeClick(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeClick", reflect.TypeOf((*MockURLCacheRepo)(nil).ConsumeClick), ctx, id)
+}
+
 // Create mocks base method.
-func (m *MockURLCacheRepo) Create(ctx context.Context, longURL string, ttl time.Duration) (int64, error) {
+func (m *MockURLCacheRepo) Create(ctx context.Context, link *entity.URL) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Create", ctx, longURL, ttl)
+	ret := m.ctrl.Call(m, "Create", ctx, link)
 	ret0, _ := ret[0].(int64)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Create indicates an expected call of Create.
-func (mr *MockURLCacheRepoMockRecorder) Create(ctx, longURL, ttl any) *gomock.Call {
+func (mr *MockURLCacheRepoMockRecorder) Create(ctx, link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockURLCacheRepo)(nil).Create), ctx, link)
+}
+
+// CreateBatch mocks base method.
+func (m *MockURLCacheRepo) CreateBatch(ctx context.Context, urls []*entity.URL) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", ctx, urls)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockURLCacheRepoMockRecorder) CreateBatch(ctx, urls any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockURLCacheRepo)(nil).Create), ctx, longURL, ttl)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockURLCacheRepo)(nil).CreateBatch), ctx, urls)
+}
+
+// CreateDeduped mocks base method.
+func (m *MockURLCacheRepo) CreateDeduped(ctx context.Context, link *entity.URL) (int64, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDeduped", ctx, link)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateDeduped indicates an expected call of CreateDeduped.
+func (mr *MockURLCacheRepoMockRecorder) CreateDeduped(ctx, link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDeduped", reflect.TypeOf((*MockURLCacheRepo)(nil).CreateDeduped), ctx, link)
+}
+
+// CreateWithAlias mocks base method.
+func (m *MockURLCacheRepo) CreateWithAlias(ctx context.Context, alias string, link *entity.URL) (int64, bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateWithAlias", ctx, alias, link)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(bool)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CreateWithAlias indicates an expected call of CreateWithAlias.
+func (mr *MockURLCacheRepoMockRecorder) CreateWithAlias(ctx, alias, link any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateWithAlias", reflect.TypeOf((*MockURLCacheRepo)(nil).CreateWithAlias), ctx, alias, link)
 }
 
 // Delete mocks base method.
@@ -71,11 +166,26 @@ func (mr *MockURLCacheRepoMockRecorder) Delete(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockURLCacheRepo)(nil).Delete), ctx, id)
 }
 
+// Discard mocks base method.
+func (m *MockURLCacheRepo) Discard(ctx context.Context, id int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Discard", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Discard indicates an expected call of Discard.
+func (mr *MockURLCacheRepoMockRecorder) Discard(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Discard", reflect.TypeOf((*MockURLCacheRepo)(nil).Discard), ctx, id)
+}
+
 // Get mocks base method.
-func (m *MockURLCacheRepo) Get(ctx context.Context, id int64) (string, error) {
+func (m *MockURLCacheRepo) Get(ctx context.Context, id int64) (*entity.URL, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Get", ctx, id)
-	ret0, _ := ret[0].(string)
+	ret0, _ := ret[0].(*entity.URL)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
@@ -86,6 +196,80 @@ func (mr *MockURLCacheRepoMockRecorder) Get(ctx, id any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockURLCacheRepo)(nil).Get), ctx, id)
 }
 
+// IsRevoked mocks base method.
+func (m *MockURLCacheRepo) IsRevoked(ctx context.Context, id int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IsRevoked", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IsRevoked indicates an expected call of IsRevoked.
+func (mr *MockURLCacheRepoMockRecorder) IsRevoked(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IsRevoked", reflect.TypeOf((*MockURLCacheRepo)(nil).IsRevoked), ctx, id)
+}
+
+// ListPending mocks base method.
+func (m *MockURLCacheRepo) ListPending(ctx context.Context, before time.Time, limit int64) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListPending", ctx, before, limit)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListPending indicates an expected call of ListPending.
+func (mr *MockURLCacheRepoMockRecorder) ListPending(ctx, before, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListPending", reflect.TypeOf((*MockURLCacheRepo)(nil).ListPending), ctx, before, limit)
+}
+
+// MarkRevoked mocks base method.
+func (m *MockURLCacheRepo) MarkRevoked(ctx context.Context, id int64, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkRevoked", ctx, id, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkRevoked indicates an expected call of MarkRevoked.
+func (mr *MockURLCacheRepoMockRecorder) MarkRevoked(ctx, id, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkRevoked", reflect.TypeOf((*MockURLCacheRepo)(nil).MarkRevoked), ctx, id, ttl)
+}
+
+// ResolveAlias mocks base method.
+func (m *MockURLCacheRepo) ResolveAlias(ctx context.Context, alias string) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveAlias", ctx, alias)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveAlias indicates an expected call of ResolveAlias.
+func (mr *MockURLCacheRepoMockRecorder) ResolveAlias(ctx, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveAlias", reflect.TypeOf((*MockURLCacheRepo)(nil).ResolveAlias), ctx, alias)
+}
+
+// Restore mocks base method.
+func (m *MockURLCacheRepo) Restore(ctx context.Context, links []*entity.URLAnalytic, now time.Time) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Restore", ctx, links, now)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Restore indicates an expected call of Restore.
+func (mr *MockURLCacheRepoMockRecorder) Restore(ctx, links, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Restore", reflect.TypeOf((*MockURLCacheRepo)(nil).Restore), ctx, links, now)
+}
+
 // Set mocks base method.
 func (m *MockURLCacheRepo) Set(ctx context.Context, id int64, longURL string, ttl time.Duration) error {
 	m.ctrl.T.Helper()
@@ -100,6 +284,20 @@ func (mr *MockURLCacheRepoMockRecorder) Set(ctx, id, longURL, ttl any) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Set", reflect.TypeOf((*MockURLCacheRepo)(nil).Set), ctx, id, longURL, ttl)
 }
 
+// Update mocks base method.
+func (m *MockURLCacheRepo) Update(ctx context.Context, link *entity.URL, alias *string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", ctx, link, alias)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockURLCacheRepoMockRecorder) Update(ctx, link, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockURLCacheRepo)(nil).Update), ctx, link, alias)
+}
+
 // MockURLAnalyticRepo is a mock of URLAnalyticRepo interface.
 type MockURLAnalyticRepo struct {
 	ctrl     *gomock.Controller
@@ -124,6 +322,20 @@ func (m *MockURLAnalyticRepo) EXPECT() *MockURLAnalyticRepoMockRecorder {
 	return m.recorder
 }
 
+// ApplyChange mocks base method.
+func (m *MockURLAnalyticRepo) ApplyChange(ctx context.Context, change *entity.LinkChange) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ApplyChange", ctx, change)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ApplyChange indicates an expected call of ApplyChange.
+func (mr *MockURLAnalyticRepoMockRecorder) ApplyChange(ctx, change any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ApplyChange", reflect.TypeOf((*MockURLAnalyticRepo)(nil).ApplyChange), ctx, change)
+}
+
 // Create mocks base method.
 func (m *MockURLAnalyticRepo) Create(ctx context.Context, analytic *entity.URLAnalytic) (int64, error) {
 	m.ctrl.T.Helper()
@@ -139,6 +351,35 @@ func (mr *MockURLAnalyticRepoMockRecorder) Create(ctx, analytic any) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockURLAnalyticRepo)(nil).Create), ctx, analytic)
 }
 
+// CreateBatch mocks base method.
+func (m *MockURLAnalyticRepo) CreateBatch(ctx context.Context, analytics []*entity.URLAnalytic) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBatch", ctx, analytics)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateBatch indicates an expected call of CreateBatch.
+func (mr *MockURLAnalyticRepoMockRecorder) CreateBatch(ctx, analytics any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBatch", reflect.TypeOf((*MockURLAnalyticRepo)(nil).CreateBatch), ctx, analytics)
+}
+
+// GetByAlias mocks base method.
+func (m *MockURLAnalyticRepo) GetByAlias(ctx context.Context, alias string) (*entity.URLAnalytic, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByAlias", ctx, alias)
+	ret0, _ := ret[0].(*entity.URLAnalytic)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByAlias indicates an expected call of GetByAlias.
+func (mr *MockURLAnalyticRepoMockRecorder) GetByAlias(ctx, alias any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByAlias", reflect.TypeOf((*MockURLAnalyticRepo)(nil).GetByAlias), ctx, alias)
+}
+
 // GetByURLID mocks base method.
 func (m *MockURLAnalyticRepo) GetByURLID(ctx context.Context, urlID int64) (*entity.URLAnalytic, error) {
 	m.ctrl.T.Helper()
@@ -154,6 +395,80 @@ func (mr *MockURLAnalyticRepoMockRecorder) GetByURLID(ctx, urlID any) *gomock.Ca
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByURLID", reflect.TypeOf((*MockURLAnalyticRepo)(nil).GetByURLID), ctx, urlID)
 }
 
+// ListByOwner mocks base method.
+func (m *MockURLAnalyticRepo) ListByOwner(ctx context.Context, query entity.LinkQuery) ([]*entity.URLAnalytic, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByOwner", ctx, query)
+	ret0, _ := ret[0].([]*entity.URLAnalytic)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByOwner indicates an expected call of ListByOwner.
+func (mr *MockURLAnalyticRepoMockRecorder) ListByOwner(ctx, query any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByOwner", reflect.TypeOf((*MockURLAnalyticRepo)(nil).ListByOwner), ctx, query)
+}
+
+// ListChanges mocks base method.
+func (m *MockURLAnalyticRepo) ListChanges(ctx context.Context, urlID int64) ([]*entity.LinkChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListChanges", ctx, urlID)
+	ret0, _ := ret[0].([]*entity.LinkChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListChanges indicates an expected call of ListChanges.
+func (mr *MockURLAnalyticRepoMockRecorder) ListChanges(ctx, urlID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListChanges", reflect.TypeOf((*MockURLAnalyticRepo)(nil).ListChanges), ctx, urlID)
+}
+
+// ListLive mocks base method.
+func (m *MockURLAnalyticRepo) ListLive(ctx context.Context, afterURLID int64, now time.Time, limit int) ([]*entity.URLAnalytic, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListLive", ctx, afterURLID, now, limit)
+	ret0, _ := ret[0].([]*entity.URLAnalytic)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListLive indicates an expected call of ListLive.
+func (mr *MockURLAnalyticRepoMockRecorder) ListLive(ctx, afterURLID, now, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListLive", reflect.TypeOf((*MockURLAnalyticRepo)(nil).ListLive), ctx, afterURLID, now, limit)
+}
+
+// MaxURLID mocks base method.
+func (m *MockURLAnalyticRepo) MaxURLID(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxURLID", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MaxURLID indicates an expected call of MaxURLID.
+func (mr *MockURLAnalyticRepoMockRecorder) MaxURLID(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxURLID", reflect.TypeOf((*MockURLAnalyticRepo)(nil).MaxURLID), ctx)
+}
+
+// Revoke mocks base method.
+func (m *MockURLAnalyticRepo) Revoke(ctx context.Context, urlID int64, reason *string, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, urlID, reason, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockURLAnalyticRepoMockRecorder) Revoke(ctx, urlID, reason, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockURLAnalyticRepo)(nil).Revoke), ctx, urlID, reason, now)
+}
+
 // UpdateStat mocks base method.
 func (m *MockURLAnalyticRepo) UpdateStat(ctx context.Context, urlID int64, now time.Time) error {
 	m.ctrl.T.Helper()
@@ -167,3 +482,207 @@ func (mr *MockURLAnalyticRepoMockRecorder) UpdateStat(ctx, urlID, now any) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateStat", reflect.TypeOf((*MockURLAnalyticRepo)(nil).UpdateStat), ctx, urlID, now)
 }
+
+// MockAPIKeyRepo is a mock of APIKeyRepo interface.
+type MockAPIKeyRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockAPIKeyRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockAPIKeyRepoMockRecorder is the mock recorder for MockAPIKeyRepo.
+type MockAPIKeyRepoMockRecorder struct {
+	mock *MockAPIKeyRepo
+}
+
+// NewMockAPIKeyRepo creates a new mock instance.
+func NewMockAPIKeyRepo(ctrl *gomock.Controller) *MockAPIKeyRepo {
+	mock := &MockAPIKeyRepo{ctrl: ctrl}
+	mock.recorder = &MockAPIKeyRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAPIKeyRepo) EXPECT() *MockAPIKeyRepoMockRecorder {
+	return m.recorder
+}
+
+// Create mocks base method.
+func (m *MockAPIKeyRepo) Create(ctx context.Context, key *entity.APIKey) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", ctx, key)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockAPIKeyRepoMockRecorder) Create(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockAPIKeyRepo)(nil).Create), ctx, key)
+}
+
+// GetByHash mocks base method.
+func (m *MockAPIKeyRepo) GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByHash", ctx, keyHash)
+	ret0, _ := ret[0].(*entity.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByHash indicates an expected call of GetByHash.
+func (mr *MockAPIKeyRepoMockRecorder) GetByHash(ctx, keyHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByHash", reflect.TypeOf((*MockAPIKeyRepo)(nil).GetByHash), ctx, keyHash)
+}
+
+// Revoke mocks base method.
+func (m *MockAPIKeyRepo) Revoke(ctx context.Context, id int64, now time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", ctx, id, now)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockAPIKeyRepoMockRecorder) Revoke(ctx, id, now any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockAPIKeyRepo)(nil).Revoke), ctx, id, now)
+}
+
+// MockUnlockAttemptRepo is a mock of UnlockAttemptRepo interface.
+type MockUnlockAttemptRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnlockAttemptRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockUnlockAttemptRepoMockRecorder is the mock recorder for MockUnlockAttemptRepo.
+type MockUnlockAttemptRepoMockRecorder struct {
+	mock *MockUnlockAttemptRepo
+}
+
+// NewMockUnlockAttemptRepo creates a new mock instance.
+func NewMockUnlockAttemptRepo(ctrl *gomock.Controller) *MockUnlockAttemptRepo {
+	mock := &MockUnlockAttemptRepo{ctrl: ctrl}
+	mock.recorder = &MockUnlockAttemptRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnlockAttemptRepo) EXPECT() *MockUnlockAttemptRepoMockRecorder {
+	return m.recorder
+}
+
+// Release mocks base method.
+func (m *MockUnlockAttemptRepo) Release(ctx context.Context, id int64) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockUnlockAttemptRepoMockRecorder) Release(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockUnlockAttemptRepo)(nil).Release), ctx, id)
+}
+
+// Reserve mocks base method.
+func (m *MockUnlockAttemptRepo) Reserve(ctx context.Context, id, limit int64, window time.Duration) (bool, time.Duration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, id, limit, window)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(time.Duration)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockUnlockAttemptRepoMockRecorder) Reserve(ctx, id, limit, window any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockUnlockAttemptRepo)(nil).Reserve), ctx, id, limit, window)
+}
+
+// MockIdempotencyRepo is a mock of IdempotencyRepo interface.
+type MockIdempotencyRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdempotencyRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockIdempotencyRepoMockRecorder is the mock recorder for MockIdempotencyRepo.
+type MockIdempotencyRepoMockRecorder struct {
+	mock *MockIdempotencyRepo
+}
+
+// NewMockIdempotencyRepo creates a new mock instance.
+func NewMockIdempotencyRepo(ctrl *gomock.Controller) *MockIdempotencyRepo {
+	mock := &MockIdempotencyRepo{ctrl: ctrl}
+	mock.recorder = &MockIdempotencyRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdempotencyRepo) EXPECT() *MockIdempotencyRepoMockRecorder {
+	return m.recorder
+}
+
+// Complete mocks base method.
+func (m *MockIdempotencyRepo) Complete(ctx context.Context, key string, record *entity.IdempotencyRecord, ttl time.Duration) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", ctx, key, record, ttl)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockIdempotencyRepoMockRecorder) Complete(ctx, key, record, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockIdempotencyRepo)(nil).Complete), ctx, key, record, ttl)
+}
+
+// Get mocks base method.
+func (m *MockIdempotencyRepo) Get(ctx context.Context, key string) (*entity.IdempotencyRecord, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", ctx, key)
+	ret0, _ := ret[0].(*entity.IdempotencyRecord)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockIdempotencyRepoMockRecorder) Get(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockIdempotencyRepo)(nil).Get), ctx, key)
+}
+
+// Release mocks base method.
+func (m *MockIdempotencyRepo) Release(ctx context.Context, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Release", ctx, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Release indicates an expected call of Release.
+func (mr *MockIdempotencyRepoMockRecorder) Release(ctx, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Release", reflect.TypeOf((*MockIdempotencyRepo)(nil).Release), ctx, key)
+}
+
+// Reserve mocks base method.
+func (m *MockIdempotencyRepo) Reserve(ctx context.Context, key string, record *entity.IdempotencyRecord, ttl time.Duration) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Reserve", ctx, key, record, ttl)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Reserve indicates an expected call of Reserve.
+func (mr *MockIdempotencyRepoMockRecorder) Reserve(ctx, key, record, ttl any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Reserve", reflect.TypeOf((*MockIdempotencyRepo)(nil).Reserve), ctx, key, record, ttl)
+}
@@ -0,0 +1,31 @@
+package lib
+
+import "strings"
+
+const (
+	MinAliasLen = 3
+	MaxAliasLen = 64
+)
+
+// aliasAlphabet lists the characters allowed in a vanity alias. It follows the
+// same readability constraint as generated codes: no '0', 'O', 'I', 'l' or '1'.
+const aliasAlphabet = "abcdefghijkmnopqrstuvwxyz23456789-"
+
+// IsValidAlias reports whether s can be used as a vanity alias.
+// An alias must contain at least one character outside the hex alphabet so it
+// can never collide with a code produced by HexEncode. Tagged codes, such as
+// base-57 codes, start with an uppercase letter, which aliases never contain.
+func IsValidAlias(s string) bool {
+	if len(s) < MinAliasLen || len(s) > MaxAliasLen {
+		return false
+	}
+	if strings.HasPrefix(s, "-") || strings.HasSuffix(s, "-") {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune(aliasAlphabet, c) {
+			return false
+		}
+	}
+	return !IsHexCode(s)
+}
@@ -0,0 +1,77 @@
+package lib
+
+import "strings"
+
+// DefaultBlockedWords is the curated blocklist used unless configured otherwise.
+// Words that commonly occur inside harmless ones ("ass" in "class", "cock" in
+// "peacock") are left out, since the list also vets vanity aliases.
+var DefaultBlockedWords = []string{
+	"bitch", "boob", "cunt", "dildo", "fag", "fuck", "hitler", "jizz", "kkk", "nazi",
+	"nigg", "penis", "piss", "porn", "pussy", "shit", "slut", "twat", "whore",
+}
+
+// DefaultCodeOnlyWords are refused in generated codes on top of
+// DefaultBlockedWords. They can be spelled with hex digits read as letters
+// ("5ha6", "7ea8a6"), which almost none of the curated words can, but hide inside
+// harmless words ("shatter", "class"), so aliases may still contain them.
+var DefaultCodeOnlyWords = []string{
+	"ass", "btch", "feces", "gash", "scat", "shag", "shat", "teabag",
+}
+
+// DefaultCodeWords returns the default words to refuse in generated codes over
+// alphabet: the curated and code-only words that its characters can spell.
+func DefaultCodeWords(alphabet string) []string {
+	return SpellableWords(append(append([]string(nil), DefaultBlockedWords...), DefaultCodeOnlyWords...), alphabet)
+}
+
+// SpellableWords returns the words that a string over alphabet can contain once
+// normalized like Blocks does. Checking the others against codes is wasted work.
+func SpellableWords(words []string, alphabet string) []string {
+	letters := normalizeBlocked(alphabet)
+	var spellable []string
+	for _, word := range words {
+		if strings.Trim(normalizeBlocked(word), letters) == "" {
+			spellable = append(spellable, word)
+		}
+	}
+	return spellable
+}
+
+// lookalikes reads digits as the letters they are used to spell.
+var lookalikes = strings.NewReplacer(
+	"0", "o", "1", "i", "3", "e", "4", "a", "5", "s", "6", "g", "7", "t", "8", "b", "9", "g",
+	"-", "",
+)
+
+// Blocklist refuses generated codes and aliases that spell unwanted words.
+type Blocklist struct {
+	words []string
+}
+
+// NewBlocklist creates a blocklist of words, which are matched as substrings.
+// Words are normalized like the strings they are checked against; empty words are dropped.
+func NewBlocklist(words []string) *Blocklist {
+	b := &Blocklist{}
+	for _, word := range words {
+		if word = normalizeBlocked(strings.TrimSpace(word)); word != "" {
+			b.words = append(b.words, word)
+		}
+	}
+	return b
+}
+
+// Blocks reports whether s contains a blocked word, ignoring case and dashes and
+// reading digits as the letters they resemble, so "F4G" and "f-a-g" match "fag".
+func (b *Blocklist) Blocks(s string) bool {
+	s = normalizeBlocked(s)
+	for _, word := range b.words {
+		if strings.Contains(s, word) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeBlocked(s string) string {
+	return lookalikes.Replace(strings.ToLower(s))
+}
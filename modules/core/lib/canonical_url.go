@@ -0,0 +1,174 @@
+package lib
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+var ErrInvalidURL = errors.New("invalid URL")
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// CanonicalizeURL returns the canonical form of an absolute URL so that equivalent
+// spellings of the same destination compare equal. It lowercases the scheme and host,
+// converts internationalized host names to punycode, drops the scheme's default port,
+// resolves "." and ".." path segments and normalizes percent-encoding (RFC 3986 §6.2.2).
+// A bare "/" path is dropped, so "https://example.com/" becomes "https://example.com".
+func CanonicalizeURL(rawURL string) (string, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+
+	scheme := strings.ToLower(parsed.Scheme)
+	host, err := canonicalHost(scheme, parsed.Hostname(), parsed.Port())
+	if err != nil {
+		return "", err
+	}
+
+	// An empty path and "/" are equivalent for http(s); keep the shorter form
+	path := removeDotSegments(normalizePercentEncoding(parsed.EscapedPath()))
+	if path == "/" {
+		path = ""
+	}
+
+	var b strings.Builder
+	b.WriteString(scheme)
+	b.WriteString("://")
+	if parsed.User != nil {
+		b.WriteString(parsed.User.String())
+		b.WriteByte('@')
+	}
+	b.WriteString(host)
+	b.WriteString(path)
+	if parsed.ForceQuery || parsed.RawQuery != "" {
+		b.WriteByte('?')
+		b.WriteString(normalizePercentEncoding(parsed.RawQuery))
+	}
+	if parsed.Fragment != "" {
+		b.WriteByte('#')
+		b.WriteString(normalizePercentEncoding(parsed.EscapedFragment()))
+	}
+
+	return b.String(), nil
+}
+
+// canonicalHost lowercases the host, converts IDNs to punycode and drops the default port.
+func canonicalHost(scheme, hostname, port string) (string, error) {
+	if hostname == "" {
+		return "", ErrInvalidURL
+	}
+
+	host := strings.ToLower(hostname)
+	if !isASCII(host) {
+		ascii, err := idna.Lookup.ToASCII(host)
+		if err != nil {
+			return "", ErrInvalidURL
+		}
+		host = ascii
+	}
+
+	if port == defaultPorts[scheme] {
+		port = ""
+	}
+
+	if port != "" {
+		return net.JoinHostPort(host, port), nil
+	}
+	if strings.Contains(host, ":") {
+		// IPv6 literal
+		return "[" + host + "]", nil
+	}
+	return host, nil
+}
+
+// normalizePercentEncoding decodes percent-encoded unreserved characters and
+// uppercases the hex digits of every remaining escape.
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHexDigit(s[i+1]) || !isHexDigit(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		decoded := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(decoded) {
+			b.WriteByte(decoded)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// removeDotSegments resolves "." and ".." segments of an absolute path (RFC 3986 §5.2.4).
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	segments := strings.Split(path, "/")
+	out := make([]string, 0, len(segments))
+	for _, seg := range segments {
+		switch seg {
+		case ".":
+		case "..":
+			// Never pop the empty segment that represents the leading slash
+			if len(out) > 1 {
+				out = out[:len(out)-1]
+			}
+		default:
+			out = append(out, seg)
+		}
+	}
+
+	// A trailing "." or ".." refers to a directory, so keep the trailing slash
+	if last := segments[len(segments)-1]; last == "." || last == ".." {
+		out = append(out, "")
+	}
+	return strings.Join(out, "/")
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			return false
+		}
+	}
+	return true
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
+
+const unreservedChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-._~"
+
+func isUnreserved(c byte) bool {
+	return strings.IndexByte(unreservedChars, c) >= 0
+}
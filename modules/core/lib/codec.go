@@ -0,0 +1,216 @@
+package lib
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+var ErrInvalidCode = errors.New("invalid short code")
+
+// Codec turns link IDs into short codes and back.
+type Codec interface {
+	// Encode returns the short code of a non-negative ID.
+	Encode(id int64) string
+	// Decode returns the ID of a code produced by Encode, or ErrInvalidCode.
+	Decode(code string) (int64, error)
+	// Matches reports whether code has this codec's format. Formats of different
+	// codecs never overlap, so at most one codec matches a code.
+	Matches(code string) bool
+	// Alphabet returns every character the codec's codes can contain.
+	Alphabet() string
+}
+
+// HexCodec is the original format of ADR-001: hex with '0' and '1' replaced by
+// 'g' and 'h'. Its codes are untagged, so every code printed before codecs
+// became pluggable still decodes.
+type HexCodec struct{}
+
+func (HexCodec) Encode(id int64) string {
+	return HexEncode(id)
+}
+
+func (HexCodec) Decode(code string) (int64, error) {
+	if !IsHexCode(code) {
+		return 0, ErrInvalidCode
+	}
+	id, err := HexDecode(code)
+	if err != nil {
+		return 0, ErrInvalidCode
+	}
+	return id, nil
+}
+
+func (HexCodec) Matches(code string) bool {
+	return IsHexCode(code)
+}
+
+func (HexCodec) Alphabet() string {
+	return hexAlphabet
+}
+
+// Base57Alphabet is every digit and letter except the confusable '0', 'O', 'I',
+// 'l' and '1'.
+const Base57Alphabet = "23456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// codeTags are the characters that can lead a tagged code. They are uppercase, so
+// no alias starts with one, and exclude 'A'-'H', 'I', 'L' and 'O' so an uppercased
+// or mistyped hex code never reads as a tagged code.
+const codeTags = "JKMNPQRSTUVWXYZ"
+
+// Base57Tag leads the codes of the base-57 codec.
+const Base57Tag = 'J'
+
+// BaseNCodec writes IDs in positional notation over an alphabet, after a tag
+// character that tells its codes apart from hex codes and aliases.
+type BaseNCodec struct {
+	alphabet string
+	tag      byte
+}
+
+// NewBaseNCodec creates a codec over alphabet, an ASCII string of at least two
+// distinct characters, whose codes start with tag, one of the reserved code tags.
+func NewBaseNCodec(alphabet string, tag byte) (*BaseNCodec, error) {
+	if len(alphabet) < 2 {
+		return nil, errors.New("codec alphabet must have at least two characters")
+	}
+	for i := 0; i < len(alphabet); i++ {
+		if alphabet[i] >= 0x80 || strings.IndexByte(alphabet[i+1:], alphabet[i]) >= 0 {
+			return nil, errors.New("codec alphabet must be distinct ASCII characters")
+		}
+	}
+	if strings.IndexByte(codeTags, tag) < 0 {
+		return nil, errors.New("codec tag must be one of " + codeTags)
+	}
+	return &BaseNCodec{alphabet: alphabet, tag: tag}, nil
+}
+
+// NewBase57Codec creates the base-57 codec. Its codes are about a third shorter
+// than hex codes, plus the tag.
+func NewBase57Codec() *BaseNCodec {
+	// The alphabet and tag are known to be valid
+	codec, _ := NewBaseNCodec(Base57Alphabet, Base57Tag)
+	return codec
+}
+
+func (c *BaseNCodec) Encode(id int64) string {
+	return string(c.tag) + c.digits(uint64(id), 0)
+}
+
+func (c *BaseNCodec) Decode(code string) (int64, error) {
+	if !c.Matches(code) {
+		return 0, ErrInvalidCode
+	}
+	digits := code[1:]
+	// A leading zero digit would give one ID two codes
+	if len(digits) > 1 && digits[0] == c.alphabet[0] {
+		return 0, ErrInvalidCode
+	}
+
+	n, ok := c.value(digits)
+	if !ok || n > math.MaxInt64 {
+		return 0, ErrInvalidCode
+	}
+	return int64(n), nil
+}
+
+// digits writes n in the codec's base, left-padded with its zero digit to width.
+func (c *BaseNCodec) digits(n uint64, width int) string {
+	base := uint64(len(c.alphabet))
+
+	var digits [64]byte
+	i := len(digits)
+	for {
+		i--
+		digits[i] = c.alphabet[n%base]
+		n /= base
+		if n == 0 {
+			break
+		}
+	}
+	for len(digits)-i < width {
+		i--
+		digits[i] = c.alphabet[0]
+	}
+	return string(digits[i:])
+}
+
+// value parses digits of the codec's alphabet, reporting false if they overflow 64 bits.
+func (c *BaseNCodec) value(digits string) (uint64, bool) {
+	base := uint64(len(c.alphabet))
+	var n uint64
+	for i := 0; i < len(digits); i++ {
+		digit := uint64(strings.IndexByte(c.alphabet, digits[i]))
+		if n > (math.MaxUint64-digit)/base {
+			return 0, false
+		}
+		n = n*base + digit
+	}
+	return n, true
+}
+
+func (c *BaseNCodec) Alphabet() string {
+	return string(c.tag) + c.alphabet
+}
+
+func (c *BaseNCodec) Matches(code string) bool {
+	if len(code) < 2 || code[0] != c.tag {
+		return false
+	}
+	for i := 1; i < len(code); i++ {
+		if strings.IndexByte(c.alphabet, code[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// CodeSet encodes new codes with one codec and decodes the codes of every codec
+// it knows, so switching codecs keeps older links working.
+type CodeSet struct {
+	encoder Codec
+	codecs  []Codec
+}
+
+// NewCodeSet creates a set that encodes with encoder and decodes codes of it and
+// of the decoders, which may include encoder again.
+func NewCodeSet(encoder Codec, decoders ...Codec) *CodeSet {
+	return &CodeSet{encoder: encoder, codecs: append([]Codec{encoder}, decoders...)}
+}
+
+// DefaultCodeSet encodes hex codes and also decodes base-57 codes.
+func DefaultCodeSet() *CodeSet {
+	return NewCodeSet(HexCodec{}, NewBase57Codec())
+}
+
+func (s *CodeSet) Encode(id int64) string {
+	return s.encoder.Encode(id)
+}
+
+// Alphabet returns the characters of the codes the set encodes.
+func (s *CodeSet) Alphabet() string {
+	return s.encoder.Alphabet()
+}
+
+// Matches reports whether code has the format of one of the set's codecs.
+func (s *CodeSet) Matches(code string) bool {
+	for _, codec := range s.codecs {
+		if codec.Matches(code) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode returns the ID of a code of any known format. ok is false when code is
+// not in any of them, so may be an alias instead; err is ErrInvalidCode or
+// ErrBadChecksum when it has a known format but does not decode.
+func (s *CodeSet) Decode(code string) (id int64, ok bool, err error) {
+	for _, codec := range s.codecs {
+		if codec.Matches(code) {
+			id, err := codec.Decode(code)
+			return id, true, err
+		}
+	}
+	return 0, false, nil
+}
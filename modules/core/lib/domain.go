@@ -0,0 +1,66 @@
+package lib
+
+import (
+	"net"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeDomain lowercases a host name, strips a trailing dot and converts
+// internationalized names to punycode so it can be compared against domain lists.
+func NormalizeDomain(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+	if host == "" {
+		return "", ErrInvalidURL
+	}
+	if isASCII(host) {
+		return host, nil
+	}
+	return idna.Lookup.ToASCII(host)
+}
+
+// MatchDomain reports whether a normalized host matches a normalized pattern.
+// "example.com" matches only itself; "*.example.com" matches every subdomain
+// of example.com but not example.com itself.
+func MatchDomain(pattern, host string) bool {
+	if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+		return strings.HasSuffix(host, "."+suffix)
+	}
+	return pattern == host
+}
+
+// IsInternalHost reports whether a normalized host points at the local machine or
+// a non-public network: localhost, private, loopback, link-local and unspecified
+// IP literals, and numeric hosts that browsers read as IPv4 addresses.
+func IsInternalHost(host string) bool {
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return true
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+			ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast()
+	}
+
+	// Browsers resolve hosts such as 2130706433 or 0x7f.1 to IPv4 addresses, so a
+	// numeric last label can hide a loopback address from net.ParseIP
+	return isNumericLabel(host[strings.LastIndexByte(host, '.')+1:])
+}
+
+func isNumericLabel(label string) bool {
+	digits := label
+	if rest, ok := strings.CutPrefix(label, "0x"); ok {
+		digits = rest
+	}
+	if digits == "" {
+		return label == "0x"
+	}
+	for i := 0; i < len(digits); i++ {
+		if !isHexDigit(digits[i]) {
+			return false
+		}
+	}
+	// Without the 0x prefix only decimal digits make a number
+	return digits != label || strings.Trim(label, "0123456789") == ""
+}
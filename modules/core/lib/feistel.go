@@ -0,0 +1,159 @@
+package lib
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var ErrBadChecksum = errors.New("short code check characters do not match")
+
+const (
+	// MinCodeKeyLen is the shortest secret accepted for a code key.
+	MinCodeKeyLen = 16
+
+	// MaxCodeKeyVersion is the highest key version, one per code tag after Base57Tag.
+	MaxCodeKeyVersion = len(codeTags) - 1
+
+	// MaxCheckChars is the most check characters a keyed code can carry.
+	MaxCheckChars = 2
+
+	feistelRounds = 6
+)
+
+// Feistel is a keyed permutation of 64-bit values: a balanced Feistel network over
+// two 32-bit halves with an HMAC-SHA256 round function. Every value has exactly one
+// image, so permuted IDs never collide, and Invert undoes Permute.
+type Feistel struct {
+	secret []byte
+}
+
+func NewFeistel(secret []byte) (*Feistel, error) {
+	if len(secret) < MinCodeKeyLen {
+		return nil, errors.New("code key must be at least 16 bytes")
+	}
+	return &Feistel{secret: append([]byte(nil), secret...)}, nil
+}
+
+func (f *Feistel) Permute(x uint64) uint64 {
+	left, right := uint32(x>>32), uint32(x)
+	for round := 0; round < feistelRounds; round++ {
+		left, right = right, left^f.round(round, right)
+	}
+	return uint64(left)<<32 | uint64(right)
+}
+
+func (f *Feistel) Invert(x uint64) uint64 {
+	left, right := uint32(x>>32), uint32(x)
+	for round := feistelRounds - 1; round >= 0; round-- {
+		left, right = right^f.round(round, left), left
+	}
+	return uint64(left)<<32 | uint64(right)
+}
+
+func (f *Feistel) round(round int, half uint32) uint32 {
+	var input [5]byte
+	input[0] = byte(round)
+	binary.BigEndian.PutUint32(input[1:], half)
+
+	mac := hmac.New(sha256.New, f.secret)
+	mac.Write(input[:])
+	return binary.BigEndian.Uint32(mac.Sum(nil))
+}
+
+// keyedCodeWidth is the number of base-57 digits that hold any 64-bit value.
+const keyedCodeWidth = 11
+
+// KeyedCodec encodes a keyed permutation of each ID as a fixed-width base-57 code,
+// so consecutive IDs give unrelated codes and cannot be enumerated without the key.
+// The tag records the key version: codes of retired keys keep resolving as long
+// as their codec stays in the code set.
+//
+// Codes can end in HMAC-derived check characters, which let Decode refuse
+// mistyped or guessed codes without a lookup. Whether a version carries them is
+// fixed when the version is introduced, so every code of a version has the same
+// length.
+type KeyedCodec struct {
+	base    *BaseNCodec
+	feistel *Feistel
+	checks  int
+}
+
+// NewKeyedCodec creates the codec of a key version, from 1 to MaxCodeKeyVersion,
+// whose codes end in checks check characters, from 0 to MaxCheckChars.
+func NewKeyedCodec(version int, secret []byte, checks int) (*KeyedCodec, error) {
+	if version < 1 || version > MaxCodeKeyVersion {
+		return nil, errors.New("code key version must be between 1 and 14")
+	}
+	if checks < 0 || checks > MaxCheckChars {
+		return nil, errors.New("code check characters must be between 0 and 2")
+	}
+	feistel, err := NewFeistel(secret)
+	if err != nil {
+		return nil, err
+	}
+	base, err := NewBaseNCodec(Base57Alphabet, codeTags[version])
+	if err != nil {
+		return nil, err
+	}
+	return &KeyedCodec{base: base, feistel: feistel, checks: checks}, nil
+}
+
+func (c *KeyedCodec) Encode(id int64) string {
+	code := string(c.base.tag) + c.base.digits(c.feistel.Permute(uint64(id)), keyedCodeWidth)
+	return code + c.checkChars(code)
+}
+
+// Decode returns ErrBadChecksum for a code whose check characters do not match.
+func (c *KeyedCodec) Decode(code string) (int64, error) {
+	if !c.Matches(code) {
+		return 0, ErrInvalidCode
+	}
+	body := code[:1+keyedCodeWidth]
+	if subtle.ConstantTimeCompare([]byte(code[len(body):]), []byte(c.checkChars(body))) != 1 {
+		return 0, ErrBadChecksum
+	}
+	n, ok := c.base.value(body[1:])
+	if !ok {
+		return 0, ErrInvalidCode
+	}
+	// Only permutations of valid IDs were ever handed out
+	id := c.feistel.Invert(n)
+	if id > math.MaxInt64 {
+		return 0, ErrInvalidCode
+	}
+	return int64(id), nil
+}
+
+func (c *KeyedCodec) Alphabet() string {
+	return c.base.Alphabet()
+}
+
+// Checks returns the number of check characters the codec's codes end in.
+func (c *KeyedCodec) Checks() int {
+	return c.checks
+}
+
+func (c *KeyedCodec) Matches(code string) bool {
+	return len(code) == 1+keyedCodeWidth+c.checks && c.base.Matches(code)
+}
+
+// checkChars derives the check characters of a code from an HMAC of it under the
+// codec's key. A mistyped code slips through with probability 1/57 per check character.
+func (c *KeyedCodec) checkChars(code string) string {
+	if c.checks == 0 {
+		return ""
+	}
+	mac := hmac.New(sha256.New, c.feistel.secret)
+	mac.Write([]byte("check:" + code))
+	sum := mac.Sum(nil)
+
+	checks := make([]byte, c.checks)
+	for i := range checks {
+		checks[i] = c.base.alphabet[binary.BigEndian.Uint16(sum[2*i:])%uint16(len(c.base.alphabet))]
+	}
+	return string(checks)
+}
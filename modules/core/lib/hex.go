@@ -18,6 +18,53 @@ var decodeMap = map[rune]rune{
 	'h': '1',
 }
 
+// confusableMap reads the characters ADR-001 avoids as the ones that replaced
+// them, after case folding: 'o' and '0' as zero ('g'), 'i', 'l' and '1' as one ('h').
+var confusableMap = map[rune]rune{
+	'o': 'g',
+	'0': 'g',
+	'i': 'h',
+	'l': 'h',
+	'1': 'h',
+}
+
+// hexAlphabet lists every character HexEncode can produce.
+const hexAlphabet = "23456789abcdefgh"
+
+// IsHexCode reports whether s consists only of characters HexEncode can produce.
+func IsHexCode(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune(hexAlphabet, c) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanonicalHexCode returns the code HexEncode would produce for a retyped code:
+// case is folded and confusable characters are read as zero or one. ok is false
+// when s is not a hex code even then.
+func CanonicalHexCode(s string) (string, bool) {
+	if s == "" {
+		return "", false
+	}
+
+	var result strings.Builder
+	for _, c := range strings.ToLower(s) {
+		if mapped, ok := confusableMap[c]; ok {
+			c = mapped
+		}
+		if !strings.ContainsRune(hexAlphabet, c) {
+			return "", false
+		}
+		result.WriteRune(c)
+	}
+	return result.String(), true
+}
+
 // HexEncode converts a number to a custom hex string.
 func HexEncode(n int64) string {
 	hex := fmt.Sprintf("%x", n)
@@ -34,14 +81,17 @@ func HexEncode(n int64) string {
 	return result.String()
 }
 
-// HexDecode converts a custom hex string back to a number.
+// HexDecode converts a custom hex string back to a number. It accepts the
+// spellings CanonicalHexCode folds, such as "FF" or "lO" for "ff" and "hg".
 func HexDecode(s string) (int64, error) {
-	if s == "" {
+	canonical, ok := CanonicalHexCode(s)
+	if !ok {
+		// Sscanf stops at the first non-hex rune, so reject up front
 		return 0, ErrInvalidHexChar
 	}
 
 	var result strings.Builder
-	for _, c := range s {
+	for _, c := range canonical {
 		if mapped, ok := decodeMap[c]; ok {
 			result.WriteRune(mapped)
 		} else {
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+)
+
+const (
+	// APIKeyPrefix marks the keys issued by this service so they are easy to spot in logs and secret scanners.
+	APIKeyPrefix = "doit_"
+
+	MaxOwnerIDLen = 255
+	apiKeyBytes   = 32
+)
+
+var (
+	ErrInvalidAPIKey  = errors.New("invalid API key")
+	ErrInvalidOwnerID = errors.New("invalid owner: must be 1-255 characters")
+
+	// ErrForbidden refuses to edit or revoke a link created without an API key,
+	// unless the caller is an admin.
+	ErrForbidden = errors.New("link has no owner and cannot be changed")
+)
+
+// APIKeyAuthenticator resolves the owner behind an API key.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, rawKey string) (string, error)
+}
+
+type APIKeyService struct {
+	repo APIKeyRepo
+}
+
+func NewAPIKeyService(repo APIKeyRepo) *APIKeyService {
+	return &APIKeyService{repo: repo}
+}
+
+// Issue creates a key for the owner and returns it in clear text. Only its hash is
+// stored, so the key cannot be shown again.
+func (s *APIKeyService) Issue(ctx context.Context, ownerID, name string) (string, *entity.APIKey, error) {
+	if ownerID == "" || len(ownerID) > MaxOwnerIDLen {
+		return "", nil, ErrInvalidOwnerID
+	}
+
+	secret := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", nil, err
+	}
+	rawKey := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(secret)
+
+	key := &entity.APIKey{
+		OwnerID:   ownerID,
+		Name:      name,
+		KeyHash:   hashAPIKey(rawKey),
+		CreatedAt: time.Now(),
+	}
+	id, err := s.repo.Create(ctx, key)
+	if err != nil {
+		return "", nil, err
+	}
+	key.ID = id
+
+	return rawKey, key, nil
+}
+
+// Authenticate returns the owner of a live key. Unknown and revoked keys are
+// both reported as ErrInvalidAPIKey.
+func (s *APIKeyService) Authenticate(ctx context.Context, rawKey string) (string, error) {
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		return "", ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetByHash(ctx, hashAPIKey(rawKey))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", ErrInvalidAPIKey
+		}
+		return "", err
+	}
+	if key.RevokedAt != nil {
+		return "", ErrInvalidAPIKey
+	}
+	return key.OwnerID, nil
+}
+
+// Revoke disables a key. Links created with it keep their owner.
+func (s *APIKeyService) Revoke(ctx context.Context, id int64) error {
+	err := s.repo.Revoke(ctx, id, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// hashAPIKey returns the stored form of a key. Keys carry 256 random bits, so a
+// fast hash is enough and lets the key be found by its hash.
+func hashAPIKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+type ownerKey struct{}
+
+type adminKey struct{}
+
+// WithOwner returns a context carrying the authenticated owner of the request.
+func WithOwner(ctx context.Context, ownerID string) context.Context {
+	return context.WithValue(ctx, ownerKey{}, ownerID)
+}
+
+// OwnerFromContext returns the authenticated owner, if the request has one.
+func OwnerFromContext(ctx context.Context) (string, bool) {
+	ownerID, ok := ctx.Value(ownerKey{}).(string)
+	return ownerID, ok
+}
+
+// WithAdmin returns a context marking the request as made by an operator, who may
+// read, edit and revoke every link, including links without an owner.
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey{}, true)
+}
+
+// IsAdmin reports whether the request was made by an operator.
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey{}).(bool)
+	return admin
+}
+
+// ownerOf returns the owner to record on a new link, nil for anonymous requests.
+func ownerOf(ctx context.Context) *string {
+	if ownerID, ok := OwnerFromContext(ctx); ok {
+		return &ownerID
+	}
+	return nil
+}
+
+// authorizeOwner lets only the owner or an admin read an owned link. Anonymous
+// links can be read by everyone. Other callers get ErrNotFound so owned codes
+// cannot be probed.
+func authorizeOwner(ctx context.Context, analytic *entity.URLAnalytic) error {
+	if analytic.OwnerID == nil || IsAdmin(ctx) {
+		return nil
+	}
+	if ownerID, ok := OwnerFromContext(ctx); ok && ownerID == *analytic.OwnerID {
+		return nil
+	}
+	return ErrNotFound
+}
+
+// authorizeChange lets only the owner or an admin edit or revoke a link. A link
+// created without an API key has no one to prove ownership, so only an admin can
+// change it.
+func authorizeChange(ctx context.Context, analytic *entity.URLAnalytic) error {
+	if IsAdmin(ctx) {
+		return nil
+	}
+	if analytic.OwnerID == nil {
+		return ErrForbidden
+	}
+	return authorizeOwner(ctx, analytic)
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/lib"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAPIKeyService_Issue(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var stored *entity.APIKey
+	repo := mocks.NewMockAPIKeyRepo(ctrl)
+	repo.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, key *entity.APIKey) (int64, error) {
+			stored = key
+			return 7, nil
+		})
+
+	svc := NewAPIKeyService(repo)
+	rawKey, key, err := svc.Issue(context.Background(), "team-a", "ci")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !strings.HasPrefix(rawKey, APIKeyPrefix) {
+		t.Errorf("expected key to start with %s, got %s", APIKeyPrefix, rawKey)
+	}
+	if key.ID != 7 || key.OwnerID != "team-a" || key.Name != "ci" {
+		t.Errorf("unexpected key record: %+v", key)
+	}
+	if stored.KeyHash == "" || strings.Contains(stored.KeyHash, rawKey) {
+		t.Errorf("expected only a hash of the key to be stored, got %q", stored.KeyHash)
+	}
+
+	if _, _, err := svc.Issue(context.Background(), "", "ci"); !errors.Is(err, ErrInvalidOwnerID) {
+		t.Errorf("expected ErrInvalidOwnerID for empty owner, got %v", err)
+	}
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	revokedAt := time.Now()
+	rawKey := APIKeyPrefix + "secret"
+
+	tests := []struct {
+		name        string
+		rawKey      string
+		stored      *entity.APIKey
+		lookupError error
+		expectOwner string
+		expectError error
+	}{
+		{
+			name:        "live_key_returns_owner",
+			rawKey:      rawKey,
+			stored:      &entity.APIKey{OwnerID: "team-a"},
+			expectOwner: "team-a",
+		},
+		{
+			name:        "revoked_key_is_invalid",
+			rawKey:      rawKey,
+			stored:      &entity.APIKey{OwnerID: "team-a", RevokedAt: &revokedAt},
+			expectError: ErrInvalidAPIKey,
+		},
+		{
+			name:        "unknown_key_is_invalid",
+			rawKey:      rawKey,
+			lookupError: sql.ErrNoRows,
+			expectError: ErrInvalidAPIKey,
+		},
+		{
+			name:        "key_without_prefix_is_invalid",
+			rawKey:      "secret",
+			expectError: ErrInvalidAPIKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockAPIKeyRepo(ctrl)
+			if tt.stored != nil || tt.lookupError != nil {
+				repo.EXPECT().GetByHash(gomock.Any(), hashAPIKey(tt.rawKey)).Return(tt.stored, tt.lookupError)
+			}
+
+			svc := NewAPIKeyService(repo)
+			owner, err := svc.Authenticate(context.Background(), tt.rawKey)
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if owner != tt.expectOwner {
+				t.Errorf("expected owner %q, got %q", tt.expectOwner, owner)
+			}
+		})
+	}
+}
+
+func TestLinkOwnership(t *testing.T) {
+	tests := []struct {
+		name        string
+		linkOwner   *string
+		caller      *string
+		admin       bool
+		expectError error
+		// expectRevokeError defaults to expectError
+		expectRevokeError error
+	}{
+		{
+			name:      "owner_sees_own_link",
+			linkOwner: ptr("team-a"),
+			caller:    ptr("team-a"),
+		},
+		{
+			name:        "other_owner_gets_not_found",
+			linkOwner:   ptr("team-a"),
+			caller:      ptr("team-b"),
+			expectError: ErrNotFound,
+		},
+		{
+			name:        "anonymous_caller_gets_not_found",
+			linkOwner:   ptr("team-a"),
+			expectError: ErrNotFound,
+		},
+		{
+			name:              "anonymous_link_is_readable_but_not_revocable",
+			caller:            ptr("team-b"),
+			expectRevokeError: ErrForbidden,
+		},
+		{
+			name:   "admin_revokes_anonymous_link",
+			caller: ptr("ops"),
+			admin:  true,
+		},
+		{
+			name:      "admin_revokes_other_owners_link",
+			linkOwner: ptr("team-a"),
+			caller:    ptr("ops"),
+			admin:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			analytic := &entity.URLAnalytic{URLID: 5, LongURL: "https://example.com", OwnerID: tt.linkOwner}
+			ctx := context.Background()
+			if tt.caller != nil {
+				ctx = WithOwner(ctx, *tt.caller)
+			}
+			if tt.admin {
+				ctx = WithAdmin(ctx)
+			}
+
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+			analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(5)).Return(analytic, nil).Times(2)
+
+			// Statistics and revocation apply the same rule to owned links
+			if _, err := NewLinkAnalyzerService(analyticRepo, lib.DefaultCodeSet()).Analyze(ctx, lib.HexEncode(5)); !errors.Is(err, tt.expectError) {
+				t.Errorf("analyze: expected error %v, got %v", tt.expectError, err)
+			}
+
+			revokeError := tt.expectError
+			if tt.expectRevokeError != nil {
+				revokeError = tt.expectRevokeError
+			}
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			if revokeError == nil {
+				cacheRepo.EXPECT().MarkRevoked(gomock.Any(), int64(5), gomock.Any()).Return(nil)
+				cacheRepo.EXPECT().Delete(gomock.Any(), int64(5)).Return(nil)
+				analyticRepo.EXPECT().Revoke(gomock.Any(), int64(5), nil, gomock.Any()).Return(nil)
+				analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(5)).Return(analytic, nil)
+			}
+			if _, err := NewLinkRevokerService(cacheRepo, analyticRepo, lib.DefaultCodeSet()).Revoke(ctx, lib.HexEncode(5), nil); !errors.Is(err, revokeError) {
+				t.Errorf("revoke: expected error %v, got %v", revokeError, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+)
+
+// DefaultRebuildBatchSize is how many links a rebuild reads and writes at a time.
+const DefaultRebuildBatchSize = 500
+
+// RebuildOptions controls a cache rebuild.
+type RebuildOptions struct {
+	// AfterURLID resumes a rebuild after the last link a previous run reported.
+	AfterURLID int64
+	// DryRun reads and counts the links without writing to Redis.
+	DryRun bool
+	// BatchSize defaults to DefaultRebuildBatchSize.
+	BatchSize int
+}
+
+// RebuildProgress counts the links a rebuild has gone through so far.
+type RebuildProgress struct {
+	// LastURLID is the highest link ID handled; pass it as AfterURLID to resume.
+	LastURLID int64
+	// Scanned links were unexpired and not revoked in analytics.
+	Scanned int
+	// Restored links had no keys in Redis and were written back. In a dry run it
+	// counts the links that would be written, present or not.
+	Restored int
+	// Present links already had keys in Redis and were left alone.
+	Present int
+	// Skipped links were recorded before password hashes were kept, so they may be
+	// protected and are not restored.
+	Skipped int
+}
+
+// CacheRebuilderService writes the links recorded in analytics back to Redis,
+// after Redis lost data. Every live link is restored with the TTL it has left and
+// the clicks it has left; links already in Redis are not touched.
+type CacheRebuilderService struct {
+	cacheRepo    URLCacheRepo
+	analyticRepo URLAnalyticRepo
+}
+
+func NewCacheRebuilderService(cacheRepo URLCacheRepo, analyticRepo URLAnalyticRepo) *CacheRebuilderService {
+	return &CacheRebuilderService{
+		cacheRepo:    cacheRepo,
+		analyticRepo: analyticRepo,
+	}
+}
+
+// Rebuild restores links in URLID order, calling progress after each batch. It
+// first moves the ID sequence past every recorded ID, so links created while it
+// runs never take the ID of a link it has yet to restore. It stops at the first
+// storage error and reports the progress made until then.
+func (s *CacheRebuilderService) Rebuild(ctx context.Context, opts RebuildOptions, progress func(RebuildProgress)) (RebuildProgress, error) {
+	result := RebuildProgress{LastURLID: opts.AfterURLID}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = DefaultRebuildBatchSize
+	}
+
+	if !opts.DryRun {
+		if err := s.advanceSequence(ctx); err != nil {
+			return result, err
+		}
+	}
+
+	for {
+		scanned, err := s.rebuildPage(ctx, opts.DryRun, batchSize, &result)
+		if err != nil {
+			return result, err
+		}
+		if scanned == 0 {
+			return result, nil
+		}
+		if progress != nil {
+			progress(result)
+		}
+		if scanned < batchSize {
+			return result, nil
+		}
+	}
+}
+
+// advanceSequence moves the ID sequence past the highest recorded link ID.
+func (s *CacheRebuilderService) advanceSequence(ctx context.Context) error {
+	maxID, err := s.analyticRepo.MaxURLID(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = s.cacheRepo.AdvanceSequence(ctx, maxID)
+	return err
+}
+
+// rebuildPage fetches the next page of live links after result.LastURLID, restores
+// it and adds it to result. It returns how many links the page held.
+func (s *CacheRebuilderService) rebuildPage(ctx context.Context, dryRun bool, batchSize int, result *RebuildProgress) (int, error) {
+	now := time.Now()
+	links, err := s.analyticRepo.ListLive(ctx, result.LastURLID, now, batchSize)
+	if err != nil || len(links) == 0 {
+		return 0, err
+	}
+
+	if err := s.restoreRows(ctx, links, now, dryRun, result); err != nil {
+		return 0, err
+	}
+	result.Scanned += len(links)
+	result.LastURLID = links[len(links)-1].URLID
+	return len(links), nil
+}
+
+// restoreRows writes back the links of one page, skipping those recorded without a
+// password hash, and counts each as restored, present or skipped.
+func (s *CacheRebuilderService) restoreRows(ctx context.Context, links []*entity.URLAnalytic, now time.Time, dryRun bool, result *RebuildProgress) error {
+	known := make([]*entity.URLAnalytic, 0, len(links))
+	for _, link := range links {
+		if link.PasswordHash == nil {
+			result.Skipped++
+			continue
+		}
+		known = append(known, link)
+	}
+
+	restored := len(known)
+	if !dryRun && len(known) > 0 {
+		var err error
+		restored, err = s.cacheRepo.Restore(ctx, known, now)
+		if err != nil {
+			return err
+		}
+		result.Present += len(known) - restored
+	}
+	result.Restored += restored
+	return nil
+}
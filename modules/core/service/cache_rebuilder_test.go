@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func recordedLink(id int64, passwordHash *string) *entity.URLAnalytic {
+	return &entity.URLAnalytic{URLID: id, LongURL: "https://example.com", PasswordHash: passwordHash}
+}
+
+func TestCacheRebuilderService_Rebuild(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	none, hash := "", "$2a$10$hash"
+
+	gomock.InOrder(
+		analyticRepo.EXPECT().MaxURLID(gomock.Any()).Return(int64(9), nil),
+		cacheRepo.EXPECT().AdvanceSequence(gomock.Any(), int64(9)).Return(int64(0), nil),
+		analyticRepo.EXPECT().ListLive(gomock.Any(), int64(0), gomock.Any(), 3).
+			Return([]*entity.URLAnalytic{recordedLink(1, &none), recordedLink(2, nil), recordedLink(4, &hash)}, nil),
+		// A link recorded before hashes were kept may be protected, so it is left out
+		cacheRepo.EXPECT().Restore(gomock.Any(), gomock.Len(2), gomock.Any()).Return(1, nil),
+		// A full batch means more may follow
+		analyticRepo.EXPECT().ListLive(gomock.Any(), int64(4), gomock.Any(), 3).
+			Return([]*entity.URLAnalytic{recordedLink(7, &none)}, nil),
+		cacheRepo.EXPECT().Restore(gomock.Any(), gomock.Len(1), gomock.Any()).Return(1, nil),
+	)
+
+	var reported []RebuildProgress
+	svc := NewCacheRebuilderService(cacheRepo, analyticRepo)
+	result, err := svc.Rebuild(context.Background(), RebuildOptions{BatchSize: 3}, func(p RebuildProgress) {
+		reported = append(reported, p)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := RebuildProgress{LastURLID: 7, Scanned: 4, Restored: 2, Present: 1, Skipped: 1}
+	if result != expected {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+	if len(reported) != 2 || reported[0].LastURLID != 4 || reported[1] != expected {
+		t.Errorf("expected progress after each batch, got %+v", reported)
+	}
+}
+
+func TestCacheRebuilderService_DryRunWritesNothing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	none := ""
+
+	// Resumes after the given ID and never calls the cache
+	analyticRepo.EXPECT().ListLive(gomock.Any(), int64(10), gomock.Any(), DefaultRebuildBatchSize).
+		Return([]*entity.URLAnalytic{recordedLink(11, &none), recordedLink(12, nil)}, nil)
+
+	svc := NewCacheRebuilderService(cacheRepo, analyticRepo)
+	result, err := svc.Rebuild(context.Background(), RebuildOptions{AfterURLID: 10, DryRun: true}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := RebuildProgress{LastURLID: 12, Scanned: 2, Restored: 1, Skipped: 1}
+	if result != expected {
+		t.Errorf("expected %+v, got %+v", expected, result)
+	}
+}
+
+func TestCacheRebuilderService_StopsOnRestoreError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	errUnavailable := errors.New("redis unavailable")
+	none := ""
+
+	analyticRepo.EXPECT().MaxURLID(gomock.Any()).Return(int64(5), nil)
+	cacheRepo.EXPECT().AdvanceSequence(gomock.Any(), int64(5)).Return(int64(5), nil)
+	analyticRepo.EXPECT().ListLive(gomock.Any(), int64(3), gomock.Any(), gomock.Any()).
+		Return([]*entity.URLAnalytic{recordedLink(4, &none)}, nil)
+	cacheRepo.EXPECT().Restore(gomock.Any(), gomock.Any(), gomock.Any()).Return(0, errUnavailable)
+
+	svc := NewCacheRebuilderService(cacheRepo, analyticRepo)
+	result, err := svc.Rebuild(context.Background(), RebuildOptions{AfterURLID: 3}, nil)
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected restore error, got %v", err)
+	}
+	// The failed batch is not counted, so a resume retries it
+	if result.LastURLID != 3 {
+		t.Errorf("expected to resume after 3, got %d", result.LastURLID)
+	}
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// CacheBreakerThreshold is how many Redis failures in a row open the breaker.
+	CacheBreakerThreshold = 5
+	// CacheBreakerCooldown is how long an open breaker keeps requests off Redis.
+	CacheBreakerCooldown = 10 * time.Second
+)
+
+// CircuitBreaker stops calls to a failing dependency. After threshold failures in
+// a row it opens and Allow refuses calls for the cooldown. A single probe call is
+// then let through: its success closes the breaker, its failure reopens it. If it
+// reports neither, another probe is let through after a further cooldown.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	onChange  func(open bool)
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	open      bool
+	openUntil time.Time
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// OnStateChange calls f whenever the breaker opens or closes.
+func (b *CircuitBreaker) OnStateChange(f func(open bool)) *CircuitBreaker {
+	b.onChange = f
+	return b
+}
+
+// Allow reports whether a call may be made. Once an open breaker's cooldown is
+// over it allows one call, and refuses the others for another cooldown.
+func (b *CircuitBreaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	now := b.now()
+	if now.Before(b.openUntil) {
+		return false
+	}
+	b.openUntil = now.Add(b.cooldown)
+	return true
+}
+
+// Open reports whether the breaker is open, including after its cooldown until a
+// call succeeds.
+func (b *CircuitBreaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// Success records a call that succeeded.
+func (b *CircuitBreaker) Success() {
+	b.mu.Lock()
+	closing := b.open
+	b.failures = 0
+	b.open = false
+	b.mu.Unlock()
+
+	if closing && b.onChange != nil {
+		b.onChange(false)
+	}
+}
+
+// Failure records a call that failed.
+func (b *CircuitBreaker) Failure() {
+	b.mu.Lock()
+	b.failures++
+	opening := false
+	if b.failures >= b.threshold {
+		opening = !b.open
+		b.open = true
+		b.openUntil = b.now().Add(b.cooldown)
+	}
+	b.mu.Unlock()
+
+	if opening && b.onChange != nil {
+		b.onChange(true)
+	}
+}
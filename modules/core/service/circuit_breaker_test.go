@@ -0,0 +1,61 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var changes []bool
+	breaker := NewCircuitBreaker(3, 10*time.Second).OnStateChange(func(open bool) {
+		changes = append(changes, open)
+	})
+	breaker.now = func() time.Time { return now }
+
+	// A success resets the run of failures
+	breaker.Failure()
+	breaker.Failure()
+	breaker.Success()
+	breaker.Failure()
+	breaker.Failure()
+	if !breaker.Allow() || breaker.Open() {
+		t.Fatal("expected breaker to stay closed below the threshold")
+	}
+
+	breaker.Failure()
+	if breaker.Allow() || !breaker.Open() {
+		t.Fatal("expected breaker to open at the threshold")
+	}
+
+	// After the cooldown a single probe goes through, and its failure reopens the breaker
+	now = now.Add(10 * time.Second)
+	if !breaker.Allow() {
+		t.Fatal("expected a call to be allowed after the cooldown")
+	}
+	if breaker.Allow() {
+		t.Fatal("expected only one call to be allowed while the probe runs")
+	}
+	breaker.Failure()
+	if breaker.Allow() {
+		t.Fatal("expected a failure after the cooldown to reopen the breaker")
+	}
+
+	// A probe that reports nothing does not keep the breaker open for good
+	now = now.Add(10 * time.Second)
+	if !breaker.Allow() || breaker.Allow() {
+		t.Fatal("expected a single probe after the cooldown")
+	}
+	now = now.Add(10 * time.Second)
+	if !breaker.Allow() {
+		t.Fatal("expected another probe once the first reported nothing")
+	}
+	breaker.Success()
+	if !breaker.Allow() || breaker.Open() {
+		t.Fatal("expected a success to close the breaker")
+	}
+
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Errorf("expected one open and one close, got %v", changes)
+	}
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nanda/doit/modules/core/lib"
+)
+
+// DestinationPolicyReloadInterval is how often a file-backed policy checks its file for changes.
+const DestinationPolicyReloadInterval = 10 * time.Second
+
+// Machine-readable reasons carried by a PolicyViolation.
+const (
+	ReasonInternalHost     = "internal_host"
+	ReasonDomainDenied     = "domain_denied"
+	ReasonDomainNotAllowed = "domain_not_allowed"
+)
+
+var ErrDestinationRejected = errors.New("destination is not allowed")
+
+// PolicyViolation is the error a DestinationPolicy returns for a refused URL.
+// It matches ErrDestinationRejected with errors.Is.
+type PolicyViolation struct {
+	Reason string
+}
+
+func (v *PolicyViolation) Error() string {
+	return ErrDestinationRejected.Error() + ": " + v.Reason
+}
+
+func (v *PolicyViolation) Unwrap() error {
+	return ErrDestinationRejected
+}
+
+// DestinationPolicy decides whether a canonical long URL may be shortened.
+type DestinationPolicy interface {
+	Check(ctx context.Context, longURL string) error
+}
+
+// DestinationRules is a DestinationPolicy built from allow and deny lists of exact
+// ("example.com") and wildcard ("*.example.com") domains. Internal hosts are always
+// refused, deny entries win over allow entries, and a non-empty allow list refuses
+// every domain it does not match. The zero value only refuses internal hosts.
+type DestinationRules struct {
+	Allow []string `json:"allow"`
+	Deny  []string `json:"deny"`
+}
+
+func (r *DestinationRules) Check(_ context.Context, longURL string) error {
+	parsed, err := url.Parse(longURL)
+	if err != nil {
+		return ErrInvalidURL
+	}
+	host, err := lib.NormalizeDomain(parsed.Hostname())
+	if err != nil {
+		return ErrInvalidURL
+	}
+
+	switch {
+	case lib.IsInternalHost(host):
+		return &PolicyViolation{Reason: ReasonInternalHost}
+	case matchAny(r.Deny, host):
+		return &PolicyViolation{Reason: ReasonDomainDenied}
+	case len(r.Allow) > 0 && !matchAny(r.Allow, host):
+		return &PolicyViolation{Reason: ReasonDomainNotAllowed}
+	}
+	return nil
+}
+
+// normalized returns a copy of the rules with every entry normalized for matching.
+func (r *DestinationRules) normalized() (*DestinationRules, error) {
+	allow, err := normalizePatterns(r.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := normalizePatterns(r.Deny)
+	if err != nil {
+		return nil, err
+	}
+	return &DestinationRules{Allow: allow, Deny: deny}, nil
+}
+
+func normalizePatterns(patterns []string) ([]string, error) {
+	normalized := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		rest, wildcard := strings.CutPrefix(pattern, "*.")
+		domain, err := lib.NormalizeDomain(rest)
+		if err != nil {
+			return nil, err
+		}
+		if wildcard {
+			domain = "*." + domain
+		}
+		normalized[i] = domain
+	}
+	return normalized, nil
+}
+
+func matchAny(patterns []string, host string) bool {
+	for _, pattern := range patterns {
+		if lib.MatchDomain(pattern, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// FileDestinationPolicy applies DestinationRules read from a JSON file of the form
+// {"allow": [...], "deny": [...]}. The file is re-read when its modification time
+// changes, checked at most once per reload interval, so edits take effect without
+// a restart. A file that fails to load keeps the previous rules in force.
+type FileDestinationPolicy struct {
+	path     string
+	interval time.Duration
+	rules    atomic.Pointer[DestinationRules]
+
+	mu        sync.Mutex
+	modTime   time.Time
+	nextCheck time.Time
+}
+
+// NewFileDestinationPolicy loads the rules file, failing if it cannot be read or parsed.
+func NewFileDestinationPolicy(path string, interval time.Duration) (*FileDestinationPolicy, error) {
+	p := &FileDestinationPolicy{path: path, interval: interval}
+	if err := p.Reload(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+func (p *FileDestinationPolicy) Check(ctx context.Context, longURL string) error {
+	p.reloadIfChanged()
+	return p.rules.Load().Check(ctx, longURL)
+}
+
+// Reload re-reads the rules file unconditionally.
+func (p *FileDestinationPolicy) Reload() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(p.path)
+	if err != nil {
+		return err
+	}
+	return p.load(info.ModTime())
+}
+
+func (p *FileDestinationPolicy) reloadIfChanged() {
+	// Another request is already checking the file
+	if !p.mu.TryLock() {
+		return
+	}
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if now.Before(p.nextCheck) {
+		return
+	}
+	p.nextCheck = now.Add(p.interval)
+
+	info, err := os.Stat(p.path)
+	if err != nil || info.ModTime().Equal(p.modTime) {
+		return
+	}
+	_ = p.load(info.ModTime())
+}
+
+func (p *FileDestinationPolicy) load(modTime time.Time) error {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return err
+	}
+
+	var rules DestinationRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	normalized, err := rules.normalized()
+	if err != nil {
+		return err
+	}
+
+	p.rules.Store(normalized)
+	p.modTime = modTime
+	return nil
+}
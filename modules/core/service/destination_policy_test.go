@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDestinationRules_Check(t *testing.T) {
+	rules := &DestinationRules{
+		Allow: []string{"example.com", "*.example.org", "*.phish.example.org"},
+		Deny:  []string{"*.phish.example.org", "bad.example.com"},
+	}
+
+	tests := []struct {
+		name         string
+		rules        *DestinationRules
+		longURL      string
+		expectReason string
+	}{
+		{name: "public_host_passes_empty_rules", rules: &DestinationRules{}, longURL: "https://example.net/a"},
+		{name: "localhost_is_refused", rules: &DestinationRules{}, longURL: "http://localhost:8080/admin", expectReason: ReasonInternalHost},
+		{name: "localhost_subdomain_is_refused", rules: &DestinationRules{}, longURL: "http://api.localhost/", expectReason: ReasonInternalHost},
+		{name: "loopback_ip_is_refused", rules: &DestinationRules{}, longURL: "http://127.0.0.1/", expectReason: ReasonInternalHost},
+		{name: "private_ip_is_refused", rules: &DestinationRules{}, longURL: "http://10.1.2.3/", expectReason: ReasonInternalHost},
+		{name: "link_local_ip_is_refused", rules: &DestinationRules{}, longURL: "http://169.254.169.254/latest", expectReason: ReasonInternalHost},
+		{name: "ipv6_loopback_is_refused", rules: &DestinationRules{}, longURL: "http://[::1]/", expectReason: ReasonInternalHost},
+		{name: "numeric_ipv4_host_is_refused", rules: &DestinationRules{}, longURL: "http://2130706433/", expectReason: ReasonInternalHost},
+		{name: "public_ip_passes", rules: &DestinationRules{}, longURL: "http://93.184.216.34/"},
+		{name: "exact_allow_matches", rules: rules, longURL: "https://example.com/x"},
+		{name: "wildcard_allow_matches_subdomain", rules: rules, longURL: "https://www.example.org/x"},
+		{name: "wildcard_allow_skips_apex", rules: rules, longURL: "https://example.org/x", expectReason: ReasonDomainNotAllowed},
+		{name: "unlisted_domain_is_not_allowed", rules: rules, longURL: "https://other.net/", expectReason: ReasonDomainNotAllowed},
+		{name: "deny_wins_over_allow", rules: rules, longURL: "https://login.phish.example.org/", expectReason: ReasonDomainDenied},
+		{name: "exact_deny_matches_trailing_dot", rules: rules, longURL: "https://bad.example.com./", expectReason: ReasonDomainDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.rules.Check(context.Background(), tt.longURL)
+
+			if tt.expectReason == "" {
+				if err != nil {
+					t.Errorf("expected no error, got %v", err)
+				}
+				return
+			}
+
+			var violation *PolicyViolation
+			if !errors.As(err, &violation) {
+				t.Fatalf("expected policy violation, got %v", err)
+			}
+			if violation.Reason != tt.expectReason {
+				t.Errorf("expected reason %s, got %s", tt.expectReason, violation.Reason)
+			}
+			if !errors.Is(err, ErrDestinationRejected) {
+				t.Error("expected error to match ErrDestinationRejected")
+			}
+		})
+	}
+}
+
+func TestFileDestinationPolicy_ReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy(t, path, `{"deny": ["Evil.Example"]}`, time.Now().Add(-time.Minute))
+
+	policy, err := NewFileDestinationPolicy(path, 0)
+	if err != nil {
+		t.Fatalf("failed to load policy: %v", err)
+	}
+	ctx := context.Background()
+
+	if err := policy.Check(ctx, "https://evil.example/"); !errors.Is(err, ErrDestinationRejected) {
+		t.Fatalf("expected denied domain to be rejected, got %v", err)
+	}
+
+	writePolicy(t, path, `{"deny": ["other.example"]}`, time.Now())
+	if err := policy.Check(ctx, "https://evil.example/"); err != nil {
+		t.Errorf("expected reloaded policy to allow domain, got %v", err)
+	}
+
+	// A broken file keeps the last good rules
+	writePolicy(t, path, `{"deny": [`, time.Now().Add(time.Minute))
+	if err := policy.Check(ctx, "https://other.example/"); !errors.Is(err, ErrDestinationRejected) {
+		t.Errorf("expected previous rules to stay in force, got %v", err)
+	}
+}
+
+func TestNewFileDestinationPolicy_InvalidFileReturnsError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	writePolicy(t, path, `not json`, time.Now())
+
+	if _, err := NewFileDestinationPolicy(path, time.Minute); err == nil {
+		t.Error("expected error for invalid policy file, got nil")
+	}
+	if _, err := NewFileDestinationPolicy(filepath.Join(t.TempDir(), "missing.json"), time.Minute); err == nil {
+		t.Error("expected error for missing policy file, got nil")
+	}
+}
+
+func writePolicy(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write policy: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set policy mtime: %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// IDSequenceCheckInterval is how often the server verifies the ID sequence.
+const IDSequenceCheckInterval = 30 * time.Second
+
+var ErrIDSequenceUnverified = errors.New("link creation is unavailable: the ID sequence could not be verified")
+
+// IDSequenceGuard keeps the Redis ID sequence ahead of every ID recorded in
+// analytics. If Redis loses data, the sequence restarts low and new links would
+// reuse the IDs of links whose codes are still in circulation. Creates are
+// refused until a verification has succeeded, and again whenever one fails.
+type IDSequenceGuard struct {
+	cacheRepo    URLCacheRepo
+	analyticRepo URLAnalyticRepo
+	verified     atomic.Bool
+}
+
+func NewIDSequenceGuard(cacheRepo URLCacheRepo, analyticRepo URLAnalyticRepo) *IDSequenceGuard {
+	return &IDSequenceGuard{
+		cacheRepo:    cacheRepo,
+		analyticRepo: analyticRepo,
+	}
+}
+
+// Verify moves the sequence up to the highest recorded ID if it is behind, and
+// returns how many IDs it skipped.
+func (g *IDSequenceGuard) Verify(ctx context.Context) (int64, error) {
+	maxID, err := g.analyticRepo.MaxURLID(ctx)
+	if err != nil {
+		g.verified.Store(false)
+		return 0, err
+	}
+	previous, err := g.cacheRepo.AdvanceSequence(ctx, maxID)
+	if err != nil {
+		g.verified.Store(false)
+		return 0, err
+	}
+
+	g.verified.Store(true)
+	if previous < maxID {
+		return maxID - previous, nil
+	}
+	return 0, nil
+}
+
+// Check returns ErrIDSequenceUnverified unless the last verification succeeded.
+func (g *IDSequenceGuard) Check() error {
+	if !g.verified.Load() {
+		return ErrIDSequenceUnverified
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIDSequenceGuard_Verify(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+
+	tests := []struct {
+		name          string
+		maxID         int64
+		maxErr        error
+		previous      int64
+		advanceErr    error
+		expectSkipped int64
+		expectError   error
+	}{
+		{
+			name:     "sequence_ahead_is_kept",
+			maxID:    100,
+			previous: 120,
+		},
+		{
+			name:          "sequence_behind_is_advanced",
+			maxID:         100,
+			previous:      3,
+			expectSkipped: 97,
+		},
+		{
+			name:          "lost_sequence_is_restored",
+			maxID:         100,
+			expectSkipped: 100,
+		},
+		{
+			name:        "postgres_error_leaves_sequence_unverified",
+			maxErr:      errUnavailable,
+			expectError: errUnavailable,
+		},
+		{
+			name:        "redis_error_leaves_sequence_unverified",
+			maxID:       100,
+			advanceErr:  errUnavailable,
+			expectError: errUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+			analyticRepo.EXPECT().MaxURLID(gomock.Any()).Return(tt.maxID, tt.maxErr)
+			if tt.maxErr == nil {
+				cacheRepo.EXPECT().AdvanceSequence(gomock.Any(), tt.maxID).Return(tt.previous, tt.advanceErr)
+			}
+
+			guard := NewIDSequenceGuard(cacheRepo, analyticRepo)
+			skipped, err := guard.Verify(context.Background())
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if skipped != tt.expectSkipped {
+				t.Errorf("expected %d skipped IDs, got %d", tt.expectSkipped, skipped)
+			}
+
+			var expectCheck error
+			if tt.expectError != nil {
+				expectCheck = ErrIDSequenceUnverified
+			}
+			if err := guard.Check(); err != expectCheck {
+				t.Errorf("expected check %v, got %v", expectCheck, err)
+			}
+		})
+	}
+}
+
+func TestIDSequenceGuard_FailedVerificationRevokes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	gomock.InOrder(
+		analyticRepo.EXPECT().MaxURLID(gomock.Any()).Return(int64(10), nil),
+		analyticRepo.EXPECT().MaxURLID(gomock.Any()).Return(int64(0), errors.New("unavailable")),
+	)
+	cacheRepo.EXPECT().AdvanceSequence(gomock.Any(), int64(10)).Return(int64(10), nil)
+
+	guard := NewIDSequenceGuard(cacheRepo, analyticRepo)
+	if err := guard.Check(); err != ErrIDSequenceUnverified {
+		t.Errorf("expected an unchecked sequence to be unverified, got %v", err)
+	}
+	_, _ = guard.Verify(context.Background())
+	if err := guard.Check(); err != nil {
+		t.Errorf("expected verified sequence, got %v", err)
+	}
+	_, _ = guard.Verify(context.Background())
+	if err := guard.Check(); err != ErrIDSequenceUnverified {
+		t.Errorf("expected a failed check to revoke verification, got %v", err)
+	}
+}
+
+func TestLinkCreatorService_RefusesUnverifiedSequence(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// No ID is allocated until the sequence is verified
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	guard := NewIDSequenceGuard(cacheRepo, analyticRepo)
+
+	svc := NewLinkCreatorService(cacheRepo, analyticRepo, WithIDSequenceGuard(guard))
+	if _, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: "https://example.com"}); err != ErrIDSequenceUnverified {
+		t.Errorf("expected ErrIDSequenceUnverified, got %v", err)
+	}
+	if _, err := svc.CreateBatch(context.Background(), []entity.CreateLinkInput{{LongURL: "https://example.com"}}); err != ErrIDSequenceUnverified {
+		t.Errorf("expected ErrIDSequenceUnverified for a batch, got %v", err)
+	}
+}
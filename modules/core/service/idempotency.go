@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+)
+
+const (
+	// IdempotencyRetention is how long a completed response stays replayable.
+	IdempotencyRetention = 24 * time.Hour
+	// IdempotencyLockTTL bounds how long a crashed request can hold its key.
+	IdempotencyLockTTL   = 30 * time.Second
+	MaxIdempotencyKeyLen = 255
+)
+
+var (
+	ErrInvalidIdempotencyKey = errors.New("invalid idempotency key: must be 1-255 characters")
+	ErrIdempotencyInProgress = errors.New("a request with this idempotency key is still in progress")
+	ErrIdempotencyKeyReused  = errors.New("idempotency key was already used with a different request body")
+)
+
+type IdempotencyGuard interface {
+	Begin(ctx context.Context, key, fingerprint string) (*entity.IdempotencyRecord, error)
+	Complete(ctx context.Context, key, fingerprint string, statusCode int, body []byte) error
+	Abort(ctx context.Context, key string) error
+}
+
+type IdempotencyService struct {
+	repo IdempotencyRepo
+}
+
+func NewIdempotencyService(repo IdempotencyRepo) *IdempotencyService {
+	return &IdempotencyService{repo: repo}
+}
+
+// Begin claims the key for a request with the given body fingerprint.
+// It returns nil when the caller should run the request, or the stored record when
+// the request is a replay of a completed one. The claim is a Redis SET NX, so
+// concurrent retries landing on different tasks cannot both run the request.
+func (s *IdempotencyService) Begin(ctx context.Context, key, fingerprint string) (*entity.IdempotencyRecord, error) {
+	if key == "" || len(key) > MaxIdempotencyKeyLen {
+		return nil, ErrInvalidIdempotencyKey
+	}
+
+	key = idempotencyScope(ctx, key)
+	reserved, err := s.repo.Reserve(ctx, key, &entity.IdempotencyRecord{Fingerprint: fingerprint}, IdempotencyLockTTL)
+	if err != nil {
+		return nil, err
+	}
+	if reserved {
+		return nil, nil
+	}
+
+	record, err := s.repo.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if record == nil {
+		// The holder released or expired between our SET NX and GET; let the client retry
+		return nil, ErrIdempotencyInProgress
+	}
+	if record.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !record.Completed {
+		return nil, ErrIdempotencyInProgress
+	}
+
+	return record, nil
+}
+
+// Complete stores the final response so retries replay it for the retention window.
+func (s *IdempotencyService) Complete(ctx context.Context, key, fingerprint string, statusCode int, body []byte) error {
+	return s.repo.Complete(ctx, idempotencyScope(ctx, key), &entity.IdempotencyRecord{
+		Fingerprint: fingerprint,
+		Completed:   true,
+		StatusCode:  statusCode,
+		Body:        body,
+	}, IdempotencyRetention)
+}
+
+// Abort releases the key after a server-side failure so the client can retry.
+func (s *IdempotencyService) Abort(ctx context.Context, key string) error {
+	return s.repo.Release(ctx, idempotencyScope(ctx, key))
+}
+
+// idempotencyScope keeps each owner's keys apart, so one caller can never replay
+// another's response by reusing its key. Anonymous keys share one namespace. The
+// owner is hashed to a fixed width so no client-chosen key can name another scope.
+func idempotencyScope(ctx context.Context, key string) string {
+	if ownerID, ok := OwnerFromContext(ctx); ok {
+		sum := sha256.Sum256([]byte(ownerID))
+		return "owner:" + hex.EncodeToString(sum[:]) + ":" + key
+	}
+	return "anonymous:" + key
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestIdempotencyService_Begin(t *testing.T) {
+	completed := &entity.IdempotencyRecord{
+		Fingerprint: "fp-1",
+		Completed:   true,
+		StatusCode:  200,
+		Body:        []byte(`{"short_code":"abc"}`),
+	}
+
+	tests := []struct {
+		name         string
+		key          string
+		reserved     bool
+		stored       *entity.IdempotencyRecord
+		expectRepo   bool
+		expectRecord *entity.IdempotencyRecord
+		expectError  error
+	}{
+		{
+			name:       "first_request_acquires_key",
+			key:        "key-1",
+			reserved:   true,
+			expectRepo: true,
+		},
+		{
+			name:         "replay_with_same_body_returns_stored_record",
+			key:          "key-1",
+			stored:       completed,
+			expectRepo:   true,
+			expectRecord: completed,
+		},
+		{
+			name:        "replay_with_different_body_returns_error",
+			key:         "key-1",
+			stored:      &entity.IdempotencyRecord{Fingerprint: "fp-other", Completed: true},
+			expectRepo:  true,
+			expectError: ErrIdempotencyKeyReused,
+		},
+		{
+			name:        "concurrent_retry_returns_in_progress",
+			key:         "key-1",
+			stored:      &entity.IdempotencyRecord{Fingerprint: "fp-1"},
+			expectRepo:  true,
+			expectError: ErrIdempotencyInProgress,
+		},
+		{
+			name:        "key_released_between_reserve_and_get_returns_in_progress",
+			key:         "key-1",
+			expectRepo:  true,
+			expectError: ErrIdempotencyInProgress,
+		},
+		{
+			name:        "oversized_key_returns_error",
+			key:         strings.Repeat("k", MaxIdempotencyKeyLen+1),
+			expectError: ErrInvalidIdempotencyKey,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			repo := mocks.NewMockIdempotencyRepo(ctrl)
+			if tt.expectRepo {
+				repo.EXPECT().
+					Reserve(gomock.Any(), "anonymous:"+tt.key, &entity.IdempotencyRecord{Fingerprint: "fp-1"}, IdempotencyLockTTL).
+					Return(tt.reserved, nil)
+				if !tt.reserved {
+					repo.EXPECT().Get(gomock.Any(), "anonymous:"+tt.key).Return(tt.stored, nil)
+				}
+			}
+
+			svc := NewIdempotencyService(repo)
+			record, err := svc.Begin(context.Background(), tt.key, "fp-1")
+
+			if err != tt.expectError {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+			if record != tt.expectRecord {
+				t.Errorf("expected record %+v, got %+v", tt.expectRecord, record)
+			}
+		})
+	}
+}
+
+func TestIdempotencyService_Complete(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	repo := mocks.NewMockIdempotencyRepo(ctrl)
+	repo.EXPECT().
+		Complete(gomock.Any(), "anonymous:key-1", &entity.IdempotencyRecord{
+			Fingerprint: "fp-1",
+			Completed:   true,
+			StatusCode:  200,
+			Body:        []byte(`{}`),
+		}, IdempotencyRetention).
+		Return(nil)
+
+	svc := NewIdempotencyService(repo)
+	if err := svc.Complete(context.Background(), "key-1", "fp-1", 200, []byte(`{}`)); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestIdempotencyService_OwnerScope(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// The same client key lands in a different scope for each owner and for anonymous callers
+	var scopes []string
+	repo := mocks.NewMockIdempotencyRepo(ctrl)
+	repo.EXPECT().
+		Reserve(gomock.Any(), gomock.Any(), gomock.Any(), IdempotencyLockTTL).
+		DoAndReturn(func(_ context.Context, key string, _ *entity.IdempotencyRecord, _ time.Duration) (bool, error) {
+			scopes = append(scopes, key)
+			return true, nil
+		}).
+		Times(3)
+
+	svc := NewIdempotencyService(repo)
+	for _, ctx := range []context.Context{
+		context.Background(),
+		WithOwner(context.Background(), "alice"),
+		WithOwner(context.Background(), "bob"),
+	} {
+		if _, err := svc.Begin(ctx, "key-1", "fp-1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	seen := map[string]bool{}
+	for _, scope := range scopes {
+		if !strings.HasSuffix(scope, ":key-1") || seen[scope] {
+			t.Errorf("expected distinct scopes for key-1, got %v", scopes)
+			break
+		}
+		seen[scope] = true
+	}
+}
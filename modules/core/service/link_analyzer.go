@@ -15,27 +15,56 @@ type LinkAnalyzer interface {
 
 type LinkAnalyzerService struct {
 	analyticRepo URLAnalyticRepo
+	codes        *lib.CodeSet
 }
 
-func NewLinkAnalyzerService(analyticRepo URLAnalyticRepo) *LinkAnalyzerService {
+func NewLinkAnalyzerService(analyticRepo URLAnalyticRepo, codes *lib.CodeSet) *LinkAnalyzerService {
 	return &LinkAnalyzerService{
 		analyticRepo: analyticRepo,
+		codes:        codes,
 	}
 }
 
+// Analyze returns the statistics of a link. Owned links are only shown to their owner.
 func (s *LinkAnalyzerService) Analyze(ctx context.Context, shortCode string) (*entity.URLAnalytic, error) {
-	id, err := lib.HexDecode(shortCode)
+	analytic, _, err := lookupAnalytic(ctx, s.codes, s.analyticRepo, shortCode)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
+	}
+	if err := authorizeOwner(ctx, analytic); err != nil {
+		return nil, err
 	}
+	return analytic, nil
+}
 
-	analytic, err := s.analyticRepo.GetByURLID(ctx, id)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
+// lookupAnalytic fetches the analytics row for a generated code or a vanity alias,
+// and returns the spelling of the code it was found under. Unlike resolveShortCode
+// it does not need the link to be live in Redis.
+func lookupAnalytic(ctx context.Context, codes *lib.CodeSet, analyticRepo URLAnalyticRepo, shortCode string) (*entity.URLAnalytic, string, error) {
+	for _, spelling := range shortCodeSpellings(codes, shortCode) {
+		analytic, err := findAnalytic(ctx, codes, analyticRepo, spelling)
+		// ErrBadChecksum wraps ErrNotFound but is final, so only a plain miss moves on
+		if errors.Is(err, sql.ErrNoRows) || err == ErrNotFound {
+			continue
 		}
-		return nil, err
+		if err != nil {
+			return nil, "", err
+		}
+		return analytic, spelling, nil
 	}
+	return nil, "", ErrNotFound
+}
 
-	return analytic, nil
+func findAnalytic(ctx context.Context, codes *lib.CodeSet, analyticRepo URLAnalyticRepo, shortCode string) (*entity.URLAnalytic, error) {
+	if id, generated, err := codes.Decode(shortCode); generated {
+		if err != nil {
+			return nil, decodeError(err)
+		}
+		return analyticRepo.GetByURLID(ctx, id)
+	}
+
+	if !lib.IsValidAlias(shortCode) {
+		return nil, ErrNotFound
+	}
+	return analyticRepo.GetByAlias(ctx, shortCode)
 }
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -74,26 +75,30 @@ func TestLinkAnalyzerService(t *testing.T) {
 				urlID = int64(1)
 
 				cacheRepo.EXPECT().
-					Create(gomock.Any(), *tt.setupURL, DefaultTTL).
+					Create(gomock.Any(), linkTo(*tt.setupURL, DefaultTTL)).
 					Return(urlID, nil)
 
 				analyticRepo.EXPECT().
 					Create(gomock.Any(), gomock.Any()).
 					Return(int64(1), nil)
 
+				cacheRepo.EXPECT().
+					Confirm(gomock.Any(), urlID).
+					Return(nil)
+
 				creatorSvc := NewLinkCreatorService(cacheRepo, analyticRepo)
-				var err error
-				shortCode, err = creatorSvc.Create(ctx, *tt.setupURL, nil)
+				link, err := creatorSvc.Create(ctx, entity.CreateLinkInput{LongURL: *tt.setupURL})
 				if err != nil {
 					t.Fatalf("setup failed: %v", err)
 				}
+				shortCode = link.ShortCode
 			}
 
 			// Setup: Perform redirects
 			if tt.redirectCount > 0 && tt.setupURL != nil {
 				cacheRepo.EXPECT().
 					Get(gomock.Any(), urlID).
-					Return(*tt.setupURL, nil).
+					Return(&entity.URL{ID: urlID, LongURL: *tt.setupURL}, nil).
 					Times(tt.redirectCount)
 
 				analyticRepo.EXPECT().
@@ -101,9 +106,9 @@ func TestLinkAnalyzerService(t *testing.T) {
 					Return(nil).
 					Times(tt.redirectCount)
 
-				redirectorSvc := NewLinkRedirectorService(cacheRepo, analyticRepo)
+				redirectorSvc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet())
 				for i := 0; i < tt.redirectCount; i++ {
-					_, _ = redirectorSvc.Redirect(ctx, shortCode)
+					_, _ = redirectorSvc.Redirect(ctx, shortCode, "")
 				}
 
 				// Wait for async updates
@@ -148,7 +153,7 @@ func TestLinkAnalyzerService(t *testing.T) {
 				// If decode fails, no GetByURLID call will be made
 			}
 
-			analyzerSvc := NewLinkAnalyzerService(analyticRepo)
+			analyzerSvc := NewLinkAnalyzerService(analyticRepo, lib.DefaultCodeSet())
 			analytic, err := analyzerSvc.Analyze(ctx, shortCode)
 
 			if tt.expectError != nil {
@@ -178,3 +183,54 @@ func TestLinkAnalyzerService(t *testing.T) {
 		})
 	}
 }
+
+func TestLinkAnalyzerService_Alias(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	analyticRepo.EXPECT().
+		GetByAlias(gomock.Any(), "spring-promo").
+		Return(&entity.URLAnalytic{URLID: 7, Alias: ptr("spring-promo"), LongURL: "https://example.com/promo"}, nil)
+	analyticRepo.EXPECT().
+		GetByAlias(gomock.Any(), "unknown-promo").
+		Return(nil, sql.ErrNoRows)
+
+	svc := NewLinkAnalyzerService(analyticRepo, lib.DefaultCodeSet())
+
+	analytic, err := svc.Analyze(context.Background(), "spring-promo")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if analytic.URLID != 7 {
+		t.Errorf("expected url id 7, got %d", analytic.URLID)
+	}
+
+	if _, err := svc.Analyze(context.Background(), "unknown-promo"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestLinkAnalyzerService_Checksum(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	codec, err := lib.NewKeyedCodec(1, []byte("0123456789abcdef"), 2)
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+	code := codec.Encode(7)
+
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(7)).Return(&entity.URLAnalytic{URLID: 7}, nil)
+
+	svc := NewLinkAnalyzerService(analyticRepo, lib.NewCodeSet(codec))
+	if _, err := svc.Analyze(context.Background(), code); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A mistyped check character is refused without a lookup
+	if _, err := svc.Analyze(context.Background(), code[:len(code)-1]+mistype(code[len(code)-1])); !errors.Is(err, ErrBadChecksum) {
+		t.Errorf("expected ErrBadChecksum, got %v", err)
+	}
+}
@@ -15,70 +15,550 @@ const (
 	MinTTL     = 1 * time.Hour
 	MaxTTL     = 7 * 24 * time.Hour
 	MaxURLLen  = 2048
+
+	MaxBatchSize = 1000
+
+	// MaxCodeAttempts bounds how many IDs are allocated for one link while their
+	// codes contain blocked words.
+	MaxCodeAttempts = 10
 )
 
 var (
 	ErrInvalidURL = errors.New("invalid URL: must be a valid HTTP or HTTPS URL")
 	ErrURLTooLong = errors.New("URL too long: maximum length is 2048 characters")
 	ErrInvalidTTL = errors.New("invalid TTL: must be between 1 hour and 1 week")
+
+	ErrConflictingExpiry = errors.New("invalid expiry: ttl_seconds and expires_at cannot both be set")
+	ErrBatchNotBefore    = errors.New("invalid not_before: scheduled activation is not supported in batches")
+	ErrNotBeforeTooLate  = errors.New("invalid not_before: a scheduled link must expire within 1 week of creation")
+
+	ErrInvalidMaxClicks = errors.New("invalid max_clicks: must be a positive integer")
+	ErrBatchMaxClicks   = errors.New("invalid max_clicks: click limits are not supported in batches")
+
+	ErrInvalidAlias = errors.New("invalid alias: must be 3-64 readable characters and not a generated code")
+	ErrBlockedAlias = errors.New("invalid alias: contains a blocked word")
+	ErrAliasTaken   = errors.New("alias is already in use")
+
+	ErrNoAllowedCode = errors.New("no short code outside the blocklist could be allocated")
+
+	ErrInvalidBatchSize = errors.New("invalid batch: must contain between 1 and 1000 items")
 )
 
 type LinkCreator interface {
-	Create(ctx context.Context, longURL string, ttlSeconds *int64) (string, error)
+	Create(ctx context.Context, input entity.CreateLinkInput) (*entity.Link, error)
+	CreateBatch(ctx context.Context, inputs []entity.CreateLinkInput) ([]entity.BatchLinkResult, error)
 }
 
 type LinkCreatorService struct {
-	cacheRepo    URLCacheRepo
-	analyticRepo URLAnalyticRepo
+	cacheRepo       URLCacheRepo
+	analyticRepo    URLAnalyticRepo
+	policy          DestinationPolicy
+	codes           *lib.CodeSet
+	blocklist       *lib.Blocklist
+	codeBlocklist   *lib.Blocklist
+	sequence        *IDSequenceGuard
+	dedupeByDefault bool
+
+	// shortLinkHosts are the normalized hosts this service answers on
+	shortLinkHosts map[string]bool
+	selfLinkMode   SelfLinkMode
+}
+
+// LinkCreatorOption configures optional LinkCreatorService behaviour.
+type LinkCreatorOption func(*LinkCreatorService)
+
+// WithDedupeByDefault makes Create reuse an active link to the same destination
+// unless the input explicitly opts out.
+func WithDedupeByDefault(enabled bool) LinkCreatorOption {
+	return func(s *LinkCreatorService) {
+		s.dedupeByDefault = enabled
+	}
+}
+
+// WithCodeSet replaces the default code set, which encodes hex codes.
+func WithCodeSet(codes *lib.CodeSet) LinkCreatorOption {
+	return func(s *LinkCreatorService) {
+		s.codes = codes
+	}
+}
+
+// WithBlocklist replaces the default blocklist of generated codes and aliases,
+// which holds lib.DefaultBlockedWords.
+func WithBlocklist(blocklist *lib.Blocklist) LinkCreatorOption {
+	return func(s *LinkCreatorService) {
+		s.blocklist = blocklist
+		s.codeBlocklist = blocklist
+	}
+}
+
+// WithCodeBlocklist replaces the blocklist of generated codes only. By default
+// it holds lib.DefaultCodeWords for the alphabet of the code set.
+func WithCodeBlocklist(blocklist *lib.Blocklist) LinkCreatorOption {
+	return func(s *LinkCreatorService) {
+		s.codeBlocklist = blocklist
+	}
+}
+
+// WithIDSequenceGuard refuses creates while the guard has not verified the ID sequence.
+func WithIDSequenceGuard(guard *IDSequenceGuard) LinkCreatorOption {
+	return func(s *LinkCreatorService) {
+		s.sequence = guard
+	}
+}
+
+// WithDestinationPolicy replaces the default policy, which only refuses internal hosts.
+func WithDestinationPolicy(policy DestinationPolicy) LinkCreatorOption {
+	return func(s *LinkCreatorService) {
+		s.policy = policy
+	}
 }
 
 func NewLinkCreatorService(
 	cacheRepo URLCacheRepo,
 	analyticRepo URLAnalyticRepo,
+	opts ...LinkCreatorOption,
 ) *LinkCreatorService {
-	return &LinkCreatorService{
+	s := &LinkCreatorService{
 		cacheRepo:    cacheRepo,
 		analyticRepo: analyticRepo,
+		policy:       &DestinationRules{},
+		codes:        lib.DefaultCodeSet(),
+		blocklist:    lib.NewBlocklist(lib.DefaultBlockedWords),
 	}
-}
-
-func (s *LinkCreatorService) Create(ctx context.Context, longURL string, ttlSeconds *int64) (string, error) {
-	if err := validateURL(longURL); err != nil {
-		return "", err
+	for _, opt := range opts {
+		opt(s)
 	}
-
-	ttl := DefaultTTL
-	if ttlSeconds != nil {
-		ttl = time.Duration(*ttlSeconds) * time.Second
-		if ttl < MinTTL || ttl > MaxTTL {
-			return "", ErrInvalidTTL
-		}
+	if s.codeBlocklist == nil {
+		s.codeBlocklist = lib.NewBlocklist(lib.DefaultCodeWords(s.codes.Alphabet()))
 	}
+	return s
+}
 
+func (s *LinkCreatorService) Create(ctx context.Context, input entity.CreateLinkInput) (*entity.Link, error) {
 	now := time.Now()
-	expiresAt := now.Add(ttl)
+	link, err := s.validate(ctx, input, now)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkSequence(); err != nil {
+		return nil, err
+	}
 
 	// Create URL in Redis cache with TTL
-	id, err := s.cacheRepo.Create(ctx, longURL, ttl)
+	id, reused, err := s.store(ctx, input, link)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	// A reused link already has its analytics record
+	if reused {
+		return s.existingLink(ctx, id)
 	}
 
 	// Create analytics record in PostgreSQL
 	analyticEntity := &entity.URLAnalytic{
 		URLID:      id,
-		LongURL:    longURL,
+		Alias:      input.Alias,
+		LongURL:    link.LongURL,
 		CreatedAt:  now,
-		ExpiresAt:  expiresAt,
+		ExpiresAt:  link.ExpiresAt,
+		NotBefore:  link.NotBefore,
+		MaxClicks:  link.MaxClicks,
 		ClickCount: 0,
+		OwnerID:    ownerOf(ctx),
+
+		PasswordHash: &link.PasswordHash,
 	}
 
 	_, err = s.analyticRepo.Create(ctx, analyticEntity)
 	if err != nil {
+		s.discard(ctx, id)
+		return nil, err
+	}
+	s.confirm(ctx, id)
+
+	return &entity.Link{
+		ID:        id,
+		ShortCode: s.shortCodeFor(id, input.Alias),
+		LongURL:   link.LongURL,
+		CreatedAt: now,
+		ExpiresAt: link.ExpiresAt,
+		NotBefore: link.NotBefore,
+		MaxClicks: link.MaxClicks,
+
+		PasswordProtected: link.PasswordHash != "",
+	}, nil
+}
+
+// existingLink loads the record of a reused link so the caller sees its real timestamps.
+func (s *LinkCreatorService) existingLink(ctx context.Context, id int64) (*entity.Link, error) {
+	analytic, err := s.analyticRepo.GetByURLID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.Link{
+		ID:        id,
+		ShortCode: s.shortCodeFor(id, analytic.Alias),
+		LongURL:   analytic.LongURL,
+		CreatedAt: analytic.CreatedAt,
+		ExpiresAt: analytic.ExpiresAt,
+		NotBefore: analytic.NotBefore,
+		MaxClicks: analytic.MaxClicks,
+		Reused:    true,
+	}, nil
+}
+
+// shortCodeFor returns the public code of a link: its alias if it has one, otherwise the encoded ID.
+func (s *LinkCreatorService) shortCodeFor(id int64, alias *string) string {
+	if alias != nil {
+		return *alias
+	}
+	return s.codes.Encode(id)
+}
+
+// store allocates an ID and writes the mapping. Aliased links reserve their alias;
+// deduplicated links may return an existing ID, reported by the second result.
+func (s *LinkCreatorService) store(ctx context.Context, input entity.CreateLinkInput, link *entity.URL) (int64, bool, error) {
+	if input.Alias != nil {
+		id, reserved, err := s.cacheRepo.CreateWithAlias(ctx, *input.Alias, link)
+		if err != nil {
+			return 0, false, err
+		}
+		if !reserved {
+			return 0, false, ErrAliasTaken
+		}
+		return id, false, nil
+	}
+
+	if s.shouldDedupe(ctx, input, link) {
+		return s.allocate(ctx, func() (int64, bool, error) {
+			return s.cacheRepo.CreateDeduped(ctx, link)
+		})
+	}
+
+	return s.allocate(ctx, func() (int64, bool, error) {
+		id, err := s.cacheRepo.Create(ctx, link)
+		return id, false, err
+	})
+}
+
+// checkSequence refuses to allocate IDs from a sequence that may reuse old ones.
+func (s *LinkCreatorService) checkSequence() error {
+	if s.sequence == nil {
+		return nil
+	}
+	return s.sequence.Check()
+}
+
+// discard removes a new link whose analytics record could not be written, so it
+// never redirects without one. It runs even if ctx was cancelled. A link it fails
+// to remove stays pending and is removed by the LinkReconcilerService.
+func (s *LinkCreatorService) discard(ctx context.Context, id int64) {
+	_, _ = s.cacheRepo.Discard(context.WithoutCancel(ctx), id)
+}
+
+// confirm clears the pending mark of links whose analytics records were written.
+// The links already work, so a failure is left to the LinkReconcilerService,
+// which finds the records and confirms them later.
+func (s *LinkCreatorService) confirm(ctx context.Context, ids ...int64) {
+	_ = s.cacheRepo.Confirm(context.WithoutCancel(ctx), ids...)
+}
+
+// allocate stores a link with create until its ID encodes to a code free of blocked
+// words. Each blocked ID is removed again and skipped; after MaxCodeAttempts IDs
+// it gives up. Reused links were vetted when they were created.
+func (s *LinkCreatorService) allocate(ctx context.Context, create func() (int64, bool, error)) (int64, bool, error) {
+	for attempt := 1; ; attempt++ {
+		id, reused, err := create()
+		if err != nil || reused || !s.codeBlocklist.Blocks(s.codes.Encode(id)) {
+			return id, reused, err
+		}
+		if err := s.cacheRepo.Delete(ctx, id); err != nil {
+			return 0, false, err
+		}
+		if attempt == MaxCodeAttempts {
+			return 0, false, ErrNoAllowedCode
+		}
+	}
+}
+
+// replaceBlocked moves batch links whose IDs encode to blocked codes onto new IDs.
+func (s *LinkCreatorService) replaceBlocked(ctx context.Context, urls []*entity.URL) error {
+	for _, u := range urls {
+		if !s.codeBlocklist.Blocks(s.codes.Encode(u.ID)) {
+			continue
+		}
+		if err := s.cacheRepo.Delete(ctx, u.ID); err != nil {
+			return err
+		}
+		id, _, err := s.allocate(ctx, func() (int64, bool, error) {
+			id, err := s.cacheRepo.Create(ctx, u)
+			return id, false, err
+		})
+		if err != nil {
+			return err
+		}
+		u.ID = id
+	}
+	return nil
+}
+
+// shouldDedupe reports whether to reuse an active link. Restricted links never dedupe,
+// since a reused link would bypass or share their restrictions. Neither do links
+// created with an API key, which must not be handed a link owned by someone else.
+func (s *LinkCreatorService) shouldDedupe(ctx context.Context, input entity.CreateLinkInput, link *entity.URL) bool {
+	if isRestricted(link) {
+		return false
+	}
+	if _, owned := OwnerFromContext(ctx); owned {
+		return false
+	}
+	if input.Dedupe != nil {
+		return *input.Dedupe
+	}
+	return s.dedupeByDefault
+}
+
+// isRestricted reports whether a link is scheduled, click-limited or password protected.
+func isRestricted(link *entity.URL) bool {
+	return link.NotBefore != nil || link.MaxClicks != nil || link.PasswordHash != ""
+}
+
+// CreateBatch creates many links with one ID range allocation and one analytics insert.
+// Validation failures are reported per item; only storage failures fail the whole batch.
+// Aliases, scheduled activation, click limits, passwords and deduplication are not supported in batches.
+// Destination policy rejections are reported per item like validation failures.
+func (s *LinkCreatorService) CreateBatch(ctx context.Context, inputs []entity.CreateLinkInput) ([]entity.BatchLinkResult, error) {
+	if len(inputs) == 0 || len(inputs) > MaxBatchSize {
+		return nil, ErrInvalidBatchSize
+	}
+
+	now := time.Now()
+	results, urls, positions, err := s.validateBatch(ctx, inputs, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(urls) == 0 {
+		return results, nil
+	}
+
+	if err := s.allocateBatch(ctx, urls); err != nil {
+		return nil, err
+	}
+	if err := s.recordBatch(ctx, urls, now); err != nil {
+		return nil, err
+	}
+
+	for i, u := range urls {
+		results[positions[i]].ShortCode = s.codes.Encode(u.ID)
+	}
+	return results, nil
+}
+
+// validateBatch validates each input of a batch. Input errors are reported in the
+// results; the links that passed are returned with their positions in inputs.
+func (s *LinkCreatorService) validateBatch(ctx context.Context, inputs []entity.CreateLinkInput, now time.Time) ([]entity.BatchLinkResult, []*entity.URL, []int, error) {
+	results := make([]entity.BatchLinkResult, len(inputs))
+	urls := make([]*entity.URL, 0, len(inputs))
+	positions := make([]int, 0, len(inputs))
+
+	for i, input := range inputs {
+		if err := unsupportedInBatch(input); err != nil {
+			results[i].Err = err
+			continue
+		}
+		link, err := s.validate(ctx, input, now)
+		if err != nil {
+			if !isInputError(err) {
+				return nil, nil, nil, err
+			}
+			results[i].Err = err
+			continue
+		}
+		urls = append(urls, link)
+		positions = append(positions, i)
+	}
+	return results, urls, positions, nil
+}
+
+// allocateBatch stores the links of a batch under one ID range, then moves those
+// whose codes would contain a blocked word to new IDs.
+func (s *LinkCreatorService) allocateBatch(ctx context.Context, urls []*entity.URL) error {
+	if err := s.checkSequence(); err != nil {
+		return err
+	}
+	if err := s.cacheRepo.CreateBatch(ctx, urls); err != nil {
+		return err
+	}
+	return s.replaceBlocked(ctx, urls)
+}
+
+// recordBatch writes the analytics rows of a batch in one insert and confirms the
+// links, or discards them all from Redis if the insert fails.
+func (s *LinkCreatorService) recordBatch(ctx context.Context, urls []*entity.URL, now time.Time) error {
+	analytics := make([]*entity.URLAnalytic, len(urls))
+	ids := make([]int64, len(urls))
+	ownerID := ownerOf(ctx)
+	for i, u := range urls {
+		analytics[i] = &entity.URLAnalytic{
+			URLID:     u.ID,
+			LongURL:   u.LongURL,
+			CreatedAt: now,
+			ExpiresAt: u.ExpiresAt,
+			OwnerID:   ownerID,
+		}
+		ids[i] = u.ID
+	}
+
+	if err := s.analyticRepo.CreateBatch(ctx, analytics); err != nil {
+		for _, id := range ids {
+			s.discard(ctx, id)
+		}
+		return err
+	}
+	s.confirm(ctx, ids...)
+	return nil
+}
+
+// unsupportedInBatch refuses the per-link options a batch cannot honour.
+func unsupportedInBatch(input entity.CreateLinkInput) error {
+	switch {
+	case input.Alias != nil:
+		return ErrInvalidAlias
+	case input.NotBefore != nil:
+		return ErrBatchNotBefore
+	case input.MaxClicks != nil:
+		return ErrBatchMaxClicks
+	case input.Password != nil:
+		return ErrBatchPassword
+	}
+	return nil
+}
+
+// validate checks the input and returns the link to store. The password is hashed
+// last, once everything else has passed.
+func (s *LinkCreatorService) validate(ctx context.Context, input entity.CreateLinkInput, now time.Time) (*entity.URL, error) {
+	link, err := validateInput(input, now)
+	if err != nil {
+		return nil, err
+	}
+	if input.Alias != nil && s.blocklist.Blocks(*input.Alias) {
+		return nil, ErrBlockedAlias
+	}
+	link.LongURL, err = s.checkDestination(ctx, link.LongURL)
+	if err != nil {
+		return nil, err
+	}
+	link.PasswordHash, err = hashPassword(input.Password)
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// checkDestination flattens a canonical destination that is one of our own short links
+// and applies the destination policy to the result, which it returns.
+func (s *LinkCreatorService) checkDestination(ctx context.Context, longURL string) (string, error) {
+	longURL, err := s.followShortLinks(ctx, longURL)
+	if err != nil {
+		return "", err
+	}
+	if err := s.policy.Check(ctx, longURL); err != nil {
+		return "", err
+	}
+	return longURL, nil
+}
+
+// isInputError reports whether err refuses a single input rather than signalling a
+// failure of the policy or storage behind it.
+func isInputError(err error) bool {
+	for _, target := range []error{
+		ErrInvalidURL, ErrURLTooLong, ErrInvalidTTL, ErrConflictingExpiry, ErrBatchNotBefore, ErrNotBeforeTooLate,
+		ErrInvalidMaxClicks, ErrBatchMaxClicks, ErrInvalidPassword, ErrBatchPassword, ErrInvalidAlias, ErrBlockedAlias, ErrDestinationRejected,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// validateInput checks the URL, alias, click limit, password and activation window of a create request and
+// returns the link to store with its canonical URL and absolute bounds.
+func validateInput(input entity.CreateLinkInput, now time.Time) (*entity.URL, error) {
+	longURL, err := canonicalURL(input.LongURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Alias != nil && !lib.IsValidAlias(*input.Alias) {
+		return nil, ErrInvalidAlias
+	}
+
+	if input.MaxClicks != nil && *input.MaxClicks < 1 {
+		return nil, ErrInvalidMaxClicks
+	}
+	if err := validatePassword(input.Password); err != nil {
+		return nil, err
+	}
+
+	notBefore, expiresAt, err := activeWindow(input, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entity.URL{LongURL: longURL, ExpiresAt: expiresAt, NotBefore: notBefore, MaxClicks: input.MaxClicks}, nil
+}
+
+// activeWindow resolves when a link starts and stops redirecting. The link starts at
+// not_before, or now if that is unset or already past, and ends at expires_at or
+// after the TTL. MinTTL and MaxTTL bound the length of that window, and MaxTTL also bounds
+// how far from now it may end, so scheduling cannot stretch a link's lifetime.
+func activeWindow(input entity.CreateLinkInput, now time.Time) (*time.Time, time.Time, error) {
+	if input.TTLSeconds != nil && input.ExpiresAt != nil {
+		return nil, time.Time{}, ErrConflictingExpiry
+	}
+
+	start := now
+	var notBefore *time.Time
+	if input.NotBefore != nil && input.NotBefore.After(now) {
+		start = *input.NotBefore
+		notBefore = &start
+	}
+
+	end := start.Add(DefaultTTL)
+	switch {
+	case input.ExpiresAt != nil:
+		end = *input.ExpiresAt
+	case input.TTLSeconds != nil:
+		end = start.Add(time.Duration(*input.TTLSeconds) * time.Second)
+	}
+
+	if window := end.Sub(start); window < MinTTL || window > MaxTTL {
+		return nil, time.Time{}, ErrInvalidTTL
+	}
+	if end.Sub(now) > MaxTTL {
+		return nil, time.Time{}, ErrNotBeforeTooLate
+	}
+	return notBefore, end, nil
+}
+
+// canonicalURL validates a long URL and returns its canonical form.
+func canonicalURL(rawURL string) (string, error) {
+	if err := validateURL(rawURL); err != nil {
 		return "", err
 	}
 
-	return lib.HexEncode(id), nil
+	// Canonicalize so equivalent spellings share one stored form
+	longURL, err := lib.CanonicalizeURL(rawURL)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	if len(longURL) > MaxURLLen {
+		return "", ErrURLTooLong
+	}
+	return longURL, nil
 }
 
 func validateURL(rawURL string) error {
@@ -2,13 +2,34 @@ package service
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/nanda/doit/modules/core/entity"
 	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/lib"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// linkTo matches a link passed to the cache by destination and remaining lifetime.
+func linkTo(longURL string, ttl time.Duration) gomock.Matcher {
+	return gomock.Cond(func(link *entity.URL) bool {
+		remaining := time.Until(link.ExpiresAt)
+		return link.LongURL == longURL && remaining <= ttl && remaining > ttl-time.Minute
+	})
+}
+
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
 func TestLinkCreatorService(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -79,7 +100,7 @@ func TestLinkCreatorService(t *testing.T) {
 			// Only expect repository calls if no validation error is expected
 			if tt.expectError == nil {
 				mockCacheRepo.EXPECT().
-					Create(gomock.Any(), gomock.Any(), gomock.Any()).
+					Create(gomock.Any(), gomock.Any()).
 					Return(int64(1), nil).
 					Times(1)
 
@@ -87,12 +108,21 @@ func TestLinkCreatorService(t *testing.T) {
 					Create(gomock.Any(), gomock.Any()).
 					Return(int64(1), nil).
 					Times(1)
+
+				mockCacheRepo.EXPECT().
+					Confirm(gomock.Any(), int64(1)).
+					Return(nil).
+					Times(1)
 			}
 
 			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
 			ctx := context.Background()
 
-			shortCode, err := svc.Create(ctx, tt.inputURL, tt.inputTTL)
+			link, err := svc.Create(ctx, entity.CreateLinkInput{LongURL: tt.inputURL, TTLSeconds: tt.inputTTL})
+			var shortCode string
+			if link != nil {
+				shortCode = link.ShortCode
+			}
 
 			if tt.expectError != nil {
 				if err != tt.expectError {
@@ -120,3 +150,881 @@ func TestLinkCreatorService(t *testing.T) {
 		})
 	}
 }
+
+func TestLinkCreatorService_Alias(t *testing.T) {
+	tests := []struct {
+		name            string
+		alias           string
+		reserved        bool
+		expectRepoCalls bool
+		expectError     error
+		expectShortCode string
+	}{
+		{
+			name:            "valid_alias_is_returned_as_short_code",
+			alias:           "spring-promo",
+			reserved:        true,
+			expectRepoCalls: true,
+			expectShortCode: "spring-promo",
+		},
+		{
+			name:            "taken_alias_returns_error",
+			alias:           "spring-promo",
+			reserved:        false,
+			expectRepoCalls: true,
+			expectError:     ErrAliasTaken,
+		},
+		{
+			name:        "alias_that_looks_like_generated_code_returns_error",
+			alias:       "cafe",
+			expectError: ErrInvalidAlias,
+		},
+		{
+			name:        "alias_with_ambiguous_char_returns_error",
+			alias:       "sale-l0",
+			expectError: ErrInvalidAlias,
+		},
+		{
+			name:        "alias_with_uppercase_returns_error",
+			alias:       "Spring",
+			expectError: ErrInvalidAlias,
+		},
+		{
+			name:        "alias_too_short_returns_error",
+			alias:       "xy",
+			expectError: ErrInvalidAlias,
+		},
+		{
+			name:        "alias_with_blocked_word_returns_error",
+			alias:       "big-5hit-promo",
+			expectError: ErrBlockedAlias,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			if tt.expectRepoCalls {
+				id := int64(0)
+				if tt.reserved {
+					id = 42
+				}
+				mockCacheRepo.EXPECT().
+					CreateWithAlias(gomock.Any(), tt.alias, linkTo("https://example.com", DefaultTTL)).
+					Return(id, tt.reserved, nil).
+					Times(1)
+			}
+			if tt.reserved {
+				mockAnalyticRepo.EXPECT().
+					Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, a *entity.URLAnalytic) (int64, error) {
+						if a.Alias == nil || *a.Alias != tt.alias {
+							t.Errorf("expected analytic alias %s, got %v", tt.alias, a.Alias)
+						}
+						return 1, nil
+					}).
+					Times(1)
+				mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(42)).Return(nil)
+			}
+
+			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+			link, err := svc.Create(context.Background(), entity.CreateLinkInput{
+				LongURL: "https://example.com",
+				Alias:   ptr(tt.alias),
+			})
+			var shortCode string
+			if link != nil {
+				shortCode = link.ShortCode
+			}
+
+			if err != tt.expectError {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+			if shortCode != tt.expectShortCode {
+				t.Errorf("expected short code %q, got %q", tt.expectShortCode, shortCode)
+			}
+		})
+	}
+}
+
+func TestLinkCreatorService_CreateBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	mockCacheRepo.EXPECT().
+		CreateBatch(gomock.Any(), gomock.Len(2)).
+		DoAndReturn(func(_ context.Context, urls []*entity.URL) error {
+			for i, u := range urls {
+				u.ID = int64(100 + i)
+			}
+			return nil
+		}).
+		Times(1)
+	mockAnalyticRepo.EXPECT().
+		CreateBatch(gomock.Any(), gomock.Len(2)).
+		Return(nil).
+		Times(1)
+	mockCacheRepo.EXPECT().
+		Confirm(gomock.Any(), int64(100), int64(101)).
+		Return(nil).
+		Times(1)
+
+	svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+	results, err := svc.CreateBatch(context.Background(), []entity.CreateLinkInput{
+		{LongURL: "https://example.com/a"},
+		{LongURL: "ftp://example.com"},
+		{LongURL: "https://example.com/b", TTLSeconds: ptr(int64(7200))},
+		{LongURL: "https://example.com/c", TTLSeconds: ptr(int64(60))},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []entity.BatchLinkResult{
+		{ShortCode: lib.HexEncode(100)},
+		{Err: ErrInvalidURL},
+		{ShortCode: lib.HexEncode(101)},
+		{Err: ErrInvalidTTL},
+	}
+	for i, want := range expected {
+		if results[i] != want {
+			t.Errorf("item %d: expected %+v, got %+v", i, want, results[i])
+		}
+	}
+}
+
+func TestLinkCreatorService_CreateBatchRejectsInvalidSize(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewLinkCreatorService(mocks.NewMockURLCacheRepo(ctrl), mocks.NewMockURLAnalyticRepo(ctrl))
+
+	if _, err := svc.CreateBatch(context.Background(), nil); err != ErrInvalidBatchSize {
+		t.Errorf("expected ErrInvalidBatchSize for empty batch, got %v", err)
+	}
+
+	tooMany := make([]entity.CreateLinkInput, MaxBatchSize+1)
+	if _, err := svc.CreateBatch(context.Background(), tooMany); err != ErrInvalidBatchSize {
+		t.Errorf("expected ErrInvalidBatchSize for oversized batch, got %v", err)
+	}
+}
+
+func TestLinkCreatorService_DiscardsLinkWithoutRecord(t *testing.T) {
+	errInsert := errors.New("insert failed")
+
+	t.Run("create", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+		mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+		mockCacheRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(4), nil)
+		mockAnalyticRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0), errInsert)
+		mockCacheRepo.EXPECT().Discard(gomock.Any(), int64(4)).Return(true, nil)
+
+		svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+		if _, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: "https://example.com"}); !errors.Is(err, errInsert) {
+			t.Errorf("expected insert error, got %v", err)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+		mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+		mockCacheRepo.EXPECT().
+			CreateBatch(gomock.Any(), gomock.Len(2)).
+			DoAndReturn(func(_ context.Context, urls []*entity.URL) error {
+				urls[0].ID, urls[1].ID = 4, 5
+				return nil
+			})
+		mockAnalyticRepo.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).Return(errInsert)
+		// A failed cleanup is left to the reconciler and does not change the error
+		mockCacheRepo.EXPECT().Discard(gomock.Any(), int64(4)).Return(false, errors.New("redis unavailable"))
+		mockCacheRepo.EXPECT().Discard(gomock.Any(), int64(5)).Return(true, nil)
+
+		svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+		_, err := svc.CreateBatch(context.Background(), []entity.CreateLinkInput{
+			{LongURL: "https://example.com/a"},
+			{LongURL: "https://example.com/b"},
+		})
+		if !errors.Is(err, errInsert) {
+			t.Errorf("expected insert error, got %v", err)
+		}
+	})
+}
+
+func TestLinkCreatorService_CreateBatchSkipsStorageWhenAllInvalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewLinkCreatorService(mocks.NewMockURLCacheRepo(ctrl), mocks.NewMockURLAnalyticRepo(ctrl))
+
+	results, err := svc.CreateBatch(context.Background(), []entity.CreateLinkInput{
+		{LongURL: "not-a-url"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Err != ErrInvalidURL {
+		t.Errorf("expected ErrInvalidURL, got %v", results[0].Err)
+	}
+}
+
+func TestLinkCreatorService_Dedupe(t *testing.T) {
+	tests := []struct {
+		name            string
+		dedupeByDefault bool
+		inputDedupe     *bool
+		reused          bool
+		expectDeduped   bool
+		expectReused    bool
+	}{
+		{
+			name:          "dedupe_requested_reuses_existing_link",
+			inputDedupe:   ptr(true),
+			reused:        true,
+			expectDeduped: true,
+			expectReused:  true,
+		},
+		{
+			name:          "dedupe_requested_without_match_creates_link",
+			inputDedupe:   ptr(true),
+			expectDeduped: true,
+		},
+		{
+			name:            "server_default_enables_dedupe",
+			dedupeByDefault: true,
+			reused:          true,
+			expectDeduped:   true,
+			expectReused:    true,
+		},
+		{
+			name:            "explicit_opt_out_overrides_server_default",
+			dedupeByDefault: true,
+			inputDedupe:     ptr(false),
+		},
+		{
+			name: "dedupe_disabled_by_default",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			if tt.expectDeduped {
+				mockCacheRepo.EXPECT().
+					CreateDeduped(gomock.Any(), linkTo("https://example.com/Path", DefaultTTL)).
+					Return(int64(5), tt.reused, nil)
+			} else {
+				mockCacheRepo.EXPECT().
+					Create(gomock.Any(), linkTo("https://example.com/Path", DefaultTTL)).
+					Return(int64(5), nil)
+			}
+			if tt.expectReused {
+				mockAnalyticRepo.EXPECT().
+					GetByURLID(gomock.Any(), int64(5)).
+					Return(&entity.URLAnalytic{URLID: 5, LongURL: "HTTPS://Example.COM/Path"}, nil)
+			} else {
+				mockAnalyticRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(5)).Return(nil)
+			}
+
+			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo, WithDedupeByDefault(tt.dedupeByDefault))
+			link, err := svc.Create(context.Background(), entity.CreateLinkInput{
+				LongURL: "HTTPS://Example.COM/Path",
+				Dedupe:  tt.inputDedupe,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if link.ShortCode != lib.HexEncode(5) {
+				t.Errorf("expected short code %s, got %s", lib.HexEncode(5), link.ShortCode)
+			}
+			if link.Reused != tt.expectReused {
+				t.Errorf("expected reused=%v, got %v", tt.expectReused, link.Reused)
+			}
+		})
+	}
+}
+
+func TestLinkCreatorService_Owner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	// Owned links skip deduplication even when requested and record their owner
+	mockCacheRepo.EXPECT().
+		Create(gomock.Any(), linkTo("https://example.com/owned", DefaultTTL)).
+		Return(int64(5), nil)
+	mockAnalyticRepo.EXPECT().
+		Create(gomock.Any(), gomock.Cond(func(a *entity.URLAnalytic) bool {
+			return a.OwnerID != nil && *a.OwnerID == "team-a"
+		})).
+		Return(int64(1), nil)
+	mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(5)).Return(nil)
+
+	svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo, WithDedupeByDefault(true))
+	ctx := WithOwner(context.Background(), "team-a")
+	if _, err := svc.Create(ctx, entity.CreateLinkInput{LongURL: "https://example.com/owned", Dedupe: ptr(true)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestLinkCreatorService_CodeSet(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	mockCacheRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1000), nil)
+	mockAnalyticRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+	mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(1000)).Return(nil)
+
+	base57 := lib.NewBase57Codec()
+	svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo, WithCodeSet(lib.NewCodeSet(base57, lib.HexCodec{})))
+	link, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.ShortCode != base57.Encode(1000) {
+		t.Errorf("expected base-57 short code %s, got %s", base57.Encode(1000), link.ShortCode)
+	}
+}
+
+func TestLinkCreatorService_Blocklist(t *testing.T) {
+	blocked := int64(0xbad)
+
+	tests := []struct {
+		name        string
+		allocated   []int64
+		expectID    int64
+		expectError error
+	}{
+		{
+			name:      "blocked_id_is_skipped",
+			allocated: []int64{blocked, blocked + 1},
+			expectID:  blocked + 1,
+		},
+		{
+			name:        "gives_up_after_max_attempts",
+			allocated:   repeat(blocked, MaxCodeAttempts),
+			expectError: ErrNoAllowedCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			var calls []any
+			for _, id := range tt.allocated {
+				calls = append(calls, mockCacheRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(id, nil))
+				// Blocked IDs are removed before the next one is allocated
+				if id == blocked {
+					calls = append(calls, mockCacheRepo.EXPECT().Delete(gomock.Any(), id).Return(nil))
+				}
+			}
+			gomock.InOrder(calls...)
+			if tt.expectError == nil {
+				mockAnalyticRepo.EXPECT().
+					Create(gomock.Any(), gomock.Cond(func(a *entity.URLAnalytic) bool { return a.URLID == tt.expectID })).
+					Return(int64(1), nil)
+				mockCacheRepo.EXPECT().Confirm(gomock.Any(), tt.expectID).Return(nil)
+			}
+
+			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo, WithBlocklist(lib.NewBlocklist([]string{"bad"})))
+			link, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: "https://example.com"})
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if err == nil && link.ShortCode != lib.HexEncode(tt.expectID) {
+				t.Errorf("expected short code %s, got %s", lib.HexEncode(tt.expectID), link.ShortCode)
+			}
+		})
+	}
+}
+
+func TestLinkCreatorService_DefaultBlocklistSkipsHexWords(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	// 0x51a6 and 0x51a7 encode to "5ha6" and "5ha7", which read as "shag" and "shat"
+	gomock.InOrder(
+		mockCacheRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0x51a6), nil),
+		mockCacheRepo.EXPECT().Delete(gomock.Any(), int64(0x51a6)).Return(nil),
+		mockCacheRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0x51a7), nil),
+		mockCacheRepo.EXPECT().Delete(gomock.Any(), int64(0x51a7)).Return(nil),
+		mockCacheRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(0x51a8), nil),
+	)
+	mockAnalyticRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+	mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(0x51a8)).Return(nil)
+
+	svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+	link, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: "https://example.com"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.ShortCode != "5ha8" {
+		t.Errorf("expected short code 5ha8, got %s", link.ShortCode)
+	}
+
+	// Words hex codes cannot spell are not checked against them
+	hexWords := lib.DefaultCodeWords(lib.HexCodec{}.Alphabet())
+	if !slices.Contains(hexWords, "fag") || slices.Contains(hexWords, "fuck") {
+		t.Errorf("expected only hex-spellable words, got %v", hexWords)
+	}
+	if base57Words := lib.DefaultCodeWords(lib.NewBase57Codec().Alphabet()); !slices.Contains(base57Words, "fuck") {
+		t.Errorf("expected base-57 codes to be checked for every curated word, got %v", base57Words)
+	}
+}
+
+func TestLinkCreatorService_CreateBatchSkipsBlockedIDs(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	mockCacheRepo.EXPECT().
+		CreateBatch(gomock.Any(), gomock.Len(2)).
+		DoAndReturn(func(_ context.Context, urls []*entity.URL) error {
+			urls[0].ID, urls[1].ID = 0xbac, 0xbad
+			return nil
+		})
+	mockCacheRepo.EXPECT().Delete(gomock.Any(), int64(0xbad)).Return(nil)
+	mockCacheRepo.EXPECT().Create(gomock.Any(), linkTo("https://example.com/b", DefaultTTL)).Return(int64(0xbb0), nil)
+	mockAnalyticRepo.EXPECT().
+		CreateBatch(gomock.Any(), gomock.Cond(func(a []*entity.URLAnalytic) bool { return a[1].URLID == 0xbb0 })).
+		Return(nil)
+	mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(0xbac), int64(0xbb0)).Return(nil)
+
+	svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo, WithBlocklist(lib.NewBlocklist([]string{"bad"})))
+	results, err := svc.CreateBatch(context.Background(), []entity.CreateLinkInput{
+		{LongURL: "https://example.com/a"},
+		{LongURL: "https://example.com/b"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].ShortCode != "bac" || results[1].ShortCode != "bbg" {
+		t.Errorf("expected short codes bac and bbg, got %s and %s", results[0].ShortCode, results[1].ShortCode)
+	}
+}
+
+func repeat(id int64, n int) []int64 {
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = id
+	}
+	return ids
+}
+
+func TestLinkCreatorService_StoresCanonicalURL(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	mockCacheRepo.EXPECT().Create(gomock.Any(), linkTo("https://example.com/b", DefaultTTL)).Return(int64(3), nil)
+	mockAnalyticRepo.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, analytic *entity.URLAnalytic) (int64, error) {
+			if analytic.LongURL != "https://example.com/b" {
+				t.Errorf("expected canonical url in analytics, got %s", analytic.LongURL)
+			}
+			return 1, nil
+		})
+	mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(3)).Return(nil)
+
+	svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+	link, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: "HTTPS://Example.COM:443/a/../b"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if link.LongURL != "https://example.com/b" {
+		t.Errorf("expected canonical long url, got %s", link.LongURL)
+	}
+}
+
+func TestLinkCreatorService_DestinationPolicy(t *testing.T) {
+	errPolicyUnavailable := errors.New("policy unavailable")
+
+	tests := []struct {
+		name        string
+		policyErr   error
+		expectError error
+		expectStore bool
+	}{
+		{
+			name:        "allowed_destination_is_stored",
+			expectStore: true,
+		},
+		{
+			name:        "rejected_destination_returns_violation",
+			policyErr:   &PolicyViolation{Reason: ReasonDomainDenied},
+			expectError: ErrDestinationRejected,
+		},
+		{
+			name:        "policy_failure_is_returned",
+			policyErr:   errPolicyUnavailable,
+			expectError: errPolicyUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+			mockPolicy := mocks.NewMockDestinationPolicy(ctrl)
+
+			// The policy sees the canonical URL
+			mockPolicy.EXPECT().Check(gomock.Any(), "https://example.com/b").Return(tt.policyErr)
+			if tt.expectStore {
+				mockCacheRepo.EXPECT().Create(gomock.Any(), linkTo("https://example.com/b", DefaultTTL)).Return(int64(1), nil)
+				mockAnalyticRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(1)).Return(nil)
+			}
+
+			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo, WithDestinationPolicy(mockPolicy))
+			_, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: "https://EXAMPLE.com/a/../b"})
+
+			if tt.expectError == nil && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.expectError != nil && !errors.Is(err, tt.expectError) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestLinkCreatorService_DefaultPolicyRefusesInternalHosts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewLinkCreatorService(mocks.NewMockURLCacheRepo(ctrl), mocks.NewMockURLAnalyticRepo(ctrl))
+
+	_, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: "http://localhost:8080/admin"})
+	if !errors.Is(err, ErrDestinationRejected) {
+		t.Errorf("expected ErrDestinationRejected, got %v", err)
+	}
+
+	results, err := svc.CreateBatch(context.Background(), []entity.CreateLinkInput{{LongURL: "http://192.168.1.1/"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !errors.Is(results[0].Err, ErrDestinationRejected) {
+		t.Errorf("expected per-item ErrDestinationRejected, got %v", results[0].Err)
+	}
+}
+
+func TestLinkCreatorService_ActivationWindow(t *testing.T) {
+	now := time.Now()
+	launch := now.Add(48 * time.Hour).Truncate(time.Second)
+
+	tests := []struct {
+		name            string
+		input           entity.CreateLinkInput
+		expectError     error
+		expectNotBefore *time.Time
+		expectExpiresAt time.Time
+	}{
+		{
+			name:            "not_before_with_ttl_starts_window_at_launch_and_skips_dedupe",
+			input:           entity.CreateLinkInput{NotBefore: &launch, TTLSeconds: ptr(int64(7200)), Dedupe: ptr(true)},
+			expectNotBefore: &launch,
+			expectExpiresAt: launch.Add(2 * time.Hour),
+		},
+		{
+			name:            "not_before_with_expires_at_uses_both_bounds",
+			input:           entity.CreateLinkInput{NotBefore: &launch, ExpiresAt: ptr(launch.Add(72 * time.Hour))},
+			expectNotBefore: &launch,
+			expectExpiresAt: launch.Add(72 * time.Hour),
+		},
+		{
+			name:            "not_before_defaults_to_default_ttl",
+			input:           entity.CreateLinkInput{NotBefore: &launch},
+			expectNotBefore: &launch,
+			expectExpiresAt: launch.Add(DefaultTTL),
+		},
+		{
+			name:            "past_not_before_is_active_immediately",
+			input:           entity.CreateLinkInput{NotBefore: ptr(now.Add(-time.Hour)), ExpiresAt: ptr(launch)},
+			expectExpiresAt: launch,
+		},
+		{
+			name:        "ttl_and_expires_at_conflict",
+			input:       entity.CreateLinkInput{TTLSeconds: ptr(int64(7200)), ExpiresAt: ptr(launch)},
+			expectError: ErrConflictingExpiry,
+		},
+		{
+			name:        "window_shorter_than_min_ttl_returns_error",
+			input:       entity.CreateLinkInput{NotBefore: &launch, ExpiresAt: ptr(launch.Add(30 * time.Minute))},
+			expectError: ErrInvalidTTL,
+		},
+		{
+			name:        "window_longer_than_max_ttl_returns_error",
+			input:       entity.CreateLinkInput{NotBefore: &launch, ExpiresAt: ptr(launch.Add(MaxTTL + time.Hour))},
+			expectError: ErrInvalidTTL,
+		},
+		{
+			name:            "scheduled_window_ending_at_max_ttl_is_accepted",
+			input:           entity.CreateLinkInput{NotBefore: ptr(now.Add(MaxTTL - MinTTL).Truncate(time.Second)), TTLSeconds: ptr(int64(MinTTL / time.Second))},
+			expectNotBefore: ptr(now.Add(MaxTTL - MinTTL).Truncate(time.Second)),
+			expectExpiresAt: now.Add(MaxTTL).Truncate(time.Second),
+		},
+		{
+			name:        "scheduled_window_ending_after_max_ttl_returns_error",
+			input:       entity.CreateLinkInput{NotBefore: ptr(now.Add(MaxTTL - MinTTL + time.Second)), TTLSeconds: ptr(int64(MinTTL / time.Second))},
+			expectError: ErrNotBeforeTooLate,
+		},
+		{
+			name:        "not_before_beyond_max_ttl_returns_error",
+			input:       entity.CreateLinkInput{NotBefore: ptr(now.Add(365 * 24 * time.Hour))},
+			expectError: ErrNotBeforeTooLate,
+		},
+		{
+			name:        "expires_at_in_past_returns_error",
+			input:       entity.CreateLinkInput{ExpiresAt: ptr(now.Add(-time.Hour))},
+			expectError: ErrInvalidTTL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			if tt.expectError == nil {
+				mockCacheRepo.EXPECT().
+					Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, link *entity.URL) (int64, error) {
+						if !link.ExpiresAt.Equal(tt.expectExpiresAt) {
+							t.Errorf("expected expires_at %v, got %v", tt.expectExpiresAt, link.ExpiresAt)
+						}
+						if !sameTime(link.NotBefore, tt.expectNotBefore) {
+							t.Errorf("expected not_before %v, got %v", tt.expectNotBefore, link.NotBefore)
+						}
+						return 1, nil
+					})
+				mockAnalyticRepo.EXPECT().
+					Create(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, analytic *entity.URLAnalytic) (int64, error) {
+						if !sameTime(analytic.NotBefore, tt.expectNotBefore) {
+							t.Errorf("expected analytics not_before %v, got %v", tt.expectNotBefore, analytic.NotBefore)
+						}
+						return 1, nil
+					})
+				mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(1)).Return(nil)
+			}
+
+			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+			input := tt.input
+			input.LongURL = "https://example.com/launch"
+			link, err := svc.Create(context.Background(), input)
+
+			if err != tt.expectError {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if err == nil && !sameTime(link.NotBefore, tt.expectNotBefore) {
+				t.Errorf("expected link not_before %v, got %v", tt.expectNotBefore, link.NotBefore)
+			}
+		})
+	}
+}
+
+func TestLinkCreatorService_ReturnsLinkRecord(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	mockCacheRepo.EXPECT().Create(gomock.Any(), linkTo("https://example.com", 2*time.Hour)).Return(int64(9), nil)
+	mockAnalyticRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+	mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(9)).Return(nil)
+
+	svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+	link, err := svc.Create(context.Background(), entity.CreateLinkInput{
+		LongURL:    "https://example.com",
+		TTLSeconds: ptr(int64(7200)),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if link.ID != 9 || link.ShortCode != lib.HexEncode(9) || link.LongURL != "https://example.com" {
+		t.Errorf("unexpected link record: %+v", link)
+	}
+	if got := link.ExpiresAt.Sub(link.CreatedAt); got != 2*time.Hour {
+		t.Errorf("expected expires_at 2h after created_at, got %v", got)
+	}
+	if link.Reused {
+		t.Error("expected new link not to be marked reused")
+	}
+}
+
+func TestLinkCreatorService_MaxClicks(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxClicks   int64
+		expectError error
+	}{
+		{name: "one_time_link_is_stored_without_dedupe", maxClicks: 1},
+		{name: "zero_returns_error", maxClicks: 0, expectError: ErrInvalidMaxClicks},
+		{name: "negative_returns_error", maxClicks: -5, expectError: ErrInvalidMaxClicks},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			if tt.expectError == nil {
+				// Dedupe is requested, but a click budget must not be shared
+				mockCacheRepo.EXPECT().
+					Create(gomock.Any(), gomock.Cond(func(link *entity.URL) bool {
+						return link.MaxClicks != nil && *link.MaxClicks == tt.maxClicks
+					})).
+					Return(int64(7), nil)
+				mockAnalyticRepo.EXPECT().
+					Create(gomock.Any(), gomock.Cond(func(analytic *entity.URLAnalytic) bool {
+						return analytic.MaxClicks != nil && *analytic.MaxClicks == tt.maxClicks
+					})).
+					Return(int64(1), nil)
+				mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(7)).Return(nil)
+			}
+
+			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+			link, err := svc.Create(context.Background(), entity.CreateLinkInput{
+				LongURL:   "https://example.com/reset?token=abc",
+				MaxClicks: &tt.maxClicks,
+				Dedupe:    ptr(true),
+			})
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError == nil && (link.MaxClicks == nil || *link.MaxClicks != tt.maxClicks) {
+				t.Errorf("expected max_clicks %d, got %v", tt.maxClicks, link.MaxClicks)
+			}
+		})
+	}
+}
+
+func TestLinkCreatorService_CreateBatchRejectsMaxClicks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewLinkCreatorService(mocks.NewMockURLCacheRepo(ctrl), mocks.NewMockURLAnalyticRepo(ctrl))
+
+	results, err := svc.CreateBatch(context.Background(), []entity.CreateLinkInput{
+		{LongURL: "https://example.com", MaxClicks: ptr(int64(1))},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Err != ErrBatchMaxClicks {
+		t.Errorf("expected ErrBatchMaxClicks, got %v", results[0].Err)
+	}
+}
+
+func TestLinkCreatorService_Password(t *testing.T) {
+	tests := []struct {
+		name        string
+		password    string
+		expectError error
+	}{
+		{name: "password_is_stored_as_bcrypt_hash", password: "correct horse"},
+		{name: "empty_password_returns_error", password: "", expectError: ErrInvalidPassword},
+		{name: "password_over_72_bytes_returns_error", password: strings.Repeat("p", MaxPasswordLen+1), expectError: ErrInvalidPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			if tt.expectError == nil {
+				// Dedupe is requested, but a protected link must not be handed to other callers
+				mockCacheRepo.EXPECT().
+					Create(gomock.Any(), gomock.Cond(func(link *entity.URL) bool {
+						return link.PasswordHash != tt.password &&
+							bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(tt.password)) == nil
+					})).
+					Return(int64(8), nil)
+				mockAnalyticRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(8)).Return(nil)
+			}
+
+			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo)
+			link, err := svc.Create(context.Background(), entity.CreateLinkInput{
+				LongURL:  "https://example.com/private",
+				Password: &tt.password,
+				Dedupe:   ptr(true),
+			})
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError == nil && !link.PasswordProtected {
+				t.Error("expected link to be reported as password protected")
+			}
+		})
+	}
+}
+
+func TestLinkCreatorService_CreateBatchRejectsPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewLinkCreatorService(mocks.NewMockURLCacheRepo(ctrl), mocks.NewMockURLAnalyticRepo(ctrl))
+
+	results, err := svc.CreateBatch(context.Background(), []entity.CreateLinkInput{
+		{LongURL: "https://example.com", Password: ptr("s3cret")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if results[0].Err != ErrBatchPassword {
+		t.Errorf("expected ErrBatchPassword, got %v", results[0].Err)
+	}
+}
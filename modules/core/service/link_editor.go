@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+)
+
+var ErrEmptyEdit = errors.New("invalid edit: set long_url, ttl_seconds or expires_at")
+
+type LinkEditor interface {
+	Edit(ctx context.Context, shortCode string, input entity.EditLinkInput) (*entity.Link, error)
+	History(ctx context.Context, shortCode string) ([]*entity.LinkChange, error)
+}
+
+type LinkEditorService struct {
+	cacheRepo    URLCacheRepo
+	analyticRepo URLAnalyticRepo
+	creator      *LinkCreatorService
+}
+
+// NewLinkEditorService creates the service. New destinations are checked with the
+// creator's rules, so an edit cannot point a link anywhere creation would refuse.
+func NewLinkEditorService(
+	cacheRepo URLCacheRepo,
+	analyticRepo URLAnalyticRepo,
+	creator *LinkCreatorService,
+) *LinkEditorService {
+	return &LinkEditorService{
+		cacheRepo:    cacheRepo,
+		analyticRepo: analyticRepo,
+		creator:      creator,
+	}
+}
+
+// Edit changes the destination and/or expiry of a live link. Redis is updated first
+// so redirects follow the edit immediately; the analytics row and the change history
+// are then updated together, and Redis is put back if that fails.
+func (s *LinkEditorService) Edit(ctx context.Context, shortCode string, input entity.EditLinkInput) (*entity.Link, error) {
+	if input.LongURL == nil && input.TTLSeconds == nil && input.ExpiresAt == nil {
+		return nil, ErrEmptyEdit
+	}
+
+	link, analytic, err := s.load(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeChange(ctx, analytic); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	updated, err := s.apply(ctx, link, analytic, input, now)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.save(ctx, link, analytic, updated, now); err != nil {
+		return nil, err
+	}
+
+	return &entity.Link{
+		ID:                link.ID,
+		ShortCode:         s.creator.shortCodeFor(link.ID, analytic.Alias),
+		LongURL:           updated.LongURL,
+		CreatedAt:         analytic.CreatedAt,
+		ExpiresAt:         updated.ExpiresAt,
+		NotBefore:         link.NotBefore,
+		MaxClicks:         link.MaxClicks,
+		PasswordProtected: link.PasswordHash != "",
+	}, nil
+}
+
+// save writes an edit that changes something and records it in the history. If
+// the record cannot be written, the previous link is restored in Redis so the
+// fallback redirect and cache rebuilds, which read analytics, stay in step.
+func (s *LinkEditorService) save(ctx context.Context, link *entity.URL, analytic *entity.URLAnalytic, updated *entity.URL, now time.Time) error {
+	if updated.LongURL == analytic.LongURL && updated.ExpiresAt.Equal(analytic.ExpiresAt) {
+		return nil
+	}
+
+	if err := s.cacheRepo.Update(ctx, updated, analytic.Alias); err != nil {
+		if isCacheMiss(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	err := s.analyticRepo.ApplyChange(ctx, &entity.LinkChange{
+		URLID:        updated.ID,
+		OldLongURL:   analytic.LongURL,
+		NewLongURL:   updated.LongURL,
+		OldExpiresAt: analytic.ExpiresAt,
+		NewExpiresAt: updated.ExpiresAt,
+		ChangedAt:    now,
+	})
+	if err != nil {
+		previous := *link
+		previous.ExpiresAt = analytic.ExpiresAt
+		// Best effort; if it fails too, retrying the edit writes both again
+		_ = s.cacheRepo.Update(context.WithoutCancel(ctx), &previous, analytic.Alias)
+		return err
+	}
+	return nil
+}
+
+// History returns the recorded edits of a link, oldest first.
+func (s *LinkEditorService) History(ctx context.Context, shortCode string) ([]*entity.LinkChange, error) {
+	_, analytic, err := s.load(ctx, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	return s.analyticRepo.ListChanges(ctx, analytic.URLID)
+}
+
+// load reads a live link and its analytics record. Owned links are only loaded for their owner or an admin.
+func (s *LinkEditorService) load(ctx context.Context, shortCode string) (*entity.URL, *entity.URLAnalytic, error) {
+	id, _, err := resolveShortCode(ctx, s.creator.codes, s.cacheRepo, shortCode)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	link, err := s.cacheRepo.Get(ctx, id)
+	if err != nil {
+		if isCacheMiss(err) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	analytic, err := s.analyticRepo.GetByURLID(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+	if err := authorizeOwner(ctx, analytic); err != nil {
+		return nil, nil, err
+	}
+	return link, analytic, nil
+}
+
+// apply validates the edit and returns the link as it should be stored. The new
+// expiry is bounded like at creation: a scheduled link's window still starts at its
+// activation time.
+func (s *LinkEditorService) apply(
+	ctx context.Context,
+	link *entity.URL,
+	analytic *entity.URLAnalytic,
+	input entity.EditLinkInput,
+	now time.Time,
+) (*entity.URL, error) {
+	// Redis holds the live destination; the expiry is only kept in analytics
+	updated := *link
+	updated.ExpiresAt = analytic.ExpiresAt
+
+	if input.LongURL != nil {
+		longURL, err := canonicalURL(*input.LongURL)
+		if err != nil {
+			return nil, err
+		}
+		updated.LongURL, err = s.creator.checkDestination(ctx, longURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if input.TTLSeconds != nil || input.ExpiresAt != nil {
+		_, expiresAt, err := activeWindow(entity.CreateLinkInput{
+			TTLSeconds: input.TTLSeconds,
+			ExpiresAt:  input.ExpiresAt,
+			NotBefore:  link.NotBefore,
+		}, now)
+		if err != nil {
+			return nil, err
+		}
+		updated.ExpiresAt = expiresAt
+	}
+
+	return &updated, nil
+}
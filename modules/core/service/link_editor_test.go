@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/lib"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLinkEditorService_Edit(t *testing.T) {
+	now := time.Now()
+	createdAt := now.Add(-time.Hour)
+	expiresAt := now.Add(23 * time.Hour)
+	launch := now.Add(48 * time.Hour)
+
+	tests := []struct {
+		name            string
+		link            *entity.URL
+		input           entity.EditLinkInput
+		updateError     error
+		expectError     error
+		expectLongURL   string
+		expectExpiresAt func(time.Time) bool
+	}{
+		{
+			name:            "destination_is_canonicalized_and_expiry_kept",
+			input:           entity.EditLinkInput{LongURL: ptr("HTTPS://Example.com:443/new")},
+			expectLongURL:   "https://example.com/new",
+			expectExpiresAt: func(got time.Time) bool { return got.Equal(expiresAt) },
+		},
+		{
+			name:          "ttl_is_counted_from_now",
+			input:         entity.EditLinkInput{TTLSeconds: ptr(int64(7200))},
+			expectLongURL: "https://example.com/old",
+			expectExpiresAt: func(got time.Time) bool {
+				remaining := time.Until(got)
+				return remaining <= 2*time.Hour && remaining > 2*time.Hour-time.Minute
+			},
+		},
+		{
+			name:            "scheduled_link_ttl_is_counted_from_activation",
+			link:            &entity.URL{ID: 1, LongURL: "https://example.com/old", NotBefore: &launch},
+			input:           entity.EditLinkInput{TTLSeconds: ptr(int64(7200))},
+			expectLongURL:   "https://example.com/old",
+			expectExpiresAt: func(got time.Time) bool { return got.Equal(launch.Add(2 * time.Hour)) },
+		},
+		{
+			name:        "empty_edit_returns_error",
+			input:       entity.EditLinkInput{},
+			expectError: ErrEmptyEdit,
+		},
+		{
+			name:        "ttl_below_minimum_returns_error",
+			input:       entity.EditLinkInput{TTLSeconds: ptr(int64(60))},
+			expectError: ErrInvalidTTL,
+		},
+		{
+			name:        "ttl_and_expires_at_conflict",
+			input:       entity.EditLinkInput{TTLSeconds: ptr(int64(7200)), ExpiresAt: ptr(now.Add(3 * time.Hour))},
+			expectError: ErrConflictingExpiry,
+		},
+		{
+			name:        "invalid_url_returns_error",
+			input:       entity.EditLinkInput{LongURL: ptr("ftp://example.com")},
+			expectError: ErrInvalidURL,
+		},
+		{
+			name:        "internal_destination_is_rejected",
+			input:       entity.EditLinkInput{LongURL: ptr("http://127.0.0.1/admin")},
+			expectError: ErrDestinationRejected,
+		},
+		{
+			name:          "unchanged_edit_writes_nothing",
+			input:         entity.EditLinkInput{LongURL: ptr("https://example.com/old")},
+			expectLongURL: "https://example.com/old",
+		},
+		{
+			name:          "link_expiring_during_edit_is_not_found",
+			input:         entity.EditLinkInput{LongURL: ptr("https://example.com/new")},
+			updateError:   fmt.Errorf("URL not found or expired"),
+			expectError:   ErrNotFound,
+			expectLongURL: "https://example.com/new",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			link := tt.link
+			if link == nil {
+				link = &entity.URL{ID: 1, LongURL: "https://example.com/old"}
+			}
+			alias := ptr("spring-promo")
+			mockCacheRepo.EXPECT().ResolveAlias(gomock.Any(), "spring-promo").Return(int64(1), nil).AnyTimes()
+			mockCacheRepo.EXPECT().Get(gomock.Any(), int64(1)).Return(link, nil).AnyTimes()
+			mockAnalyticRepo.EXPECT().
+				GetByURLID(gomock.Any(), int64(1)).
+				Return(&entity.URLAnalytic{URLID: 1, OwnerID: ptr("team-a"), Alias: alias, LongURL: "https://example.com/old", CreatedAt: createdAt, ExpiresAt: expiresAt}, nil).
+				AnyTimes()
+
+			// Only edits that change something are written
+			writes := tt.expectExpiresAt != nil || tt.updateError != nil
+			if writes {
+				mockCacheRepo.EXPECT().
+					Update(gomock.Any(), gomock.Cond(func(u *entity.URL) bool {
+						return u.ID == 1 && u.LongURL == tt.expectLongURL && (tt.expectExpiresAt == nil || tt.expectExpiresAt(u.ExpiresAt))
+					}), alias).
+					Return(tt.updateError)
+			}
+			if writes && tt.updateError == nil {
+				mockAnalyticRepo.EXPECT().
+					ApplyChange(gomock.Any(), gomock.Cond(func(c *entity.LinkChange) bool {
+						return c.URLID == 1 && c.OldLongURL == "https://example.com/old" && c.NewLongURL == tt.expectLongURL &&
+							c.OldExpiresAt.Equal(expiresAt) && tt.expectExpiresAt(c.NewExpiresAt)
+					})).
+					Return(nil)
+			}
+
+			svc := NewLinkEditorService(mockCacheRepo, mockAnalyticRepo, NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo))
+			result, err := svc.Edit(WithOwner(context.Background(), "team-a"), "spring-promo", tt.input)
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError != nil {
+				return
+			}
+			if result.ShortCode != "spring-promo" || result.LongURL != tt.expectLongURL || !result.CreatedAt.Equal(createdAt) {
+				t.Errorf("unexpected link %+v", result)
+			}
+		})
+	}
+}
+
+func TestLinkEditorService_RestoresCacheWhenRecordFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	expiresAt := time.Now().Add(23 * time.Hour)
+	errUnavailable := errors.New("database unavailable")
+
+	mockCacheRepo.EXPECT().Get(gomock.Any(), int64(1)).Return(&entity.URL{ID: 1, LongURL: "https://example.com/old"}, nil)
+	mockAnalyticRepo.EXPECT().
+		GetByURLID(gomock.Any(), int64(1)).
+		Return(&entity.URLAnalytic{URLID: 1, OwnerID: ptr("team-a"), LongURL: "https://example.com/old", ExpiresAt: expiresAt}, nil)
+	gomock.InOrder(
+		mockCacheRepo.EXPECT().
+			Update(gomock.Any(), gomock.Cond(func(u *entity.URL) bool { return u.LongURL == "https://example.com/new" }), nil).
+			Return(nil),
+		mockAnalyticRepo.EXPECT().ApplyChange(gomock.Any(), gomock.Any()).Return(errUnavailable),
+		// The edit is not recorded, so redirects go back to the old destination
+		mockCacheRepo.EXPECT().
+			Update(gomock.Any(), gomock.Cond(func(u *entity.URL) bool {
+				return u.LongURL == "https://example.com/old" && u.ExpiresAt.Equal(expiresAt)
+			}), nil).
+			Return(nil),
+	)
+
+	svc := NewLinkEditorService(mockCacheRepo, mockAnalyticRepo, NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo))
+	_, err := svc.Edit(WithOwner(context.Background(), "team-a"), lib.HexEncode(1), entity.EditLinkInput{LongURL: ptr("https://example.com/new")})
+	if !errors.Is(err, errUnavailable) {
+		t.Errorf("expected the record error, got %v", err)
+	}
+}
+
+func TestLinkEditorService_NotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	mockCacheRepo.EXPECT().Get(gomock.Any(), int64(9)).Return(nil, fmt.Errorf("URL not found or expired")).Times(2)
+
+	svc := NewLinkEditorService(mockCacheRepo, mockAnalyticRepo, NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo))
+
+	if _, err := svc.Edit(context.Background(), lib.HexEncode(9), entity.EditLinkInput{TTLSeconds: ptr(int64(7200))}); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound from Edit, got %v", err)
+	}
+	if _, err := svc.History(context.Background(), lib.HexEncode(9)); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound from History, got %v", err)
+	}
+}
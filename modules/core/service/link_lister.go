@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/lib"
+)
+
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 100
+)
+
+var (
+	ErrOwnerRequired    = errors.New("an API key is required to list links")
+	ErrInvalidSort      = errors.New("invalid sort: must be created_at, click_count or last_accessed_at")
+	ErrInvalidStatus    = errors.New("invalid status: must be active, expired or revoked")
+	ErrInvalidLimit     = errors.New("invalid limit: must be between 1 and 100")
+	ErrInvalidCursor    = errors.New("invalid cursor: must come from a listing with the same sort and order")
+	ErrInvalidDomain    = errors.New("invalid domain: must be a host name or *.host name")
+	ErrInvalidMinClicks = errors.New("invalid min_clicks: must not be negative")
+)
+
+type LinkLister interface {
+	List(ctx context.Context, input entity.ListLinksInput) (*entity.LinkPage, error)
+}
+
+type LinkListerService struct {
+	analyticRepo URLAnalyticRepo
+	codes        *lib.CodeSet
+}
+
+func NewLinkListerService(analyticRepo URLAnalyticRepo, codes *lib.CodeSet) *LinkListerService {
+	return &LinkListerService{analyticRepo: analyticRepo, codes: codes}
+}
+
+// List returns one page of the caller's links. Only links created with one of the
+// owner's API keys are listed; anonymous callers are refused.
+func (s *LinkListerService) List(ctx context.Context, input entity.ListLinksInput) (*entity.LinkPage, error) {
+	ownerID, ok := OwnerFromContext(ctx)
+	if !ok {
+		return nil, ErrOwnerRequired
+	}
+
+	query, err := linkQuery(ownerID, input, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	// One extra row tells whether another page follows
+	limit := query.Limit
+	query.Limit++
+	links, err := s.analyticRepo.ListByOwner(ctx, *query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.LinkPage{}
+	if len(links) > limit {
+		links = links[:limit]
+		page.NextCursor = encodeCursor(query, links[limit-1])
+	}
+	page.Links = make([]*entity.ListedLink, len(links))
+	for i, link := range links {
+		page.Links[i] = &entity.ListedLink{URLAnalytic: link, ShortCode: s.codes.Encode(link.URLID)}
+	}
+	return page, nil
+}
+
+// linkQuery validates the input and resolves it into a storage query.
+func linkQuery(ownerID string, input entity.ListLinksInput, now time.Time) (*entity.LinkQuery, error) {
+	query := &entity.LinkQuery{
+		OwnerID:       ownerID,
+		CreatedAfter:  input.CreatedAfter,
+		CreatedBefore: input.CreatedBefore,
+		Status:        input.Status,
+		MinClicks:     input.MinClicks,
+		Now:           now,
+		Sort:          input.Sort,
+		Ascending:     input.Ascending,
+		Limit:         input.Limit,
+	}
+
+	if query.Sort == "" {
+		query.Sort = entity.SortCreatedAt
+	}
+	if query.Limit == 0 {
+		query.Limit = DefaultListLimit
+	}
+	if err := validateLinkQuery(query); err != nil {
+		return nil, err
+	}
+
+	if input.Domain != "" {
+		domain, err := listDomain(input.Domain)
+		if err != nil {
+			return nil, err
+		}
+		query.Domain = domain
+	}
+
+	if input.Cursor != "" {
+		after, err := decodeCursor(query, input.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		query.After = after
+	}
+	return query, nil
+}
+
+func validateLinkQuery(query *entity.LinkQuery) error {
+	switch query.Sort {
+	case entity.SortCreatedAt, entity.SortClickCount, entity.SortLastAccessedAt:
+	default:
+		return ErrInvalidSort
+	}
+	switch query.Status {
+	case "", entity.LinkStatusActive, entity.LinkStatusExpired, entity.LinkStatusRevoked:
+	default:
+		return ErrInvalidStatus
+	}
+	if query.Limit < 1 || query.Limit > MaxListLimit {
+		return ErrInvalidLimit
+	}
+	if query.MinClicks != nil && *query.MinClicks < 0 {
+		return ErrInvalidMinClicks
+	}
+	return nil
+}
+
+// listDomain normalizes a domain filter the way destinations are canonicalized,
+// keeping a leading "*." that selects subdomains.
+func listDomain(domain string) (string, error) {
+	suffix, wildcard := strings.CutPrefix(domain, "*.")
+	normalized, err := lib.NormalizeDomain(suffix)
+	if err != nil {
+		return "", ErrInvalidDomain
+	}
+	if wildcard {
+		return "*." + normalized, nil
+	}
+	return normalized, nil
+}
+
+// linkCursor is the serialized form of a page position. It records the sort it
+// was made for so it cannot be replayed against a different order.
+type linkCursor struct {
+	Sort      entity.LinkSort `json:"s"`
+	Ascending bool            `json:"a,omitempty"`
+	Time      *time.Time      `json:"t,omitempty"`
+	Count     int64           `json:"c,omitempty"`
+	ID        int64           `json:"i"`
+}
+
+func encodeCursor(query *entity.LinkQuery, last *entity.URLAnalytic) string {
+	cursor := linkCursor{Sort: query.Sort, Ascending: query.Ascending, ID: last.ID}
+	switch query.Sort {
+	case entity.SortClickCount:
+		cursor.Count = last.ClickCount
+	case entity.SortLastAccessedAt:
+		// Matches the storage order, where never-accessed links sort as the Unix epoch
+		accessed := time.Unix(0, 0).UTC()
+		if last.LastAccessedAt != nil {
+			accessed = *last.LastAccessedAt
+		}
+		cursor.Time = &accessed
+	default:
+		cursor.Time = &last.CreatedAt
+	}
+
+	// Marshalling a struct of plain fields cannot fail
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+func decodeCursor(query *entity.LinkQuery, raw string) (*entity.LinkCursor, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor linkCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if cursor.Sort != query.Sort || cursor.Ascending != query.Ascending {
+		return nil, ErrInvalidCursor
+	}
+	if (cursor.Time == nil) != (query.Sort == entity.SortClickCount) {
+		return nil, ErrInvalidCursor
+	}
+
+	after := &entity.LinkCursor{Count: cursor.Count, ID: cursor.ID}
+	if cursor.Time != nil {
+		after.Time = *cursor.Time
+	}
+	return after, nil
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/lib"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLinkListerService_List(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       entity.ListLinksInput
+		expectQuery func(entity.LinkQuery) bool
+		expectError error
+	}{
+		{
+			name:  "defaults_to_newest_first",
+			input: entity.ListLinksInput{},
+			expectQuery: func(q entity.LinkQuery) bool {
+				return q.OwnerID == "team-a" && q.Sort == entity.SortCreatedAt && !q.Ascending && q.Limit == DefaultListLimit+1
+			},
+		},
+		{
+			name:  "domain_is_normalized",
+			input: entity.ListLinksInput{Domain: "Example.COM."},
+			expectQuery: func(q entity.LinkQuery) bool {
+				return q.Domain == "example.com"
+			},
+		},
+		{
+			name:  "wildcard_domain_is_kept",
+			input: entity.ListLinksInput{Domain: "*.Example.com"},
+			expectQuery: func(q entity.LinkQuery) bool {
+				return q.Domain == "*.example.com"
+			},
+		},
+		{
+			name:  "filters_are_passed_through",
+			input: entity.ListLinksInput{Status: entity.LinkStatusExpired, MinClicks: ptr(int64(10)), Sort: entity.SortClickCount, Ascending: true, Limit: 5},
+			expectQuery: func(q entity.LinkQuery) bool {
+				return q.Status == entity.LinkStatusExpired && *q.MinClicks == 10 && q.Sort == entity.SortClickCount && q.Ascending && q.Limit == 6
+			},
+		},
+		{
+			name:        "unknown_sort_returns_error",
+			input:       entity.ListLinksInput{Sort: "long_url"},
+			expectError: ErrInvalidSort,
+		},
+		{
+			name:        "unknown_status_returns_error",
+			input:       entity.ListLinksInput{Status: "paused"},
+			expectError: ErrInvalidStatus,
+		},
+		{
+			name:        "oversized_limit_returns_error",
+			input:       entity.ListLinksInput{Limit: MaxListLimit + 1},
+			expectError: ErrInvalidLimit,
+		},
+		{
+			name:        "negative_min_clicks_returns_error",
+			input:       entity.ListLinksInput{MinClicks: ptr(int64(-1))},
+			expectError: ErrInvalidMinClicks,
+		},
+		{
+			name:        "malformed_cursor_returns_error",
+			input:       entity.ListLinksInput{Cursor: "not-a-cursor"},
+			expectError: ErrInvalidCursor,
+		},
+		{
+			name:        "empty_wildcard_domain_returns_error",
+			input:       entity.ListLinksInput{Domain: "*."},
+			expectError: ErrInvalidDomain,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+			if tt.expectError == nil {
+				analyticRepo.EXPECT().
+					ListByOwner(gomock.Any(), gomock.Cond(tt.expectQuery)).
+					Return(nil, nil)
+			}
+
+			svc := NewLinkListerService(analyticRepo, lib.DefaultCodeSet())
+			_, err := svc.List(WithOwner(context.Background(), "team-a"), tt.input)
+			if !errors.Is(err, tt.expectError) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestLinkListerService_RequiresOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc := NewLinkListerService(mocks.NewMockURLAnalyticRepo(ctrl), lib.DefaultCodeSet())
+	if _, err := svc.List(context.Background(), entity.ListLinksInput{}); !errors.Is(err, ErrOwnerRequired) {
+		t.Errorf("expected ErrOwnerRequired, got %v", err)
+	}
+}
+
+func TestLinkListerService_Pagination(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	created := time.Date(2026, 1, 1, 12, 0, 0, 123000, time.UTC)
+	links := []*entity.URLAnalytic{
+		{ID: 3, URLID: 30, ClickCount: 9, CreatedAt: created},
+		{ID: 2, URLID: 20, ClickCount: 7, CreatedAt: created},
+		{ID: 1, URLID: 10, ClickCount: 7, CreatedAt: created},
+	}
+
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	gomock.InOrder(
+		// The first page asks for one extra row to learn that more follow
+		analyticRepo.EXPECT().
+			ListByOwner(gomock.Any(), gomock.Cond(func(q entity.LinkQuery) bool { return q.After == nil && q.Limit == 3 })).
+			Return(links, nil),
+		// The second page continues after the last link shown, by click count then ID
+		analyticRepo.EXPECT().
+			ListByOwner(gomock.Any(), gomock.Cond(func(q entity.LinkQuery) bool {
+				return q.After != nil && q.After.Count == 7 && q.After.ID == 2
+			})).
+			Return(links[2:], nil),
+	)
+
+	svc := NewLinkListerService(analyticRepo, lib.DefaultCodeSet())
+	ctx := WithOwner(context.Background(), "team-a")
+	input := entity.ListLinksInput{Sort: entity.SortClickCount, Limit: 2}
+
+	page, err := svc.List(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Links) != 2 || page.NextCursor == "" {
+		t.Fatalf("expected 2 links and a next cursor, got %d links, cursor %q", len(page.Links), page.NextCursor)
+	}
+
+	// A cursor only continues the listing it came from
+	mismatched := input
+	mismatched.Cursor = page.NextCursor
+	mismatched.Sort = entity.SortCreatedAt
+	if _, err := svc.List(ctx, mismatched); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("expected ErrInvalidCursor for a different sort, got %v", err)
+	}
+
+	input.Cursor = page.NextCursor
+	page, err = svc.List(ctx, input)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(page.Links) != 1 || page.NextCursor != "" {
+		t.Errorf("expected a last page of 1 link, got %d links, cursor %q", len(page.Links), page.NextCursor)
+	}
+}
+
+func TestLinkListerService_TimeCursor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	accessed := time.Date(2026, 1, 1, 12, 0, 0, 123000, time.UTC)
+	links := []*entity.URLAnalytic{
+		{ID: 2, LastAccessedAt: &accessed},
+		{ID: 1},
+		{ID: 0},
+	}
+
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	gomock.InOrder(
+		analyticRepo.EXPECT().ListByOwner(gomock.Any(), gomock.Any()).Return(links[:2], nil),
+		analyticRepo.EXPECT().
+			ListByOwner(gomock.Any(), gomock.Cond(func(q entity.LinkQuery) bool {
+				return q.After != nil && q.After.Time.Equal(accessed) && q.After.ID == 2
+			})).
+			Return(links[1:], nil),
+		// Never-accessed links continue from the Unix epoch, where storage sorts them
+		analyticRepo.EXPECT().
+			ListByOwner(gomock.Any(), gomock.Cond(func(q entity.LinkQuery) bool {
+				return q.After != nil && q.After.Time.Equal(time.Unix(0, 0)) && q.After.ID == 1
+			})).
+			Return(nil, nil),
+	)
+
+	svc := NewLinkListerService(analyticRepo, lib.DefaultCodeSet())
+	ctx := WithOwner(context.Background(), "team-a")
+	input := entity.ListLinksInput{Sort: entity.SortLastAccessedAt, Limit: 1}
+
+	for i := 0; i < 3; i++ {
+		page, err := svc.List(ctx, input)
+		if err != nil {
+			t.Fatalf("page %d: unexpected error: %v", i, err)
+		}
+		input.Cursor = page.NextCursor
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// MaxPasswordLen is the longest password bcrypt can hash without truncating it.
+	MaxPasswordLen = 72
+
+	// MaxUnlockAttempts is how many passwords may be tried on one link per UnlockAttemptWindow.
+	MaxUnlockAttempts   = 5
+	UnlockAttemptWindow = 15 * time.Minute
+)
+
+var (
+	ErrInvalidPassword = errors.New("invalid password: must be 1-72 bytes")
+	ErrBatchPassword   = errors.New("invalid password: password protection is not supported in batches")
+
+	ErrPasswordRequired = errors.New("short code is password protected")
+	ErrWrongPassword    = errors.New("incorrect password")
+	ErrTooManyAttempts  = errors.New("too many password attempts")
+)
+
+// TooManyAttemptsError is returned when a protected link has used up its unlock
+// attempts. It matches ErrTooManyAttempts with errors.Is.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	seconds := int64(math.Ceil(e.RetryAfter.Seconds()))
+	return ErrTooManyAttempts.Error() + ": retry in " + strconv.FormatInt(seconds, 10) + "s"
+}
+
+func (e *TooManyAttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+func validatePassword(password *string) error {
+	if password != nil && (*password == "" || len(*password) > MaxPasswordLen) {
+		return ErrInvalidPassword
+	}
+	return nil
+}
+
+// hashPassword returns the bcrypt hash stored for a protected link, or "" without a password.
+func hashPassword(password *string) (string, error) {
+	if password == nil {
+		return "", nil
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(*password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// unlock verifies the password of a protected link. Every attempt is counted before
+// the hash is compared, so parallel guesses cannot get past MaxUnlockAttempts; a
+// correct password gives its attempt back.
+func (s *LinkRedirectorService) unlock(ctx context.Context, link *entity.URL, password string) error {
+	if link.PasswordHash == "" {
+		return nil
+	}
+	if password == "" {
+		return ErrPasswordRequired
+	}
+
+	allowed, retryAfter, err := s.attemptRepo.Reserve(ctx, link.ID, MaxUnlockAttempts, UnlockAttemptWindow)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+	_ = s.attemptRepo.Release(ctx, link.ID)
+	return nil
+}
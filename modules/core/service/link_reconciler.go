@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	// ReconcileGrace is how old a pending link must be before it is reconciled. It
+	// leaves creates that are still writing their analytics record alone.
+	ReconcileGrace = 5 * time.Minute
+
+	// ReconcileInterval is how often the server reconciles pending links.
+	ReconcileInterval = time.Minute
+
+	reconcileBatchSize = 100
+)
+
+// ReconcileResult counts the pending links a reconciliation settled.
+type ReconcileResult struct {
+	// Confirmed links had their analytics record and keep redirecting.
+	Confirmed int
+	// Discarded links had no analytics record and were removed from Redis.
+	Discarded int
+}
+
+// LinkReconcilerService repairs links left half-created in Redis by creates whose
+// analytics insert failed and whose own cleanup did not run, for example because
+// Redis was unreachable too or the process stopped.
+type LinkReconcilerService struct {
+	cacheRepo    URLCacheRepo
+	analyticRepo URLAnalyticRepo
+}
+
+func NewLinkReconcilerService(cacheRepo URLCacheRepo, analyticRepo URLAnalyticRepo) *LinkReconcilerService {
+	return &LinkReconcilerService{
+		cacheRepo:    cacheRepo,
+		analyticRepo: analyticRepo,
+	}
+}
+
+// Reconcile settles every link still pending from before the cutoff: a link with
+// an analytics record is confirmed, one without is discarded, since its creator
+// was told the create failed. It stops at the first storage error and reports
+// what it settled until then.
+func (s *LinkReconcilerService) Reconcile(ctx context.Context, cutoff time.Time) (ReconcileResult, error) {
+	var result ReconcileResult
+	for {
+		ids, err := s.cacheRepo.ListPending(ctx, cutoff, reconcileBatchSize)
+		if err != nil {
+			return result, err
+		}
+
+		for _, id := range ids {
+			if err := s.settle(ctx, id, &result); err != nil {
+				return result, err
+			}
+		}
+		if len(ids) < reconcileBatchSize {
+			return result, nil
+		}
+	}
+}
+
+func (s *LinkReconcilerService) settle(ctx context.Context, id int64, result *ReconcileResult) error {
+	_, err := s.analyticRepo.GetByURLID(ctx, id)
+	switch {
+	case err == nil:
+		if err := s.cacheRepo.Confirm(ctx, id); err != nil {
+			return err
+		}
+		result.Confirmed++
+	case errors.Is(err, sql.ErrNoRows):
+		discarded, err := s.cacheRepo.Discard(ctx, id)
+		if err != nil {
+			return err
+		}
+		if discarded {
+			result.Discarded++
+		}
+	default:
+		return err
+	}
+	return nil
+}
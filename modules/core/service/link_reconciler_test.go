@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLinkReconcilerService_Reconcile(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	cutoff := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	cacheRepo.EXPECT().ListPending(gomock.Any(), cutoff, int64(reconcileBatchSize)).Return([]int64{1, 2, 3}, nil)
+
+	// A link whose record was written only lost its confirmation
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(1)).Return(&entity.URLAnalytic{URLID: 1}, nil)
+	cacheRepo.EXPECT().Confirm(gomock.Any(), int64(1)).Return(nil)
+
+	// A link without a record failed to create and is removed
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(2)).Return(nil, sql.ErrNoRows)
+	cacheRepo.EXPECT().Discard(gomock.Any(), int64(2)).Return(true, nil)
+
+	// A link confirmed since it was listed is left alone
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(3)).Return(nil, sql.ErrNoRows)
+	cacheRepo.EXPECT().Discard(gomock.Any(), int64(3)).Return(false, nil)
+
+	svc := NewLinkReconcilerService(cacheRepo, analyticRepo)
+	result, err := svc.Reconcile(context.Background(), cutoff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result != (ReconcileResult{Confirmed: 1, Discarded: 1}) {
+		t.Errorf("expected 1 confirmed and 1 discarded, got %+v", result)
+	}
+}
+
+func TestLinkReconcilerService_ReconcilesInBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	full := make([]int64, reconcileBatchSize)
+	for i := range full {
+		full[i] = int64(i + 1)
+	}
+	gomock.InOrder(
+		cacheRepo.EXPECT().ListPending(gomock.Any(), gomock.Any(), gomock.Any()).Return(full, nil),
+		// A full batch means more may follow
+		cacheRepo.EXPECT().ListPending(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, nil),
+	)
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), gomock.Any()).Return(&entity.URLAnalytic{}, nil).Times(reconcileBatchSize)
+	cacheRepo.EXPECT().Confirm(gomock.Any(), gomock.Any()).Return(nil).Times(reconcileBatchSize)
+
+	svc := NewLinkReconcilerService(cacheRepo, analyticRepo)
+	result, err := svc.Reconcile(context.Background(), time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Confirmed != reconcileBatchSize {
+		t.Errorf("expected %d confirmed, got %d", reconcileBatchSize, result.Confirmed)
+	}
+}
+
+func TestLinkReconcilerService_KeepsLinksOnLookupError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	errUnavailable := errors.New("database unavailable")
+
+	// Without an answer from Postgres the link may still have a record, so it is kept
+	cacheRepo.EXPECT().ListPending(gomock.Any(), gomock.Any(), gomock.Any()).Return([]int64{1, 2}, nil)
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(1)).Return(nil, errUnavailable)
+
+	svc := NewLinkReconcilerService(cacheRepo, analyticRepo)
+	if _, err := svc.Reconcile(context.Background(), time.Now()); !errors.Is(err, errUnavailable) {
+		t.Errorf("expected lookup error, got %v", err)
+	}
+}
@@ -3,54 +3,408 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/nanda/doit/modules/core/entity"
 	"github.com/nanda/doit/modules/core/lib"
 )
 
-var ErrNotFound = errors.New("short code not found or expired")
+var (
+	ErrNotFound      = errors.New("short code not found or expired")
+	ErrNotYetActive  = errors.New("short code is not active yet")
+	ErrLinkExhausted = errors.New("short code has reached its click limit")
+
+	// ErrCacheUnavailable refuses a link that cannot be served from analytics while
+	// Redis is down, because its password attempts or clicks are only counted there.
+	ErrCacheUnavailable = errors.New("short code is temporarily unavailable")
+
+	ErrNonCanonicalCode = errors.New("short code is not in its canonical spelling")
+
+	// ErrBadChecksum refuses a generated code whose check characters are wrong
+	// before anything is looked up. It matches ErrNotFound with errors.Is.
+	ErrBadChecksum = fmt.Errorf("%w: check characters do not match", ErrNotFound)
+)
+
+// NonCanonicalCodeError is returned for a short code typed with the wrong case or
+// confusable characters, when such codes are redirected to their canonical
+// spelling instead of being resolved. It matches ErrNonCanonicalCode with errors.Is.
+type NonCanonicalCodeError struct {
+	Canonical string
+}
+
+func (e *NonCanonicalCodeError) Error() string {
+	return "short code is spelled " + e.Canonical
+}
+
+func (e *NonCanonicalCodeError) Unwrap() error {
+	return ErrNonCanonicalCode
+}
+
+// NonCanonicalMode selects how Redirect treats a short code that is not in its
+// canonical spelling.
+type NonCanonicalMode string
+
+const (
+	// NonCanonicalRedirect returns a NonCanonicalCodeError so the caller can
+	// redirect to the canonical spelling.
+	NonCanonicalRedirect NonCanonicalMode = "redirect"
+	// NonCanonicalResolve follows the link as if the canonical code had been given.
+	NonCanonicalResolve NonCanonicalMode = "resolve"
+)
+
+// NotYetActiveError is returned for a scheduled link before its activation time.
+// It matches ErrNotYetActive with errors.Is.
+type NotYetActiveError struct {
+	NotBefore time.Time
+}
+
+func (e *NotYetActiveError) Error() string {
+	return "short code is not active until " + e.NotBefore.UTC().Format(time.RFC3339)
+}
+
+func (e *NotYetActiveError) Unwrap() error {
+	return ErrNotYetActive
+}
+
+// CacheFallback records whether a redirect was answered from analytics because
+// Redis was unavailable.
+type CacheFallback struct {
+	used atomic.Bool
+}
+
+// Record marks the redirect as answered from analytics.
+func (f *CacheFallback) Record() {
+	f.used.Store(true)
+}
+
+// Used reports whether the redirect was answered from analytics.
+func (f *CacheFallback) Used() bool {
+	return f.used.Load()
+}
+
+type cacheFallbackKey struct{}
+
+// WithCacheFallbackRecorder returns a context in which Redirect records into f
+// whether it fell back to analytics.
+func WithCacheFallbackRecorder(ctx context.Context, f *CacheFallback) context.Context {
+	return context.WithValue(ctx, cacheFallbackKey{}, f)
+}
+
+// CacheFallbackFromContext returns the recorder of the request, if it has one.
+func CacheFallbackFromContext(ctx context.Context) (*CacheFallback, bool) {
+	f, ok := ctx.Value(cacheFallbackKey{}).(*CacheFallback)
+	return f, ok
+}
 
 type LinkRedirector interface {
-	Redirect(ctx context.Context, shortCode string) (string, error)
+	// Redirect returns the destination of the short code. password unlocks a
+	// protected link and is ignored for unprotected ones.
+	Redirect(ctx context.Context, shortCode, password string) (string, error)
 }
 
 type LinkRedirectorService struct {
-	cacheRepo    URLCacheRepo
-	analyticRepo URLAnalyticRepo
+	cacheRepo        URLCacheRepo
+	analyticRepo     URLAnalyticRepo
+	attemptRepo      UnlockAttemptRepo
+	codes            *lib.CodeSet
+	nonCanonicalMode NonCanonicalMode
+	cacheBreaker     *CircuitBreaker
+	cacheUnavailable func(error) bool
+}
+
+// LinkRedirectorOption configures optional LinkRedirectorService behaviour.
+type LinkRedirectorOption func(*LinkRedirectorService)
+
+// WithNonCanonicalMode sets how retyped codes are treated. The default,
+// NonCanonicalRedirect, answers them with their canonical spelling.
+func WithNonCanonicalMode(mode NonCanonicalMode) LinkRedirectorOption {
+	return func(s *LinkRedirectorService) {
+		s.nonCanonicalMode = mode
+	}
+}
+
+// WithCacheFallback serves redirects from analytics when Redis cannot be reached,
+// and keeps requests off Redis while breaker is open. unavailable tells such
+// errors apart from the others, which are returned as they are. Without it every
+// Redis error is returned.
+func WithCacheFallback(breaker *CircuitBreaker, unavailable func(error) bool) LinkRedirectorOption {
+	return func(s *LinkRedirectorService) {
+		s.cacheBreaker = breaker
+		s.cacheUnavailable = unavailable
+	}
 }
 
 func NewLinkRedirectorService(
 	cacheRepo URLCacheRepo,
 	analyticRepo URLAnalyticRepo,
+	attemptRepo UnlockAttemptRepo,
+	codes *lib.CodeSet,
+	opts ...LinkRedirectorOption,
 ) *LinkRedirectorService {
-	return &LinkRedirectorService{
-		cacheRepo:    cacheRepo,
-		analyticRepo: analyticRepo,
+	s := &LinkRedirectorService{
+		cacheRepo:        cacheRepo,
+		analyticRepo:     analyticRepo,
+		attemptRepo:      attemptRepo,
+		codes:            codes,
+		nonCanonicalMode: NonCanonicalRedirect,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *LinkRedirectorService) Redirect(ctx context.Context, shortCode, password string) (string, error) {
+	if s.cacheBreaker != nil && !s.cacheBreaker.Allow() {
+		return s.redirectFromAnalytics(ctx, shortCode)
+	}
+
+	now := time.Now()
+	link, err := s.cachedLink(ctx, shortCode)
+	if err == nil {
+		err = s.admit(ctx, link, password, now)
+	}
+	if err != nil {
+		return s.fallBack(ctx, shortCode, err)
 	}
+
+	// Update analytics asynchronously (non-blocking)
+	go func() {
+		_ = s.analyticRepo.UpdateStat(context.Background(), link.ID, now)
+	}()
+
+	return link.LongURL, nil
+}
+
+// fallBack answers the redirect from analytics when err means Redis could not be
+// reached, and returns err otherwise.
+func (s *LinkRedirectorService) fallBack(ctx context.Context, shortCode string, err error) (string, error) {
+	if s.cacheFailed(ctx, err) {
+		return s.redirectFromAnalytics(ctx, shortCode)
+	}
+	return "", err
 }
 
-func (s *LinkRedirectorService) Redirect(ctx context.Context, shortCode string) (string, error) {
-	id, err := lib.HexDecode(shortCode)
+// cachedLink resolves shortCode, which must be in its canonical spelling unless
+// retyped codes are resolved, and reads its link from Redis.
+func (s *LinkRedirectorService) cachedLink(ctx context.Context, shortCode string) (*entity.URL, error) {
+	id, canonical, err := resolveShortCode(ctx, s.codes, s.cacheRepo, shortCode)
 	if err != nil {
-		return "", ErrNotFound
+		return nil, err
+	}
+	if err := s.checkCanonical(shortCode, canonical); err != nil {
+		return nil, err
 	}
 
 	// Get URL from Redis cache (Redis handles expiration via TTL)
-	longURL, err := s.cacheRepo.Get(ctx, id)
+	link, err := s.cacheRepo.Get(ctx, id)
 	if err != nil {
-		// If not found in cache, it's either expired or never existed
-		if strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "expired") {
-			return "", ErrNotFound
+		// If not found in cache, it's either revoked, expired or never existed
+		if isCacheMiss(err) {
+			s.cacheAnswered()
+			return nil, s.missingLinkError(ctx, id)
 		}
-		return "", err
+		return nil, err
 	}
+	s.cacheAnswered()
+	return link, nil
+}
 
-	// Update analytics asynchronously (non-blocking)
+// admit runs the checks a link read from Redis must pass before it redirects:
+// its activation time, its password and its click limit.
+func (s *LinkRedirectorService) admit(ctx context.Context, link *entity.URL, password string, now time.Time) error {
+	// Scheduled links do not redirect, or count clicks, before they go live
+	if link.NotBefore != nil && now.Before(*link.NotBefore) {
+		return &NotYetActiveError{NotBefore: *link.NotBefore}
+	}
+	// A failed unlock neither spends a click nor counts as one
+	if err := s.unlock(ctx, link, password); err != nil {
+		return err
+	}
+	return s.consumeClick(ctx, link)
+}
+
+// redirectFromAnalytics answers a redirect from the analytics record while Redis
+// is unavailable. Links with a password or a click limit are refused, since their
+// unlock attempts and clicks are only counted in Redis.
+func (s *LinkRedirectorService) redirectFromAnalytics(ctx context.Context, shortCode string) (string, error) {
+	if f, ok := CacheFallbackFromContext(ctx); ok {
+		f.Record()
+	}
+
+	analytic, canonical, err := lookupAnalytic(ctx, s.codes, s.analyticRepo, shortCode)
+	if err != nil {
+		return "", err
+	}
+	if err := s.checkCanonical(shortCode, canonical); err != nil {
+		return "", err
+	}
 	now := time.Now()
+	if err := checkRecordedLink(analytic, now); err != nil {
+		return "", err
+	}
+	if countedInCache(analytic) {
+		return "", ErrCacheUnavailable
+	}
+
 	go func() {
-		_ = s.analyticRepo.UpdateStat(context.Background(), id, now)
+		_ = s.analyticRepo.UpdateStat(context.Background(), analytic.URLID, now)
 	}()
 
-	return longURL, nil
+	return analytic.LongURL, nil
+}
+
+// checkRecordedLink refuses an analytics record that is revoked, expired, not
+// active yet or out of clicks.
+func checkRecordedLink(analytic *entity.URLAnalytic, now time.Time) error {
+	if analytic.RevokedAt != nil {
+		return ErrLinkRevoked
+	}
+	if !now.Before(analytic.ExpiresAt) {
+		return ErrNotFound
+	}
+	if analytic.NotBefore != nil && now.Before(*analytic.NotBefore) {
+		return &NotYetActiveError{NotBefore: *analytic.NotBefore}
+	}
+	if analytic.MaxClicks != nil && analytic.ClickCount >= *analytic.MaxClicks {
+		return ErrLinkExhausted
+	}
+	return nil
+}
+
+// countedInCache reports whether a link has a click limit or may have a password,
+// whose clicks or unlock attempts only Redis counts. A missing hash may belong to
+// a protected link recorded before hashes were kept.
+func countedInCache(analytic *entity.URLAnalytic) bool {
+	return analytic.MaxClicks != nil || analytic.PasswordHash == nil || *analytic.PasswordHash != ""
+}
+
+// checkCanonical returns a NonCanonicalCodeError when retyped codes are redirected
+// and shortCode resolved through another spelling.
+func (s *LinkRedirectorService) checkCanonical(shortCode, canonical string) error {
+	if s.nonCanonicalMode == NonCanonicalRedirect && canonical != shortCode {
+		return &NonCanonicalCodeError{Canonical: canonical}
+	}
+	return nil
+}
+
+// cacheFailed reports whether err means Redis could not be reached, so the
+// redirect should fall back, and counts it against the breaker. Requests the
+// caller gave up on say nothing about Redis and are not counted.
+func (s *LinkRedirectorService) cacheFailed(ctx context.Context, err error) bool {
+	if s.cacheBreaker == nil || ctx.Err() != nil || !s.cacheUnavailable(err) {
+		return false
+	}
+	s.cacheBreaker.Failure()
+	return true
+}
+
+// cacheAnswered records that Redis answered a lookup.
+func (s *LinkRedirectorService) cacheAnswered() {
+	if s.cacheBreaker != nil {
+		s.cacheBreaker.Success()
+	}
+}
+
+// missingLinkError tells a revoked link apart from one that expired or never existed.
+func (s *LinkRedirectorService) missingLinkError(ctx context.Context, id int64) error {
+	revoked, err := s.cacheRepo.IsRevoked(ctx, id)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return ErrLinkRevoked
+	}
+	return ErrNotFound
+}
+
+// consumeClick spends one click of a click-limited link. Only redirects that get
+// this far count against the limit.
+func (s *LinkRedirectorService) consumeClick(ctx context.Context, link *entity.URL) error {
+	if link.MaxClicks == nil {
+		return nil
+	}
+
+	ok, err := s.cacheRepo.ConsumeClick(ctx, link.ID)
+	if err != nil {
+		// The link expired between the read and the click
+		if isCacheMiss(err) {
+			return ErrNotFound
+		}
+		return err
+	}
+	if !ok {
+		return ErrLinkExhausted
+	}
+	return nil
+}
+
+// resolveShortCode maps a short code to its link ID. Generated codes of any known
+// format decode locally; anything else is treated as a vanity alias and looked up in Redis.
+// Retyped codes resolve like their canonical spelling, which is returned with the ID.
+func resolveShortCode(ctx context.Context, codes *lib.CodeSet, cacheRepo URLCacheRepo, shortCode string) (int64, string, error) {
+	for _, spelling := range shortCodeSpellings(codes, shortCode) {
+		if id, generated, err := codes.Decode(spelling); generated {
+			if err != nil {
+				return 0, "", decodeError(err)
+			}
+			return id, spelling, nil
+		}
+
+		if !lib.IsValidAlias(spelling) {
+			continue
+		}
+		id, err := cacheRepo.ResolveAlias(ctx, spelling)
+		if err != nil {
+			if isCacheMiss(err) {
+				continue
+			}
+			return 0, "", err
+		}
+		return id, spelling, nil
+	}
+	return 0, "", ErrNotFound
+}
+
+// shortCodeSpellings returns the spellings a short code may stand for, in the
+// order they are tried. A code that was typed with the wrong case or with
+// characters ADR-001 treats as confusable may be an alias or a hex code: the
+// alias is tried first, so a lowercase alias always wins over the hex code it
+// might be a typo of, and the hex code is tried when no such alias exists.
+// Tagged codes are case-sensitive and are never folded.
+func shortCodeSpellings(codes *lib.CodeSet, shortCode string) []string {
+	if codes.Matches(shortCode) {
+		return []string{shortCode}
+	}
+
+	var spellings []string
+	if lib.IsValidAlias(shortCode) {
+		spellings = append(spellings, shortCode)
+	} else if lower := strings.ToLower(shortCode); lib.IsValidAlias(lower) {
+		spellings = append(spellings, lower)
+	}
+	if canonical, ok := lib.CanonicalHexCode(shortCode); ok {
+		spellings = append(spellings, canonical)
+	}
+	if len(spellings) == 0 {
+		return []string{shortCode}
+	}
+	return spellings
+}
+
+// decodeError maps a failure to decode a generated code to ErrNotFound, or to
+// ErrBadChecksum when its check characters are wrong.
+func decodeError(err error) error {
+	if errors.Is(err, lib.ErrBadChecksum) {
+		return ErrBadChecksum
+	}
+	return ErrNotFound
+}
+
+// isCacheMiss reports whether a cache error means the key is absent.
+func isCacheMiss(err error) bool {
+	return strings.Contains(err.Error(), "not found") || strings.Contains(err.Error(), "expired")
 }
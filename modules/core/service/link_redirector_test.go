@@ -2,12 +2,17 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/nanda/doit/modules/core/entity"
 	"github.com/nanda/doit/modules/core/internal/test/mocks"
 	"github.com/nanda/doit/modules/core/lib"
 	"go.uber.org/mock/gomock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestLinkRedirectorService(t *testing.T) {
@@ -68,19 +73,23 @@ func TestLinkRedirectorService(t *testing.T) {
 				}
 
 				cacheRepo.EXPECT().
-					Create(gomock.Any(), *tt.setupURL, ttl).
+					Create(gomock.Any(), linkTo(*tt.setupURL, ttl)).
 					Return(urlID, nil)
 
 				analyticRepo.EXPECT().
 					Create(gomock.Any(), gomock.Any()).
 					Return(int64(1), nil)
 
+				cacheRepo.EXPECT().
+					Confirm(gomock.Any(), urlID).
+					Return(nil)
+
 				creatorSvc := NewLinkCreatorService(cacheRepo, analyticRepo)
-				var err error
-				shortCode, err = creatorSvc.Create(ctx, *tt.setupURL, tt.setupTTL)
+				link, err := creatorSvc.Create(ctx, entity.CreateLinkInput{LongURL: *tt.setupURL, TTLSeconds: tt.setupTTL})
 				if err != nil {
 					t.Fatalf("setup failed: %v", err)
 				}
+				shortCode = link.ShortCode
 			}
 
 			if tt.inputShortCode != nil {
@@ -88,7 +97,7 @@ func TestLinkRedirectorService(t *testing.T) {
 			}
 
 			// Setup expectations for redirect
-			redirectorSvc := NewLinkRedirectorService(cacheRepo, analyticRepo)
+			redirectorSvc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet())
 
 			var longURL string
 			var err error
@@ -97,7 +106,7 @@ func TestLinkRedirectorService(t *testing.T) {
 				// Expect Get calls
 				cacheRepo.EXPECT().
 					Get(gomock.Any(), urlID).
-					Return(*tt.setupURL, nil).
+					Return(&entity.URL{ID: urlID, LongURL: *tt.setupURL}, nil).
 					Times(tt.redirectCount)
 
 				// Expect UpdateStat calls (async, may complete after function returns)
@@ -112,14 +121,18 @@ func TestLinkRedirectorService(t *testing.T) {
 					// Valid short code format, will reach Get
 					cacheRepo.EXPECT().
 						Get(gomock.Any(), gomock.Any()).
-						Return("", ErrNotFound).
+						Return(nil, ErrNotFound).
+						Times(tt.redirectCount)
+					cacheRepo.EXPECT().
+						IsRevoked(gomock.Any(), gomock.Any()).
+						Return(false, nil).
 						Times(tt.redirectCount)
 				}
 				// If decode fails, no Get call will be made
 			}
 
 			for i := 0; i < tt.redirectCount; i++ {
-				longURL, err = redirectorSvc.Redirect(ctx, shortCode)
+				longURL, err = redirectorSvc.Redirect(ctx, shortCode, "")
 			}
 
 			// Wait briefly for async operations (gomock will verify they were called)
@@ -139,3 +152,800 @@ func TestLinkRedirectorService(t *testing.T) {
 		})
 	}
 }
+
+func TestLinkRedirectorService_Alias(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	cacheRepo.EXPECT().ResolveAlias(gomock.Any(), "spring-promo").Return(int64(7), nil)
+	cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(&entity.URL{ID: 7, LongURL: "https://example.com/promo"}, nil)
+	analyticRepo.EXPECT().UpdateStat(gomock.Any(), int64(7), gomock.Any()).Return(nil)
+	cacheRepo.EXPECT().ResolveAlias(gomock.Any(), "gone-promo").Return(int64(0), errors.New("alias not found or expired"))
+
+	svc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet())
+
+	longURL, err := svc.Redirect(context.Background(), "spring-promo", "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if longURL != "https://example.com/promo" {
+		t.Errorf("expected https://example.com/promo, got %s", longURL)
+	}
+
+	if _, err := svc.Redirect(context.Background(), "gone-promo", ""); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound for expired alias, got %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+}
+
+func TestLinkRedirectorService_CodeFormats(t *testing.T) {
+	tests := []struct {
+		name        string
+		shortCode   string
+		expectError error
+	}{
+		{
+			name:      "hex_code_resolves",
+			shortCode: lib.HexEncode(7),
+		},
+		{
+			name:      "base57_code_resolves",
+			shortCode: lib.NewBase57Codec().Encode(7),
+		},
+		{
+			name:      "keyed_code_resolves",
+			shortCode: testKeyedCodec(t).Encode(7),
+		},
+		{
+			name:        "base57_code_with_leading_zero_digit_is_not_found",
+			shortCode:   "J29",
+			expectError: ErrNotFound,
+		},
+		{
+			name:        "base57_code_with_confusable_character_is_not_found",
+			shortCode:   "J2l",
+			expectError: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+			if tt.expectError == nil {
+				cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(&entity.URL{ID: 7, LongURL: "https://example.com"}, nil)
+				analyticRepo.EXPECT().UpdateStat(gomock.Any(), int64(7), gomock.Any()).Return(nil).AnyTimes()
+			}
+
+			// Both formats resolve whichever codec encodes new links
+			codes := lib.NewCodeSet(lib.NewBase57Codec(), lib.HexCodec{}, testKeyedCodec(t))
+			svc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), codes)
+			if _, err := svc.Redirect(context.Background(), tt.shortCode, ""); !errors.Is(err, tt.expectError) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		})
+	}
+}
+
+func TestLinkRedirectorService_NonCanonical(t *testing.T) {
+	tests := []struct {
+		name            string
+		shortCode       string
+		mode            NonCanonicalMode
+		expectID        int64
+		expectAlias     string
+		missedAlias     string
+		expectCanonical string
+	}{
+		{
+			name:            "uppercase_hex_code_redirects_to_canonical_by_default",
+			shortCode:       "FF",
+			expectCanonical: "ff",
+		},
+		{
+			name:            "confusable_characters_redirect_to_canonical",
+			shortCode:       "lO1",
+			mode:            NonCanonicalRedirect,
+			expectCanonical: "hgh",
+		},
+		{
+			name:            "uppercase_alias_redirects_to_canonical",
+			shortCode:       "Spring-Promo",
+			mode:            NonCanonicalRedirect,
+			expectID:        7,
+			expectAlias:     "spring-promo",
+			expectCanonical: "spring-promo",
+		},
+		{
+			name:            "retyped_hex_code_without_alias_redirects_to_canonical",
+			shortCode:       "HOO",
+			mode:            NonCanonicalRedirect,
+			missedAlias:     "hoo",
+			expectCanonical: "hgg",
+		},
+		{
+			name:      "canonical_code_is_not_redirected",
+			shortCode: "ff",
+			mode:      NonCanonicalRedirect,
+			expectID:  0xff,
+		},
+		{
+			name:      "confusable_characters_resolve_directly",
+			shortCode: "Ol",
+			mode:      NonCanonicalResolve,
+			expectID:  0x01,
+		},
+		{
+			name:        "uppercase_alias_resolves_directly",
+			shortCode:   "SPRING-PROMO",
+			mode:        NonCanonicalResolve,
+			expectID:    7,
+			expectAlias: "spring-promo",
+		},
+		{
+			name:      "lowercase_alias_is_not_read_as_hex",
+			shortCode: "food",
+			mode:      NonCanonicalResolve,
+			expectID:  8,
+			// "food" would fold to the hex code "fggd", but an alias as typed wins
+			expectAlias: "food",
+		},
+		{
+			name:        "uppercase_hex_code_without_alias_resolves_as_hex",
+			shortCode:   "DEADBEEO",
+			mode:        NonCanonicalResolve,
+			missedAlias: "deadbeeo",
+			expectID:    0xdeadbee0,
+		},
+		{
+			name:        "lowercase_hex_code_without_alias_resolves_as_hex",
+			shortCode:   "hoo",
+			mode:        NonCanonicalResolve,
+			missedAlias: "hoo",
+			expectID:    0x100,
+		},
+		{
+			name:        "lowercase_i_resolves_as_hex",
+			shortCode:   "iii",
+			mode:        NonCanonicalResolve,
+			missedAlias: "iii",
+			expectID:    0x111,
+		},
+		{
+			name:      "mixed_confusables_resolve_as_hex",
+			shortCode: "Il1",
+			mode:      NonCanonicalResolve,
+			expectID:  0x111,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+			if tt.missedAlias != "" {
+				cacheRepo.EXPECT().ResolveAlias(gomock.Any(), tt.missedAlias).Return(int64(0), errors.New("alias not found"))
+			}
+			if tt.expectAlias != "" {
+				cacheRepo.EXPECT().ResolveAlias(gomock.Any(), tt.expectAlias).Return(tt.expectID, nil)
+			}
+			if tt.expectCanonical == "" {
+				cacheRepo.EXPECT().Get(gomock.Any(), tt.expectID).Return(&entity.URL{ID: tt.expectID, LongURL: "https://example.com"}, nil)
+				analyticRepo.EXPECT().UpdateStat(gomock.Any(), tt.expectID, gomock.Any()).Return(nil).AnyTimes()
+			}
+
+			var opts []LinkRedirectorOption
+			if tt.mode != "" {
+				opts = append(opts, WithNonCanonicalMode(tt.mode))
+			}
+			svc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet(), opts...)
+			_, err := svc.Redirect(context.Background(), tt.shortCode, "")
+
+			var nonCanonical *NonCanonicalCodeError
+			switch {
+			case tt.expectCanonical == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.expectCanonical != "" && (!errors.As(err, &nonCanonical) || nonCanonical.Canonical != tt.expectCanonical):
+				t.Errorf("expected redirect to %s, got %v", tt.expectCanonical, err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		})
+	}
+}
+
+func TestLinkRedirectorService_Checksum(t *testing.T) {
+	codec, err := lib.NewKeyedCodec(1, []byte("0123456789abcdef"), 1)
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+	code := codec.Encode(7)
+
+	tests := []struct {
+		name      string
+		shortCode string
+	}{
+		{
+			name:      "mistyped_digit_is_refused",
+			shortCode: code[:3] + mistype(code[3]) + code[4:],
+		},
+		{
+			name:      "mistyped_check_character_is_refused",
+			shortCode: code[:len(code)-1] + mistype(code[len(code)-1]),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			// No repository calls are expected: the code is refused in-process
+			svc := NewLinkRedirectorService(
+				mocks.NewMockURLCacheRepo(ctrl),
+				mocks.NewMockURLAnalyticRepo(ctrl),
+				mocks.NewMockUnlockAttemptRepo(ctrl),
+				lib.NewCodeSet(codec),
+			)
+			if _, err := svc.Redirect(context.Background(), tt.shortCode, ""); !errors.Is(err, ErrBadChecksum) || !errors.Is(err, ErrNotFound) {
+				t.Errorf("expected ErrBadChecksum matching ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestLinkRedirectorService_Revoked(t *testing.T) {
+	tests := []struct {
+		name        string
+		revoked     bool
+		expectError error
+	}{
+		{
+			name:        "revoked_link_is_gone",
+			revoked:     true,
+			expectError: ErrLinkRevoked,
+		},
+		{
+			name:        "expired_link_is_not_found",
+			revoked:     false,
+			expectError: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			cacheRepo.EXPECT().Get(gomock.Any(), int64(6)).Return(nil, fmt.Errorf("URL not found or expired"))
+			cacheRepo.EXPECT().IsRevoked(gomock.Any(), int64(6)).Return(tt.revoked, nil)
+
+			svc := NewLinkRedirectorService(cacheRepo, mocks.NewMockURLAnalyticRepo(ctrl), mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet())
+			if _, err := svc.Redirect(context.Background(), lib.HexEncode(6), ""); !errors.Is(err, tt.expectError) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+		})
+	}
+}
+
+func TestLinkRedirectorService_NotBefore(t *testing.T) {
+	tests := []struct {
+		name        string
+		notBefore   time.Time
+		expectError error
+	}{
+		{
+			name:        "scheduled_link_is_not_yet_active",
+			notBefore:   time.Now().Add(time.Hour),
+			expectError: ErrNotYetActive,
+		},
+		{
+			name:      "launched_link_redirects",
+			notBefore: time.Now().Add(-time.Minute),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			cacheRepo.EXPECT().
+				Get(gomock.Any(), int64(3)).
+				Return(&entity.URL{ID: 3, LongURL: "https://example.com/launch", NotBefore: &tt.notBefore}, nil)
+			done := make(chan struct{})
+			if tt.expectError == nil {
+				analyticRepo.EXPECT().
+					UpdateStat(gomock.Any(), int64(3), gomock.Any()).
+					DoAndReturn(func(context.Context, int64, time.Time) error {
+						close(done)
+						return nil
+					})
+			} else {
+				close(done)
+			}
+
+			svc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet())
+			longURL, err := svc.Redirect(context.Background(), lib.HexEncode(3), "")
+			<-done
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			var notYetActive *NotYetActiveError
+			if tt.expectError != nil && (!errors.As(err, &notYetActive) || !notYetActive.NotBefore.Equal(tt.notBefore)) {
+				t.Errorf("expected activation time %v in error, got %v", tt.notBefore, err)
+			}
+			if tt.expectError == nil && longURL != "https://example.com/launch" {
+				t.Errorf("expected long URL, got %s", longURL)
+			}
+		})
+	}
+}
+
+func TestLinkRedirectorService_MaxClicks(t *testing.T) {
+	tests := []struct {
+		name          string
+		consumed      bool
+		consumeError  error
+		expectError   error
+		expectCounted bool
+	}{
+		{
+			name:          "click_within_limit_redirects",
+			consumed:      true,
+			expectCounted: true,
+		},
+		{
+			name:        "exhausted_link_is_gone",
+			consumed:    false,
+			expectError: ErrLinkExhausted,
+		},
+		{
+			name:         "link_expired_before_click_is_not_found",
+			consumeError: fmt.Errorf("URL not found or expired"),
+			expectError:  ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			cacheRepo.EXPECT().
+				Get(gomock.Any(), int64(4)).
+				Return(&entity.URL{ID: 4, LongURL: "https://example.com/reset", MaxClicks: ptr(int64(1))}, nil)
+			cacheRepo.EXPECT().
+				ConsumeClick(gomock.Any(), int64(4)).
+				Return(tt.consumed, tt.consumeError)
+			done := make(chan struct{})
+			if tt.expectCounted {
+				analyticRepo.EXPECT().
+					UpdateStat(gomock.Any(), int64(4), gomock.Any()).
+					DoAndReturn(func(context.Context, int64, time.Time) error {
+						close(done)
+						return nil
+					})
+			} else {
+				close(done)
+			}
+
+			svc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet())
+			longURL, err := svc.Redirect(context.Background(), lib.HexEncode(4), "")
+			<-done
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError == nil && longURL != "https://example.com/reset" {
+				t.Errorf("expected long URL, got %s", longURL)
+			}
+		})
+	}
+}
+
+func TestLinkRedirectorService_Password(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		passwordHash  string
+		password      string
+		reserve       *bool
+		expectRelease bool
+		expectError   error
+	}{
+		{
+			name:         "missing_password_is_required",
+			passwordHash: string(hash),
+			expectError:  ErrPasswordRequired,
+		},
+		{
+			name:          "correct_password_redirects_and_releases_attempt",
+			passwordHash:  string(hash),
+			password:      "s3cret",
+			reserve:       ptr(true),
+			expectRelease: true,
+		},
+		{
+			name:         "wrong_password_is_rejected_without_spending_a_click",
+			passwordHash: string(hash),
+			password:     "guess",
+			reserve:      ptr(true),
+			expectError:  ErrWrongPassword,
+		},
+		{
+			name:         "locked_link_is_rate_limited",
+			passwordHash: string(hash),
+			password:     "s3cret",
+			reserve:      ptr(false),
+			expectError:  ErrTooManyAttempts,
+		},
+		{
+			name:     "password_is_ignored_for_unprotected_link",
+			password: "anything",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+			attemptRepo := mocks.NewMockUnlockAttemptRepo(ctrl)
+
+			// The click limit makes any premature ConsumeClick an unexpected call
+			cacheRepo.EXPECT().
+				Get(gomock.Any(), int64(5)).
+				Return(&entity.URL{ID: 5, LongURL: "https://example.com/private", PasswordHash: tt.passwordHash, MaxClicks: ptr(int64(3))}, nil)
+			if tt.reserve != nil {
+				attemptRepo.EXPECT().
+					Reserve(gomock.Any(), int64(5), int64(MaxUnlockAttempts), UnlockAttemptWindow).
+					Return(*tt.reserve, time.Minute, nil)
+			}
+			if tt.expectRelease {
+				attemptRepo.EXPECT().Release(gomock.Any(), int64(5)).Return(nil)
+			}
+			done := make(chan struct{})
+			if tt.expectError == nil {
+				cacheRepo.EXPECT().ConsumeClick(gomock.Any(), int64(5)).Return(true, nil)
+				analyticRepo.EXPECT().
+					UpdateStat(gomock.Any(), int64(5), gomock.Any()).
+					DoAndReturn(func(context.Context, int64, time.Time) error {
+						close(done)
+						return nil
+					})
+			} else {
+				close(done)
+			}
+
+			svc := NewLinkRedirectorService(cacheRepo, analyticRepo, attemptRepo, lib.DefaultCodeSet())
+			longURL, err := svc.Redirect(context.Background(), lib.HexEncode(5), tt.password)
+			<-done
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError == nil && longURL != "https://example.com/private" {
+				t.Errorf("expected long URL, got %s", longURL)
+			}
+		})
+	}
+}
+
+// errCacheDown stands for an error of a Redis that cannot be reached.
+var errCacheDown = errors.New("dial tcp: connection refused")
+
+func cacheDown(err error) bool {
+	return errors.Is(err, errCacheDown)
+}
+
+func TestLinkRedirectorService_CacheFallback(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name          string
+		analytic      *entity.URLAnalytic
+		lookupError   error
+		expectError   error
+		expectLongURL string
+	}{
+		{
+			name:          "live_link_redirects",
+			analytic:      &entity.URLAnalytic{URLID: 7, LongURL: "https://example.com", ExpiresAt: future, PasswordHash: ptr("")},
+			expectLongURL: "https://example.com",
+		},
+		{
+			name:        "unknown_link_returns_not_found",
+			lookupError: sql.ErrNoRows,
+			expectError: ErrNotFound,
+		},
+		{
+			name:        "expired_link_returns_not_found",
+			analytic:    &entity.URLAnalytic{URLID: 7, ExpiresAt: time.Now().Add(-time.Minute), PasswordHash: ptr("")},
+			expectError: ErrNotFound,
+		},
+		{
+			name:        "revoked_link_returns_revoked",
+			analytic:    &entity.URLAnalytic{URLID: 7, ExpiresAt: future, RevokedAt: ptr(time.Now()), PasswordHash: ptr("")},
+			expectError: ErrLinkRevoked,
+		},
+		{
+			name:        "scheduled_link_is_not_yet_active",
+			analytic:    &entity.URLAnalytic{URLID: 7, ExpiresAt: future, NotBefore: &future, PasswordHash: ptr("")},
+			expectError: ErrNotYetActive,
+		},
+		{
+			name:        "spent_link_returns_exhausted",
+			analytic:    &entity.URLAnalytic{URLID: 7, ExpiresAt: future, MaxClicks: ptr(int64(2)), ClickCount: 2, PasswordHash: ptr("")},
+			expectError: ErrLinkExhausted,
+		},
+		{
+			name:        "click_limited_link_is_unavailable",
+			analytic:    &entity.URLAnalytic{URLID: 7, ExpiresAt: future, MaxClicks: ptr(int64(2)), ClickCount: 1, PasswordHash: ptr("")},
+			expectError: ErrCacheUnavailable,
+		},
+		{
+			name:        "protected_link_is_unavailable",
+			analytic:    &entity.URLAnalytic{URLID: 7, ExpiresAt: future, PasswordHash: ptr("$2a$10$hash")},
+			expectError: ErrCacheUnavailable,
+		},
+		{
+			name:        "link_without_recorded_hash_is_unavailable",
+			analytic:    &entity.URLAnalytic{URLID: 7, ExpiresAt: future},
+			expectError: ErrCacheUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(nil, errCacheDown)
+			analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(7)).Return(tt.analytic, tt.lookupError)
+			done := make(chan struct{})
+			if tt.expectError == nil {
+				analyticRepo.EXPECT().
+					UpdateStat(gomock.Any(), int64(7), gomock.Any()).
+					DoAndReturn(func(context.Context, int64, time.Time) error {
+						close(done)
+						return nil
+					})
+			} else {
+				close(done)
+			}
+
+			svc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet(),
+				WithCacheFallback(NewCircuitBreaker(CacheBreakerThreshold, CacheBreakerCooldown), cacheDown))
+			fallback := &CacheFallback{}
+			ctx := WithCacheFallbackRecorder(context.Background(), fallback)
+			longURL, err := svc.Redirect(ctx, lib.HexEncode(7), "")
+			<-done
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if longURL != tt.expectLongURL {
+				t.Errorf("expected long URL %q, got %q", tt.expectLongURL, longURL)
+			}
+			if !fallback.Used() {
+				t.Error("expected the fallback to be recorded")
+			}
+		})
+	}
+}
+
+func TestLinkRedirectorService_CacheFallbackAlias(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+	cacheRepo.EXPECT().ResolveAlias(gomock.Any(), "spring-promo").Return(int64(0), errCacheDown)
+	analyticRepo.EXPECT().GetByAlias(gomock.Any(), "spring-promo").
+		Return(&entity.URLAnalytic{URLID: 7, LongURL: "https://example.com/promo", ExpiresAt: time.Now().Add(time.Hour), PasswordHash: ptr("")}, nil)
+	done := make(chan struct{})
+	analyticRepo.EXPECT().UpdateStat(gomock.Any(), int64(7), gomock.Any()).
+		DoAndReturn(func(context.Context, int64, time.Time) error {
+			close(done)
+			return nil
+		})
+
+	svc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet(),
+		WithCacheFallback(NewCircuitBreaker(CacheBreakerThreshold, CacheBreakerCooldown), cacheDown))
+	longURL, err := svc.Redirect(context.Background(), "spring-promo", "")
+	<-done
+	if err != nil || longURL != "https://example.com/promo" {
+		t.Errorf("expected alias to redirect from analytics, got %q, %v", longURL, err)
+	}
+}
+
+func TestLinkRedirectorService_CacheBreaker(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+	// Redis is only tried until the breaker opens
+	cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(nil, errCacheDown).Times(2)
+	analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(7)).Return(nil, sql.ErrNoRows).Times(3)
+
+	svc := NewLinkRedirectorService(cacheRepo, analyticRepo, mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet(),
+		WithCacheFallback(NewCircuitBreaker(2, time.Minute), cacheDown))
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Redirect(context.Background(), lib.HexEncode(7), ""); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("expected not found from analytics, got %v", err)
+		}
+	}
+}
+
+func TestLinkRedirectorService_CacheFailureAfterLookup(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		link     *entity.URL
+		password string
+		setup    func(cacheRepo *mocks.MockURLCacheRepo, attemptRepo *mocks.MockUnlockAttemptRepo)
+		analytic *entity.URLAnalytic
+	}{
+		{
+			name: "revocation_check_falls_back",
+			setup: func(cacheRepo *mocks.MockURLCacheRepo, _ *mocks.MockUnlockAttemptRepo) {
+				cacheRepo.EXPECT().IsRevoked(gomock.Any(), int64(7)).Return(false, errCacheDown)
+			},
+			analytic: &entity.URLAnalytic{URLID: 7, ExpiresAt: future, RevokedAt: ptr(time.Now()), PasswordHash: ptr("")},
+		},
+		{
+			name: "click_falls_back",
+			link: &entity.URL{ID: 7, LongURL: "https://example.com", MaxClicks: ptr(int64(3))},
+			setup: func(cacheRepo *mocks.MockURLCacheRepo, _ *mocks.MockUnlockAttemptRepo) {
+				cacheRepo.EXPECT().ConsumeClick(gomock.Any(), int64(7)).Return(false, errCacheDown)
+			},
+			analytic: &entity.URLAnalytic{URLID: 7, ExpiresAt: future, MaxClicks: ptr(int64(3)), PasswordHash: ptr("")},
+		},
+		{
+			name:     "unlock_attempt_falls_back",
+			link:     &entity.URL{ID: 7, LongURL: "https://example.com", PasswordHash: string(hash)},
+			password: "secret",
+			setup: func(_ *mocks.MockURLCacheRepo, attemptRepo *mocks.MockUnlockAttemptRepo) {
+				attemptRepo.EXPECT().Reserve(gomock.Any(), int64(7), gomock.Any(), gomock.Any()).Return(false, time.Duration(0), errCacheDown)
+			},
+			analytic: &entity.URLAnalytic{URLID: 7, ExpiresAt: future, PasswordHash: ptr(string(hash))},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+			attemptRepo := mocks.NewMockUnlockAttemptRepo(ctrl)
+
+			if tt.link != nil {
+				cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(tt.link, nil)
+			} else {
+				cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(nil, errors.New("URL not found or expired"))
+			}
+			tt.setup(cacheRepo, attemptRepo)
+			analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(7)).Return(tt.analytic, nil)
+
+			// Each of these failures counts against the breaker like a failed lookup
+			breaker := NewCircuitBreaker(1, time.Minute)
+			svc := NewLinkRedirectorService(cacheRepo, analyticRepo, attemptRepo, lib.DefaultCodeSet(), WithCacheFallback(breaker, cacheDown))
+			fallback := &CacheFallback{}
+			_, err := svc.Redirect(WithCacheFallbackRecorder(context.Background(), fallback), lib.HexEncode(7), tt.password)
+
+			// The analytics record answers what it can; revoked links stay revoked
+			if tt.analytic.RevokedAt != nil {
+				if !errors.Is(err, ErrLinkRevoked) {
+					t.Errorf("expected ErrLinkRevoked, got %v", err)
+				}
+			} else if !errors.Is(err, ErrCacheUnavailable) {
+				t.Errorf("expected ErrCacheUnavailable, got %v", err)
+			}
+			if !fallback.Used() {
+				t.Error("expected the fallback to be recorded")
+			}
+			if !breaker.Open() {
+				t.Error("expected the failure to open the breaker")
+			}
+		})
+	}
+}
+
+func TestLinkRedirectorService_CacheErrorsThatAreNotOutages(t *testing.T) {
+	errBadSettings := errors.New("invalid max_clicks in URL settings")
+
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		err         error
+		expectError error
+	}{
+		{
+			name:        "unparsable_settings_are_returned",
+			ctx:         context.Background,
+			err:         errBadSettings,
+			expectError: errBadSettings,
+		},
+		{
+			name: "canceled_request_is_returned",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			err:         errCacheDown,
+			expectError: errCacheDown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(nil, tt.err)
+
+			// Neither falls back to analytics nor counts against the breaker
+			breaker := NewCircuitBreaker(1, time.Minute)
+			svc := NewLinkRedirectorService(cacheRepo, mocks.NewMockURLAnalyticRepo(ctrl), mocks.NewMockUnlockAttemptRepo(ctrl),
+				lib.DefaultCodeSet(), WithCacheFallback(breaker, cacheDown))
+			if _, err := svc.Redirect(tt.ctx(), lib.HexEncode(7), ""); !errors.Is(err, tt.expectError) {
+				t.Errorf("expected error %v, got %v", tt.expectError, err)
+			}
+			if breaker.Open() {
+				t.Error("expected the breaker to stay closed")
+			}
+		})
+	}
+}
+
+func TestLinkRedirectorService_CacheErrorWithoutFallback(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+	cacheRepo.EXPECT().Get(gomock.Any(), int64(7)).Return(nil, errCacheDown)
+
+	svc := NewLinkRedirectorService(cacheRepo, mocks.NewMockURLAnalyticRepo(ctrl), mocks.NewMockUnlockAttemptRepo(ctrl), lib.DefaultCodeSet())
+	if _, err := svc.Redirect(context.Background(), lib.HexEncode(7), ""); !errors.Is(err, errCacheDown) {
+		t.Errorf("expected the cache error, got %v", err)
+	}
+}
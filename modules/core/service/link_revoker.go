@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/lib"
+)
+
+const (
+	// RevokedRetention is the minimum time redirects to a revoked link answer 410
+	// instead of 404. The tombstone also lasts until the link would have expired.
+	RevokedRetention = 7 * 24 * time.Hour
+
+	MaxRevokeReasonLen = 500
+)
+
+var (
+	ErrLinkRevoked         = errors.New("short code has been revoked")
+	ErrInvalidRevokeReason = errors.New("invalid reason: must be at most 500 characters")
+)
+
+type LinkRevoker interface {
+	Revoke(ctx context.Context, shortCode string, reason *string) (*entity.URLAnalytic, error)
+}
+
+type LinkRevokerService struct {
+	cacheRepo    URLCacheRepo
+	analyticRepo URLAnalyticRepo
+	codes        *lib.CodeSet
+}
+
+func NewLinkRevokerService(cacheRepo URLCacheRepo, analyticRepo URLAnalyticRepo, codes *lib.CodeSet) *LinkRevokerService {
+	return &LinkRevokerService{
+		cacheRepo:    cacheRepo,
+		analyticRepo: analyticRepo,
+		codes:        codes,
+	}
+}
+
+// Revoke stops a link from redirecting and returns its revoked analytics record.
+// The link is found through its analytics row, so links whose Redis keys already
+// expired can still be revoked. Revoking twice keeps the first time and reason.
+// Only the owner or an admin can revoke a link; links without an owner can only be
+// revoked by an admin.
+func (s *LinkRevokerService) Revoke(ctx context.Context, shortCode string, reason *string) (*entity.URLAnalytic, error) {
+	if reason != nil && len(*reason) > MaxRevokeReasonLen {
+		return nil, ErrInvalidRevokeReason
+	}
+
+	analytic, _, err := lookupAnalytic(ctx, s.codes, s.analyticRepo, shortCode)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeChange(ctx, analytic); err != nil {
+		return nil, err
+	}
+	if analytic.RevokedAt != nil {
+		return analytic, nil
+	}
+
+	// Stop redirects first; the tombstone goes in before the link is removed so
+	// there is no moment where the code answers 404
+	retention := time.Until(analytic.ExpiresAt)
+	if retention < RevokedRetention {
+		retention = RevokedRetention
+	}
+	if err := s.cacheRepo.MarkRevoked(ctx, analytic.URLID, retention); err != nil {
+		return nil, err
+	}
+	if err := s.cacheRepo.Delete(ctx, analytic.URLID); err != nil {
+		return nil, err
+	}
+
+	if err := s.analyticRepo.Revoke(ctx, analytic.URLID, reason, time.Now()); err != nil {
+		return nil, err
+	}
+
+	// Re-read so a concurrent revocation that won reports its own time and reason
+	return s.analyticRepo.GetByURLID(ctx, analytic.URLID)
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/lib"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLinkRevokerService_Revoke(t *testing.T) {
+	now := time.Now()
+	earlier := now.Add(-time.Hour)
+
+	tests := []struct {
+		name            string
+		shortCode       string
+		reason          *string
+		analytic        *entity.URLAnalytic
+		lookupError     error
+		expectRevoke    bool
+		expectRetention func(time.Duration) bool
+		expectError     error
+		expectReason    *string
+	}{
+		{
+			name:         "live_link_is_revoked_with_reason",
+			shortCode:    lib.HexEncode(3),
+			reason:       ptr("phishing report"),
+			analytic:     &entity.URLAnalytic{URLID: 3, OwnerID: ptr("team-a"), ExpiresAt: now.Add(30 * 24 * time.Hour)},
+			expectRevoke: true,
+			expectRetention: func(got time.Duration) bool {
+				return got > 29*24*time.Hour
+			},
+			expectReason: ptr("phishing report"),
+		},
+		{
+			name:         "expired_link_is_revoked_and_tombstone_kept",
+			shortCode:    lib.HexEncode(3),
+			analytic:     &entity.URLAnalytic{URLID: 3, OwnerID: ptr("team-a"), ExpiresAt: now.Add(-time.Hour)},
+			expectRevoke: true,
+			expectRetention: func(got time.Duration) bool {
+				return got == RevokedRetention
+			},
+		},
+		{
+			name:      "already_revoked_link_keeps_first_revocation",
+			shortCode: lib.HexEncode(3),
+			reason:    ptr("second attempt"),
+			analytic: &entity.URLAnalytic{
+				URLID:        3,
+				OwnerID:      ptr("team-a"),
+				ExpiresAt:    now.Add(time.Hour),
+				RevokedAt:    &earlier,
+				RevokeReason: ptr("first attempt"),
+			},
+			expectReason: ptr("first attempt"),
+		},
+		{
+			name:        "reason_too_long_returns_error",
+			shortCode:   lib.HexEncode(3),
+			reason:      ptr(strings.Repeat("a", MaxRevokeReasonLen+1)),
+			expectError: ErrInvalidRevokeReason,
+		},
+		{
+			name:        "link_without_owner_is_forbidden",
+			shortCode:   lib.HexEncode(3),
+			analytic:    &entity.URLAnalytic{URLID: 3, ExpiresAt: now.Add(time.Hour)},
+			expectError: ErrForbidden,
+		},
+		{
+			name:        "unknown_code_returns_not_found",
+			shortCode:   lib.HexEncode(3),
+			lookupError: sql.ErrNoRows,
+			expectError: ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			cacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			analyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			if tt.analytic != nil || tt.lookupError != nil {
+				analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(3)).Return(tt.analytic, tt.lookupError)
+			}
+			if tt.expectRevoke {
+				gomock.InOrder(
+					cacheRepo.EXPECT().
+						MarkRevoked(gomock.Any(), int64(3), gomock.Cond(func(ttl time.Duration) bool {
+							return tt.expectRetention(ttl.Round(time.Hour))
+						})).
+						Return(nil),
+					cacheRepo.EXPECT().Delete(gomock.Any(), int64(3)).Return(nil),
+				)
+				analyticRepo.EXPECT().
+					Revoke(gomock.Any(), int64(3), tt.reason, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ int64, reason *string, revokedAt time.Time) error {
+						tt.analytic.RevokedAt = &revokedAt
+						tt.analytic.RevokeReason = reason
+						return nil
+					})
+				analyticRepo.EXPECT().GetByURLID(gomock.Any(), int64(3)).DoAndReturn(
+					func(context.Context, int64) (*entity.URLAnalytic, error) { return tt.analytic, nil },
+				)
+			}
+
+			svc := NewLinkRevokerService(cacheRepo, analyticRepo, lib.DefaultCodeSet())
+			analytic, err := svc.Revoke(WithOwner(context.Background(), "team-a"), tt.shortCode, tt.reason)
+
+			if !errors.Is(err, tt.expectError) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if tt.expectError != nil {
+				return
+			}
+			if analytic.RevokedAt == nil {
+				t.Fatal("expected revoked_at to be set")
+			}
+			if (tt.expectReason == nil) != (analytic.RevokeReason == nil) ||
+				(tt.expectReason != nil && *tt.expectReason != *analytic.RevokeReason) {
+				t.Errorf("expected reason %v, got %v", tt.expectReason, analytic.RevokeReason)
+			}
+		})
+	}
+}
@@ -8,23 +8,136 @@ import (
 )
 
 // URLCacheRepo interface for URL caching operations (Redis).
+// Every create marks its new links pending until Confirm, so links whose analytics
+// record was never written can be found and discarded.
 type URLCacheRepo interface {
-	// Create generates a new ID and stores the URL mapping with the specified TTL.
-	Create(ctx context.Context, longURL string, ttl time.Duration) (int64, error)
+	// Create generates a new ID and stores the URL mapping and its settings.
+	// The TTL is derived from ExpiresAt.
+	Create(ctx context.Context, link *entity.URL) (int64, error)
 
-	// Get retrieves the long URL for the given ID.
-	Get(ctx context.Context, id int64) (string, error)
+	// CreateWithAlias atomically reserves the alias and stores the URL mapping and its settings.
+	// It returns false if the alias is already held by another link.
+	CreateWithAlias(ctx context.Context, alias string, link *entity.URL) (int64, bool, error)
+
+	// CreateDeduped returns the active link indexed under the canonical LongURL if it lives at least
+	// until ExpiresAt, or allocates a new ID, stores the URL and indexes it with the same TTL.
+	// It reports whether an existing link was reused.
+	CreateDeduped(ctx context.Context, link *entity.URL) (int64, bool, error)
+
+	// CreateBatch allocates a contiguous ID range for all URLs and stores them in one atomic step,
+	// writing nothing if any ID of the range still holds a link.
+	// Each URL's ID is filled in place; its TTL is derived from ExpiresAt.
+	CreateBatch(ctx context.Context, urls []*entity.URL) error
+
+	// ResolveAlias retrieves the ID the given alias points to.
+	ResolveAlias(ctx context.Context, alias string) (int64, error)
+
+	// Get retrieves the long URL and settings for the given ID.
+	Get(ctx context.Context, id int64) (*entity.URL, error)
+
+	// ConsumeClick atomically takes one click from a click-limited link.
+	// It returns false once the link's click limit has been reached.
+	ConsumeClick(ctx context.Context, id int64) (bool, error)
+
+	// Update replaces the long URL and TTL of a live link, moving the expiry of its
+	// settings and alias with it. The TTL is derived from ExpiresAt.
+	Update(ctx context.Context, link *entity.URL, alias *string) error
 
 	// Set stores a URL mapping with the specified TTL.
 	Set(ctx context.Context, id int64, longURL string, ttl time.Duration) error
 
 	// Delete removes a URL mapping from the cache.
 	Delete(ctx context.Context, id int64) error
+
+	// ListPending returns up to limit IDs of pending links created before the given time, oldest first.
+	ListPending(ctx context.Context, before time.Time, limit int64) ([]int64, error)
+
+	// Confirm marks links as no longer pending, once their analytics records exist.
+	Confirm(ctx context.Context, ids ...int64) error
+
+	// Discard removes a pending link with its settings and alias or dedupe index.
+	// It returns false and leaves the link alone if it is not pending.
+	Discard(ctx context.Context, id int64) (bool, error)
+
+	// Restore writes the Redis keys of links recorded in analytics: the URL, its
+	// settings and its alias, with the TTL left until ExpiresAt at now. Links whose
+	// keys exist are left alone. It returns how many links were restored.
+	Restore(ctx context.Context, links []*entity.URLAnalytic, now time.Time) (int, error)
+
+	// AdvanceSequence moves the ID sequence up to floor if it is lower, so the next
+	// ID allocated is above floor. It returns the value the sequence had before.
+	AdvanceSequence(ctx context.Context, floor int64) (int64, error)
+
+	// MarkRevoked records that the link was revoked, for the given retention.
+	MarkRevoked(ctx context.Context, id int64, ttl time.Duration) error
+
+	// IsRevoked reports whether the link was revoked within the retention.
+	IsRevoked(ctx context.Context, id int64) (bool, error)
 }
 
 // URLAnalyticRepo interface for URL analytics repository operations (PostgreSQL).
 type URLAnalyticRepo interface {
 	Create(ctx context.Context, analytic *entity.URLAnalytic) (int64, error)
+	CreateBatch(ctx context.Context, analytics []*entity.URLAnalytic) error
 	GetByURLID(ctx context.Context, urlID int64) (*entity.URLAnalytic, error)
+	GetByAlias(ctx context.Context, alias string) (*entity.URLAnalytic, error)
+
+	// ListLive returns up to limit links that are unexpired at now and not revoked,
+	// with URLID above afterURLID, in URLID order.
+	ListLive(ctx context.Context, afterURLID int64, now time.Time, limit int) ([]*entity.URLAnalytic, error)
+
+	// MaxURLID returns the highest link ID recorded, or 0 if there are none.
+	MaxURLID(ctx context.Context) (int64, error)
+
 	UpdateStat(ctx context.Context, urlID int64, now time.Time) error
+
+	// ApplyChange updates the link's long URL and expiry and records the change in one transaction.
+	ApplyChange(ctx context.Context, change *entity.LinkChange) error
+
+	// ListChanges returns the recorded changes of a link, oldest first.
+	ListChanges(ctx context.Context, urlID int64) ([]*entity.LinkChange, error)
+
+	// Revoke marks the link as revoked. A link that is already revoked keeps its
+	// original time and reason.
+	Revoke(ctx context.Context, urlID int64, reason *string, now time.Time) error
+
+	// ListByOwner returns up to query.Limit links of the owner that match the query,
+	// in the query's order and starting after query.After.
+	ListByOwner(ctx context.Context, query entity.LinkQuery) ([]*entity.URLAnalytic, error)
+}
+
+// APIKeyRepo interface for API key storage (PostgreSQL).
+type APIKeyRepo interface {
+	Create(ctx context.Context, key *entity.APIKey) (int64, error)
+
+	// GetByHash returns the key with the given SHA-256 hash, or sql.ErrNoRows.
+	GetByHash(ctx context.Context, keyHash string) (*entity.APIKey, error)
+
+	// Revoke disables the key. Revoking a revoked key is a no-op; an unknown key returns sql.ErrNoRows.
+	Revoke(ctx context.Context, id int64, now time.Time) error
+}
+
+// UnlockAttemptRepo interface for rate limiting password attempts on protected links (Redis).
+type UnlockAttemptRepo interface {
+	// Reserve counts an attempt against the link's limit for the window. When the limit
+	// is reached it returns false and how long until attempts are allowed again.
+	Reserve(ctx context.Context, id int64, limit int64, window time.Duration) (bool, time.Duration, error)
+
+	// Release gives back an attempt that turned out to be a successful unlock.
+	Release(ctx context.Context, id int64) error
+}
+
+// IdempotencyRepo interface for storing replayable request outcomes (Redis).
+type IdempotencyRepo interface {
+	// Reserve claims the key for an in-flight request. It returns false if the key is already held.
+	Reserve(ctx context.Context, key string, record *entity.IdempotencyRecord, ttl time.Duration) (bool, error)
+
+	// Get retrieves the record for the key, or nil if none exists.
+	Get(ctx context.Context, key string) (*entity.IdempotencyRecord, error)
+
+	// Complete overwrites the in-flight record with the final outcome.
+	Complete(ctx context.Context, key string, record *entity.IdempotencyRecord, ttl time.Duration) error
+
+	// Release drops the key so a later retry can run the request again.
+	Release(ctx context.Context, key string) error
 }
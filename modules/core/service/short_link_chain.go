@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/nanda/doit/modules/core/lib"
+)
+
+// MaxShortLinkHops bounds how many of our own short links are followed when
+// flattening a destination.
+const MaxShortLinkHops = 5
+
+// Reasons for refusing a destination that is one of our own short links.
+const (
+	ReasonSelfLink      = "self_link"
+	ReasonRedirectLoop  = "redirect_loop"
+	ReasonDeadShortLink = "dead_short_link"
+	// ReasonRestrictedShortLink refuses flattening a scheduled, click-limited or
+	// password-protected link, which would hand out its destination without those checks.
+	ReasonRestrictedShortLink = "restricted_short_link"
+)
+
+// SelfLinkMode decides what happens to a destination that is one of our own short links.
+type SelfLinkMode string
+
+const (
+	// SelfLinkFlatten stores the final destination the short link redirects to.
+	SelfLinkFlatten SelfLinkMode = "flatten"
+	// SelfLinkRefuse rejects the destination.
+	SelfLinkRefuse SelfLinkMode = "refuse"
+)
+
+// shortLinkPathPrefix is the path of the redirect route, GET /s/{code}.
+const shortLinkPathPrefix = "/s/"
+
+// WithShortLinkHosts registers the hosts this service answers on. Destinations of
+// the form https://{host}/s/{code} are flattened or refused according to mode.
+func WithShortLinkHosts(hosts []string, mode SelfLinkMode) LinkCreatorOption {
+	return func(s *LinkCreatorService) {
+		s.shortLinkHosts = make(map[string]bool, len(hosts))
+		for _, host := range hosts {
+			if normalized, err := lib.NormalizeDomain(host); err == nil {
+				s.shortLinkHosts[normalized] = true
+			}
+		}
+		s.selfLinkMode = mode
+	}
+}
+
+// followShortLinks resolves a destination that is one of our own short links to the
+// destination it finally redirects to. The walk stops after MaxShortLinkHops links
+// or when a link repeats, so chains and loops left by older links are caught here
+// instead of at redirect time.
+func (s *LinkCreatorService) followShortLinks(ctx context.Context, longURL string) (string, error) {
+	seen := make(map[int64]bool)
+	for hops := 0; ; hops++ {
+		code, ok := s.ownShortCode(longURL)
+		if !ok {
+			return longURL, nil
+		}
+		if s.selfLinkMode == SelfLinkRefuse {
+			return "", &PolicyViolation{Reason: ReasonSelfLink}
+		}
+		if hops == MaxShortLinkHops {
+			return "", &PolicyViolation{Reason: ReasonRedirectLoop}
+		}
+
+		next, err := s.followShortCode(ctx, code, seen)
+		if err != nil {
+			return "", err
+		}
+		longURL = next
+	}
+}
+
+// followShortCode resolves one hop of a chain: the link behind code, which must exist,
+// be unrestricted and not appear in seen, and the destination it redirects to.
+func (s *LinkCreatorService) followShortCode(ctx context.Context, code string, seen map[int64]bool) (string, error) {
+	id, _, err := resolveShortCode(ctx, s.codes, s.cacheRepo, code)
+	if errors.Is(err, ErrNotFound) {
+		return "", &PolicyViolation{Reason: ReasonDeadShortLink}
+	}
+	if err != nil {
+		return "", err
+	}
+	if seen[id] {
+		return "", &PolicyViolation{Reason: ReasonRedirectLoop}
+	}
+	seen[id] = true
+
+	link, err := s.cacheRepo.Get(ctx, id)
+	if err != nil {
+		if isCacheMiss(err) {
+			return "", &PolicyViolation{Reason: ReasonDeadShortLink}
+		}
+		return "", err
+	}
+	if isRestricted(link) {
+		return "", &PolicyViolation{Reason: ReasonRestrictedShortLink}
+	}
+	return link.LongURL, nil
+}
+
+// ownShortCode returns the short code when longURL points at the redirect route of
+// one of our own hosts. The port is ignored.
+func (s *LinkCreatorService) ownShortCode(longURL string) (string, bool) {
+	if len(s.shortLinkHosts) == 0 {
+		return "", false
+	}
+
+	parsed, err := url.Parse(longURL)
+	if err != nil {
+		return "", false
+	}
+	host, err := lib.NormalizeDomain(parsed.Hostname())
+	if err != nil || !s.shortLinkHosts[host] {
+		return "", false
+	}
+
+	code, ok := strings.CutPrefix(parsed.Path, shortLinkPathPrefix)
+	if !ok || code == "" || strings.Contains(code, "/") {
+		return "", false
+	}
+	return code, true
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nanda/doit/modules/core/entity"
+	"github.com/nanda/doit/modules/core/internal/test/mocks"
+	"github.com/nanda/doit/modules/core/lib"
+	"go.uber.org/mock/gomock"
+)
+
+func TestLinkCreatorService_ShortLinkDestinations(t *testing.T) {
+	selfLink := func(id int64) string {
+		return "https://sho.rt/s/" + lib.HexEncode(id)
+	}
+
+	tests := []struct {
+		name         string
+		mode         SelfLinkMode
+		longURL      string
+		links        map[int64]string
+		limited      map[int64]bool
+		protected    map[int64]bool
+		aliases      map[string]int64
+		expectStored string
+		expectReason string
+	}{
+		{
+			name:         "foreign_url_is_stored_unchanged",
+			mode:         SelfLinkFlatten,
+			longURL:      "https://example.com/s/abc",
+			expectStored: "https://example.com/s/abc",
+		},
+		{
+			name:         "non_redirect_path_on_own_host_is_stored",
+			mode:         SelfLinkFlatten,
+			longURL:      "https://sho.rt/stats/abc",
+			expectStored: "https://sho.rt/stats/abc",
+		},
+		{
+			name:         "own_short_link_is_flattened",
+			mode:         SelfLinkFlatten,
+			longURL:      selfLink(10),
+			links:        map[int64]string{10: "https://example.com/final"},
+			expectStored: "https://example.com/final",
+		},
+		{
+			name:         "chain_across_hosts_and_alias_is_flattened",
+			mode:         SelfLinkFlatten,
+			longURL:      "https://SHO.RT:443/s/spring-promo",
+			aliases:      map[string]int64{"spring-promo": 11},
+			links:        map[int64]string{11: "http://go.sho.rt:8080/s/" + lib.HexEncode(12), 12: "https://example.com/final"},
+			expectStored: "https://example.com/final",
+		},
+		{
+			name:         "loop_is_refused",
+			mode:         SelfLinkFlatten,
+			longURL:      selfLink(20),
+			links:        map[int64]string{20: selfLink(21), 21: selfLink(20)},
+			expectReason: ReasonRedirectLoop,
+		},
+		{
+			name:    "chain_longer_than_limit_is_refused",
+			mode:    SelfLinkFlatten,
+			longURL: selfLink(30),
+			links: map[int64]string{
+				30: selfLink(31), 31: selfLink(32), 32: selfLink(33), 33: selfLink(34), 34: selfLink(35), 35: "https://example.com",
+			},
+			expectReason: ReasonRedirectLoop,
+		},
+		{
+			name:         "expired_short_link_is_refused",
+			mode:         SelfLinkFlatten,
+			longURL:      selfLink(40),
+			expectReason: ReasonDeadShortLink,
+		},
+		{
+			name:         "refuse_mode_rejects_own_short_link",
+			mode:         SelfLinkRefuse,
+			longURL:      selfLink(10),
+			expectReason: ReasonSelfLink,
+		},
+		{
+			name:         "flattened_destination_is_checked_by_policy",
+			mode:         SelfLinkFlatten,
+			longURL:      selfLink(50),
+			links:        map[int64]string{50: "http://127.0.0.1/admin"},
+			expectReason: ReasonInternalHost,
+		},
+		{
+			name:         "click_limited_short_link_is_refused",
+			mode:         SelfLinkFlatten,
+			longURL:      selfLink(60),
+			links:        map[int64]string{60: "https://example.com/reset"},
+			limited:      map[int64]bool{60: true},
+			expectReason: ReasonRestrictedShortLink,
+		},
+		{
+			name:         "password_protected_short_link_is_refused",
+			mode:         SelfLinkFlatten,
+			longURL:      selfLink(61),
+			links:        map[int64]string{61: "https://example.com/private"},
+			protected:    map[int64]bool{61: true},
+			expectReason: ReasonRestrictedShortLink,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockCacheRepo := mocks.NewMockURLCacheRepo(ctrl)
+			mockAnalyticRepo := mocks.NewMockURLAnalyticRepo(ctrl)
+
+			mockCacheRepo.EXPECT().
+				Get(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, id int64) (*entity.URL, error) {
+					if longURL, ok := tt.links[id]; ok {
+						link := &entity.URL{ID: id, LongURL: longURL}
+						if tt.limited[id] {
+							link.MaxClicks = ptr(int64(1))
+						}
+						if tt.protected[id] {
+							link.PasswordHash = "$2a$10$hash"
+						}
+						return link, nil
+					}
+					return nil, fmt.Errorf("URL not found or expired")
+				}).
+				AnyTimes()
+			mockCacheRepo.EXPECT().
+				ResolveAlias(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, alias string) (int64, error) {
+					if id, ok := tt.aliases[alias]; ok {
+						return id, nil
+					}
+					return 0, fmt.Errorf("alias not found or expired")
+				}).
+				AnyTimes()
+			if tt.expectStored != "" {
+				mockCacheRepo.EXPECT().Create(gomock.Any(), linkTo(tt.expectStored, DefaultTTL)).Return(int64(99), nil)
+				mockAnalyticRepo.EXPECT().Create(gomock.Any(), gomock.Any()).Return(int64(1), nil)
+				mockCacheRepo.EXPECT().Confirm(gomock.Any(), int64(99)).Return(nil)
+			}
+
+			svc := NewLinkCreatorService(mockCacheRepo, mockAnalyticRepo,
+				WithShortLinkHosts([]string{"sho.rt", "go.sho.rt"}, tt.mode))
+			link, err := svc.Create(context.Background(), entity.CreateLinkInput{LongURL: tt.longURL})
+
+			if tt.expectReason == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if link.LongURL != tt.expectStored {
+					t.Errorf("expected long url %s, got %s", tt.expectStored, link.LongURL)
+				}
+				return
+			}
+
+			var violation *PolicyViolation
+			if !errors.As(err, &violation) || violation.Reason != tt.expectReason {
+				t.Errorf("expected violation %s, got %v", tt.expectReason, err)
+			}
+		})
+	}
+}
@@ -1,5 +1,29 @@
 package service
 
+import (
+	"testing"
+
+	"github.com/nanda/doit/modules/core/lib"
+)
+
 func ptr[T any](v T) *T {
 	return &v
 }
+
+// testKeyedCodec returns a keyed codec with a fixed test key.
+func testKeyedCodec(t *testing.T) *lib.KeyedCodec {
+	t.Helper()
+	codec, err := lib.NewKeyedCodec(1, []byte("0123456789abcdef"), 0)
+	if err != nil {
+		t.Fatalf("failed to create keyed codec: %v", err)
+	}
+	return codec
+}
+
+// mistype returns a different character of the base-57 alphabet than c.
+func mistype(c byte) string {
+	if c == 'x' {
+		return "y"
+	}
+	return "x"
+}
@@ -40,7 +40,7 @@ export function setup() {
 
    const response = http.post(`${BASE_URL}/s`, payload, params);
 
-   if (response.status !== 200) {
+   if (response.status !== 201) {
       fail(
          `Failed to create short URL. Status: ${response.status}, Body: ${response.body}`,
       );
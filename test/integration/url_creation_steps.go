@@ -58,8 +58,8 @@ func (s *URLCreationSteps) iCreateAShortURLFor(longURL string) error {
 	}
 	s.ctx.LastBody = bodyBytes
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("expected status 200, got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("expected status 201, got %d", resp.StatusCode)
 	}
 
 	var result struct {
@@ -0,0 +1,170 @@
+package property
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/leanovate/gopter"
+	"github.com/leanovate/gopter/gen"
+	"github.com/leanovate/gopter/prop"
+	"github.com/nanda/doit/modules/core/lib"
+)
+
+// TestProperty_CodecsRoundTrip verifies that every codec decodes its own codes back
+// to the ID they were made from, and that no code is claimed by two formats.
+func TestProperty_CodecsRoundTrip(t *testing.T) {
+	hex, base57 := lib.HexCodec{}, lib.NewBase57Codec()
+	codes := lib.NewCodeSet(base57, hex)
+	properties := gopter.NewProperties(DefaultTestParameters())
+
+	for name, codec := range map[string]lib.Codec{"hex": hex, "base57": base57} {
+		properties.Property(name+" codes decode to their ID", prop.ForAll(
+			func(id int64) bool {
+				decoded, err := codec.Decode(codec.Encode(id))
+				return err == nil && decoded == id
+			},
+			gen.Int64Range(0, 1<<62),
+		))
+	}
+
+	properties.Property("hex and base57 formats never overlap", prop.ForAll(
+		func(id int64) bool {
+			hexCode, base57Code := hex.Encode(id), base57.Encode(id)
+			return !base57.Matches(hexCode) && !hex.Matches(base57Code) && !lib.IsValidAlias(base57Code)
+		},
+		gen.Int64Range(0, 1<<62),
+	))
+
+	properties.Property("code set resolves both formats", prop.ForAll(
+		func(id int64) bool {
+			fromHex, ok, err := codes.Decode(hex.Encode(id))
+			if !ok || err != nil || fromHex != id {
+				return false
+			}
+			fromBase57, ok, err := codes.Decode(base57.Encode(id))
+			return ok && err == nil && fromBase57 == id
+		},
+		gen.Int64Range(0, 1<<62),
+	))
+
+	properties.Property("base57 codes are no longer than hex codes", prop.ForAll(
+		func(id int64) bool {
+			return len(base57.Encode(id)) <= len(hex.Encode(id))
+		},
+		gen.Int64Range(1<<16, 1<<62),
+	))
+
+	properties.TestingRun(t)
+}
+
+// TestProperty_KeyedCodesRoundTrip verifies that keyed codes invert to their ID,
+// keep their width and stay decodable after the key is rotated.
+func TestProperty_KeyedCodesRoundTrip(t *testing.T) {
+	v1, err := lib.NewKeyedCodec(1, []byte("first secret of sixteen"), 0)
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+	v2, err := lib.NewKeyedCodec(2, []byte("second secret of sixteen"), 2)
+	if err != nil {
+		t.Fatalf("failed to create codec: %v", err)
+	}
+	rotated := lib.NewCodeSet(v2, lib.HexCodec{}, v1)
+	properties := gopter.NewProperties(DefaultTestParameters())
+
+	properties.Property("feistel permutation inverts", prop.ForAll(
+		func(x uint64) bool {
+			feistel, _ := lib.NewFeistel([]byte("first secret of sixteen"))
+			return feistel.Invert(feistel.Permute(x)) == x
+		},
+		gen.UInt64(),
+	))
+
+	properties.Property("keyed codes decode to their ID", prop.ForAll(
+		func(id int64) bool {
+			code := v1.Encode(id)
+			decoded, err := v1.Decode(code)
+			return err == nil && decoded == id && len(code) == len(v1.Encode(0))
+		},
+		gen.Int64Range(0, 1<<62),
+	))
+
+	properties.Property("codes of a rotated key still resolve", prop.ForAll(
+		func(id int64) bool {
+			old, ok, err := rotated.Decode(v1.Encode(id))
+			if !ok || err != nil || old != id {
+				return false
+			}
+			current, ok, err := rotated.Decode(rotated.Encode(id))
+			return ok && err == nil && current == id
+		},
+		gen.Int64Range(0, 1<<62),
+	))
+
+	properties.Property("consecutive IDs give unrelated codes", prop.ForAll(
+		func(id int64) bool {
+			a, b := v1.Encode(id), v1.Encode(id+1)
+			return a[:len(a)-2] != b[:len(b)-2]
+		},
+		gen.Int64Range(0, 1<<62),
+	))
+
+	properties.TestingRun(t)
+}
+
+// TestProperty_HexDecodeIsTypoTolerant verifies that a hex code retyped with any
+// mix of case and confusable characters decodes to the ID it was made from, and
+// folds back to the code HexEncode produced.
+func TestProperty_HexDecodeIsTypoTolerant(t *testing.T) {
+	// Each canonical character and the ways it gets retyped
+	spellings := map[rune][]string{
+		'g': {"g", "G", "o", "O", "0"},
+		'h': {"h", "H", "i", "I", "l", "L", "1"},
+	}
+	properties := gopter.NewProperties(DefaultTestParameters())
+
+	properties.Property("retyped hex codes decode to their ID", prop.ForAll(
+		func(id int64, choices []int) bool {
+			code := lib.HexEncode(id)
+			retyped := retype(code, spellings, choices)
+
+			decoded, err := lib.HexDecode(retyped)
+			canonical, ok := lib.CanonicalHexCode(retyped)
+			if err != nil || decoded != id || !ok || canonical != code {
+				t.Logf("%s retyped as %s: decoded %d (%v), canonical %s", code, retyped, decoded, err, canonical)
+				return false
+			}
+			return true
+		},
+		gen.Int64Range(0, 1<<62),
+		gen.SliceOfN(16, gen.IntRange(0, 6)),
+	))
+
+	properties.Property("canonical codes are unchanged", prop.ForAll(
+		func(id int64) bool {
+			code := lib.HexEncode(id)
+			canonical, ok := lib.CanonicalHexCode(code)
+			return ok && canonical == code
+		},
+		gen.Int64Range(0, 1<<62),
+	))
+
+	properties.TestingRun(t)
+}
+
+// retype spells each character of code the way choices picks: one of its
+// confusable spellings, or either case for the other letters.
+func retype(code string, spellings map[rune][]string, choices []int) string {
+	var retyped strings.Builder
+	for i, c := range code {
+		choice := choices[i%len(choices)]
+		switch options, ok := spellings[c]; {
+		case ok:
+			retyped.WriteString(options[choice%len(options)])
+		case choice%2 == 1:
+			retyped.WriteString(strings.ToUpper(string(c)))
+		default:
+			retyped.WriteRune(c)
+		}
+	}
+	return retyped.String()
+}
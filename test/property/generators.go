@@ -80,8 +80,8 @@ func CreateShortURL(t *testing.T, server *testutil.TestServer, url string) strin
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode != http.StatusOK {
-		t.Fatalf("Expected 200, got %d", resp.StatusCode)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d", resp.StatusCode)
 	}
 
 	var result struct {
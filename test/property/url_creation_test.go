@@ -42,8 +42,8 @@ func TestProperty_CreatedURLsAreRetrievable(t *testing.T) {
 			}
 			defer func() { _ = resp.Body.Close() }()
 
-			if resp.StatusCode != http.StatusOK {
-				t.Logf("Expected 200, got %d", resp.StatusCode)
+			if resp.StatusCode != http.StatusCreated {
+				t.Logf("Expected 201, got %d", resp.StatusCode)
 				return false
 			}
 
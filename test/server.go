@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/nanda/doit/config"
 	"github.com/nanda/doit/modules/core"
 	"github.com/nanda/doit/modules/core/handler"
 	"github.com/redis/go-redis/v9"
@@ -23,7 +24,13 @@ type TestServer struct {
 // NewTestServer creates a new test HTTP server with the given database and Redis connections.
 func NewTestServer(db *sql.DB, redisClient *redis.Client) *TestServer {
 	// Build application dependencies
-	builder := core.NewBuilder(db, redisClient)
+	builder, err := core.NewBuilder(&config.Config{}, db, redisClient)
+	if err != nil {
+		panic(fmt.Sprintf("failed to build application: %v", err))
+	}
+	if _, err := builder.IDSequenceGuard.Verify(context.Background()); err != nil {
+		panic(fmt.Sprintf("failed to verify ID sequence: %v", err))
+	}
 
 	e := echo.New()
 	e.HideBanner = true
@@ -34,9 +41,16 @@ func NewTestServer(db *sql.DB, redisClient *redis.Client) *TestServer {
 
 	// Register routes
 	e.GET("/healthz", builder.HealthzHandler.Handle)
-	e.POST("/s", builder.LinkCreatorHandler.Handle)
+	auth := builder.APIKeyAuth
+	e.POST("/s", builder.LinkCreatorHandler.Handle, auth.Resolve, auth.RequireForCreate)
+	e.POST("/s/batch", builder.LinkCreatorHandler.HandleBatch, auth.Resolve, auth.RequireForCreate)
 	e.GET("/s/:short_code", builder.LinkRedirectorHandler.Handle)
-	e.GET("/stats/:short_code", builder.LinkAnalyzerHandler.Handle)
+	e.POST("/s/:short_code/unlock", builder.LinkRedirectorHandler.HandleUnlock)
+	e.PATCH("/s/:short_code", builder.LinkEditorHandler.Handle, auth.Resolve)
+	e.DELETE("/s/:short_code", builder.LinkRevokerHandler.Handle, auth.Resolve)
+	e.GET("/stats/:short_code", builder.LinkAnalyzerHandler.Handle, auth.Resolve)
+	e.GET("/stats/:short_code/history", builder.LinkEditorHandler.HandleHistory, auth.Resolve)
+	e.GET("/links", builder.LinkListerHandler.Handle, auth.Resolve)
 
 	// Find an available port
 	listener, err := net.Listen("tcp", "127.0.0.1:0")